@@ -0,0 +1,265 @@
+package sudoku
+
+// An almost locked set is n cells of a house holding n+1 values between them
+// - take any one of the values away, and the rest are locked into the cells.
+// A value two sets both hold is a restricted common value when every place
+// for it in one set sees every place for it in the other, so at most one of
+// the sets holds it.
+//
+// ALS-XZ takes two sets with a restricted common value x. One of them can't
+// hold x, so it is locked, and holds every other value it has - any other
+// value z they share is in one of them, so every cell seeing all their
+// places for z loses it. ALS-XY-Wing does the same for two sets each tied to
+// a third by different restricted common values.
+//
+// Sue de Coq looks where a box and a line cross. The cells they share, with
+// some cells of the rest of the line and some of the rest of the box that
+// hold no value in common, hold as many values as there are cells - so every
+// value sits in them once, and the rest of the line and box lose the values
+// of the cells of them they share.
+
+// the most cells in an almost locked set, and in each part of a Sue de Coq
+const (
+	alsLimit      = 4
+	sueDeCoqLimit = 2
+)
+
+// lockedSet is an almost locked set of a boardWhole cluster. cells holds the
+// indexes of its cells, values what they hold, and places[value] the cells
+// holding each value.
+type lockedSet struct {
+	cells  CandidateSet
+	values CandidateSet
+	places []CandidateSet
+}
+
+// lockedSets returns every almost locked set of up to alsLimit cells in the
+// houses of a boardWhole cluster - or those found before done is closed
+func lockedSets(done <-chan struct{}, cells cluster, view sight, side int) []lockedSet {
+	var result []lockedSet
+	// a set can sit in more than one house
+	seen := make(map[string]bool)
+	for _, house := range view.houses {
+		// a cell holding too many values can't be part of a set
+		var open []int
+		house.Each(func(i int) {
+			if cells[i].actual == 0 && cells[i].possible.Count() <= alsLimit+1 {
+				open = append(open, i)
+			}
+		})
+		cellSubsets(done, alsLimit, CandidateSet{}, open, func(marked CandidateSet) {
+			if marked.Empty() || seen[marked.String()] {
+				return
+			}
+			values := valuesPainted(marked, cells)
+			if values.Count() != marked.Count()+1 {
+				return
+			}
+			seen[marked.String()] = true
+			set := lockedSet{cells: marked, values: values, places: make([]CandidateSet, side+1)}
+			marked.Each(func(i int) {
+				cells[i].possible.Each(func(value int) {
+					set.places[value] = set.places[value].Add(i)
+				})
+			})
+			result = append(result, set)
+		})
+	}
+	return result
+}
+
+// seesAll returns every cell that sees every cell of some
+func seesAll(view sight, some CandidateSet) CandidateSet {
+	var result CandidateSet
+	first := true
+	some.Each(func(i int) {
+		if first {
+			result, first = view.peers[i], false
+			return
+		}
+		result = result.Intersect(view.peers[i])
+	})
+	return result
+}
+
+// restricted returns the restricted common values of two almost locked sets
+// that share no cells
+func restricted(view sight, a, b lockedSet) CandidateSet {
+	var result CandidateSet
+	if !a.cells.Intersect(b.cells).Empty() {
+		return result
+	}
+	a.values.Intersect(b.values).Each(func(value int) {
+		if b.places[value].SubsetOf(seesAll(view, a.places[value])) {
+			result = result.Add(value)
+		}
+	})
+	return result
+}
+
+// alsMoves returns the almost locked set rules for the whole board
+func alsMoves(whole *wholeBoard, start board, opts settings) moves {
+	var looked bool
+	var view sight
+	return func(cells cluster) ([]change, error) {
+		if opts.level < ruleLevel(RuleALSXZ, 4) {
+			return nil, nil
+		}
+		if !looked {
+			var err error
+			if view, err = whole.look(start); err != nil {
+				return nil, err
+			}
+			looked = true
+		}
+
+		var changes []change
+		dropped := make([]CandidateSet, len(cells))
+		// drop takes values out of the cells in targets, but for those of the
+		// sets
+		drop := func(targets, values CandidateSet, rule Rule, sets ...CandidateSet) {
+			var why []coord
+			var groups [][]coord
+			for _, each := range sets {
+				targets = targets.Difference(each)
+			}
+			targets.Each(func(i int) {
+				lose := cells[i].possible.Intersect(values).Difference(dropped[i])
+				if cells[i].actual != 0 || lose.Empty() {
+					return
+				}
+				if groups == nil {
+					for _, each := range sets {
+						groups = append(groups, locations(each, cells))
+						why = append(why, locations(each, cells)...)
+					}
+				}
+				dropped[i] = dropped[i].Union(lose)
+				changes = append(changes, change{
+					cell:  cell{location: cells[i].location, excluded: lose},
+					rule:  rule,
+					cause: why,
+					sets:  groups})
+			})
+		}
+
+		// Sue de Coq first - a pair of almost locked sets can often stand in
+		// for one
+		if start.intersections != nil {
+			for _, crossing := range start.intersections.crossings {
+				sueDeCoq(opts.done, cells, whole, crossing, drop)
+			}
+		}
+
+		sets := lockedSets(opts.done, cells, view, start.side())
+		// the restricted common values of every pair of sets that have any,
+		// and the sets tied to each set in order
+		links := make([]map[int]CandidateSet, len(sets))
+		tied := make([][]int, len(sets))
+		for i := range sets {
+			links[i] = make(map[int]CandidateSet)
+		}
+		for i := range sets {
+			for j := i + 1; j < len(sets); j++ {
+				if common := restricted(view, sets[i], sets[j]); !common.Empty() {
+					links[i][j], links[j][i] = common, common
+					tied[i], tied[j] = append(tied[i], j), append(tied[j], i)
+				}
+			}
+		}
+		// shared takes every value z but x that sets a and b share out of
+		// every cell seeing all their places for z
+		shared := func(a, b lockedSet, x CandidateSet, rule Rule, sets ...CandidateSet) {
+			a.values.Intersect(b.values).Difference(x).Each(func(z int) {
+				drop(seesAll(view, a.places[z].Union(b.places[z])), NewCandidateSet(z), rule, sets...)
+			})
+		}
+
+		// ALS-XZ
+		for i, a := range sets {
+			for _, j := range tied[i] {
+				if j > i {
+					links[i][j].Each(func(x int) {
+						shared(a, sets[j], NewCandidateSet(x), RuleALSXZ, a.cells, sets[j].cells)
+					})
+				}
+			}
+		}
+
+		// ALS-XY-Wings - the pivot, then the two sets tied to it
+		for pivot := range sets {
+			for _, i := range tied[pivot] {
+				for _, j := range tied[pivot] {
+					if j <= i || !sets[i].cells.Intersect(sets[j].cells).Empty() {
+						continue
+					}
+					links[pivot][i].Each(func(x int) {
+						links[pivot][j].Remove(x).Each(func(y int) {
+							shared(sets[i], sets[j], NewCandidateSet(x, y), RuleALSXYWing,
+								sets[pivot].cells, sets[i].cells, sets[j].cells)
+						})
+					})
+				}
+			}
+		}
+
+		return changes, nil
+	}
+}
+
+// sueDeCoq tries Sue de Coq where a box and a line cross, until done is
+// closed
+func sueDeCoq(done <-chan struct{}, cells cluster, whole *wholeBoard, crossing intersection,
+	drop func(targets, values CandidateSet, rule Rule, sets ...CandidateSet)) {
+	// the cells left of the box and line, the ones they share, and the rest
+	// of each
+	var box, line, overlap []int
+	var boxCells, lineCells CandidateSet
+	for i, each := range crossing.cells {
+		index := whole.index[each]
+		if i < crossing.box {
+			boxCells = boxCells.Add(index)
+		}
+		if i >= crossing.box || crossing.overlap.Has(i) {
+			lineCells = lineCells.Add(index)
+		}
+		if cells[index].actual != 0 {
+			continue
+		}
+		switch {
+		case crossing.overlap.Has(i):
+			overlap = append(overlap, index)
+		case i < crossing.box:
+			box = append(box, index)
+		default:
+			line = append(line, index)
+		}
+	}
+
+	cellSubsets(done, len(overlap), CandidateSet{}, overlap, func(shared CandidateSet) {
+		values := valuesPainted(shared, cells)
+		// the rest of the line and box add a cell and a value at least each
+		if shared.Count() < 2 || values.Count() < shared.Count()+2 || values.Count() > shared.Count()+2*sueDeCoqLimit {
+			return
+		}
+		cellSubsets(done, sueDeCoqLimit, CandidateSet{}, line, func(onLine CandidateSet) {
+			lineValues := valuesPainted(onLine, cells)
+			if onLine.Empty() || lineValues.Intersect(values).Empty() {
+				return
+			}
+			cellSubsets(done, sueDeCoqLimit, CandidateSet{}, box, func(inBox CandidateSet) {
+				boxValues := valuesPainted(inBox, cells)
+				if inBox.Empty() || boxValues.Intersect(values).Empty() || !boxValues.Intersect(lineValues).Empty() {
+					return
+				}
+				if values.Union(lineValues).Union(boxValues).Count() != shared.Count()+onLine.Count()+inBox.Count() {
+					return
+				}
+				drop(lineCells, lineValues.Union(values.Difference(boxValues)), RuleSueDeCoq,
+					shared, onLine, inBox)
+				drop(boxCells, boxValues.Union(values.Difference(lineValues)), RuleSueDeCoq,
+					shared, onLine, inBox)
+			})
+		})
+	})
+}
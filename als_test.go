@@ -0,0 +1,104 @@
+package sudoku
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockedSets(t *testing.T) {
+	empty := NewBoard(3).puzzle
+	board := withCandidates(t, empty, coord{0, 0}, 1, 2)
+	board = withCandidates(t, board, coord{1, 1}, 1, 3)
+	board = withCandidates(t, board, coord{1, 4}, 2, 3)
+	view, err := empty.whole.look(empty)
+	assert.NoError(t, err)
+	whole, err := pickCluster(board, clusterRef{orient: boardWhole, index: 0})
+	assert.NoError(t, err)
+
+	sets := lockedSets(nil, whole, view, 9)
+	var found []string
+	for _, each := range sets {
+		found = append(found, each.cells.String())
+		assert.Equal(t, each.cells.Count()+1, each.values.Count(), "%v should hold one value more than its cells", each.cells)
+	}
+	// r1c1, r2c5, r2c2, then r2c2 and r2c5 in the row, and r1c1 and r2c2 in
+	// the box
+	assert.Equal(t, []string{"[0]", "[13]", "[10]", "[10 13]", "[0 10]"}, found)
+	assert.Equal(t, NewCandidateSet(10), sets[4].places[3])
+
+	assert.Empty(t, sets[0].places[3])
+	assert.Equal(t, NewCandidateSet(1), restricted(view, sets[0], sets[3]))
+	assert.Equal(t, CandidateSet{}, restricted(view, sets[0], sets[1]), "r1c1 and r2c5 don't see each other")
+	assert.Equal(t, CandidateSet{}, restricted(view, sets[2], sets[3]), "sets that share a cell")
+}
+
+func TestALSMoves(t *testing.T) {
+	empty := NewBoard(3).puzzle
+	excluded := func(c coord, values ...int) cell {
+		return cell{location: c, excluded: NewCandidateSet(values...)}
+	}
+
+	// r1c1 holds 1 or 2, and r2c2 and r2c5 1, 2 or 3 - tied by 1
+	xz := withCandidates(t, empty, coord{0, 0}, 1, 2)
+	xz = withCandidates(t, xz, coord{1, 1}, 1, 3)
+	xz = withCandidates(t, xz, coord{1, 4}, 2, 3)
+	xzSets := [][]coord{{{0, 0}}, {{1, 1}, {1, 4}}}
+	var xzChanges []change
+	for _, each := range []coord{{0, 3}, {0, 4}, {0, 5}, {1, 0}, {1, 2}} {
+		xzChanges = append(xzChanges, change{cell: excluded(each, 2), rule: RuleALSXZ,
+			cause: []coord{{0, 0}, {1, 1}, {1, 4}}, sets: xzSets})
+	}
+
+	// the pivot is three cells of the middle box, tied to two cells of the
+	// first column by 1 and two of the top row by 2
+	wing := withCandidates(t, empty, coord{3, 3}, 2, 6)
+	wing = withCandidates(t, wing, coord{3, 4}, 5, 6)
+	wing = withCandidates(t, wing, coord{4, 4}, 1, 5)
+	wing = withCandidates(t, wing, coord{4, 0}, 1, 3, 7)
+	wing = withCandidates(t, wing, coord{5, 0}, 3, 7)
+	wing = withCandidates(t, wing, coord{0, 3}, 2, 3, 8)
+	wing = withCandidates(t, wing, coord{0, 5}, 3, 8)
+
+	// r1c1 and r1c2 hold 1 to 4, r1c6 1 or 2 and r3c3 3 or 4
+	sueDeCoq := withCandidates(t, empty, coord{0, 0}, 1, 2, 3, 4)
+	sueDeCoq = withCandidates(t, sueDeCoq, coord{0, 1}, 1, 2, 3, 4)
+	sueDeCoq = withCandidates(t, sueDeCoq, coord{0, 5}, 1, 2)
+	sueDeCoq = withCandidates(t, sueDeCoq, coord{2, 2}, 3, 4)
+	sueDeCoqSets := [][]coord{{{0, 0}, {0, 1}}, {{0, 5}}, {{2, 2}}}
+	sueDeCoqCause := []coord{{0, 0}, {0, 1}, {0, 5}, {2, 2}}
+	var sueDeCoqChanges []change
+	for _, each := range rowExcept(0, 0, 1, 5) {
+		sueDeCoqChanges = append(sueDeCoqChanges, change{cell: excluded(each, 1, 2), rule: RuleSueDeCoq,
+			cause: sueDeCoqCause, sets: sueDeCoqSets})
+	}
+	for _, each := range []coord{{0, 2}, {1, 0}, {1, 1}, {1, 2}, {2, 0}, {2, 1}} {
+		sueDeCoqChanges = append(sueDeCoqChanges, change{cell: excluded(each, 3, 4), rule: RuleSueDeCoq,
+			cause: sueDeCoqCause, sets: sueDeCoqSets})
+	}
+
+	var tests = []struct {
+		puzzle  board
+		level   Difficulty
+		changes []change
+	}{
+		{xz, Hard, xzChanges},
+		{wing, Hard, []change{
+			{cell: excluded(coord{0, 0}, 3), rule: RuleALSXYWing,
+				cause: []coord{{3, 3}, {3, 4}, {4, 4}, {0, 3}, {0, 5}, {4, 0}, {5, 0}},
+				sets:  [][]coord{{{3, 3}, {3, 4}, {4, 4}}, {{0, 3}, {0, 5}}, {{4, 0}, {5, 0}}}},
+		}},
+		{sueDeCoq, Hard, sueDeCoqChanges},
+		// almost locked sets are a Hard rule
+		{xz, Medium, nil},
+		{empty, Hard, nil},
+	}
+
+	for id, testRun := range tests {
+		whole, err := pickCluster(testRun.puzzle, clusterRef{orient: boardWhole, index: 3})
+		assert.NoError(t, err)
+		changes, err := alsMoves(empty.whole, empty, settings{level: testRun.level})(whole)
+		assert.NoError(t, err, "test %d - unexpected error", id)
+		assert.Equal(t, testRun.changes, changes, "test %d - wrong changes", id)
+	}
+}
@@ -23,6 +23,9 @@ func TestDedupArr(t *testing.T) {
 		}, {
 			[]int{2, 1, 8, 4, 16, 6, 16, 1, 4},
 			[]int{1, 2, 4, 6, 8, 16},
+		}, {
+			[]int{1, 1, 1, 2},
+			[]int{1, 2},
 		}, {
 			[]int{},
 			[]int{},
@@ -39,7 +42,7 @@ func TestDedupArr(t *testing.T) {
 	}
 }
 
-func ExampleDedupArr() {
+func Example_dedupArr() {
 	input := []int{2, 1, 8, 4, 16, 6, 16, 1, 4}
 	fmt.Println(dedupArr(input))
 
@@ -144,6 +147,11 @@ func TestAddArr(t *testing.T) {
 		result := addArr(testRun.a, testRun.b)
 		assert.Equal(t, testRun.out, result, "wrong response test %d", id)
 	}
+
+	// the union must never write into the spare capacity of a
+	backing := []int{1, 2, 3, 9}
+	addArr(backing[:2], []int{7})
+	assert.Equal(t, []int{1, 2, 3, 9}, backing, "input changed")
 }
 
 func TestSubArr(t *testing.T) {
@@ -0,0 +1,241 @@
+package sudoku
+
+// Holds the set type used for candidate values and cell indexes.
+//
+// Every set operation on a []int had to sort, dedup and allocate. A set of
+// small ints fits in the bits of a word instead - anything under 64 in the
+// first word, which covers every value and index of boards up to 63 cells on
+// a side. Larger boards spill over into more words.
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+const wordBits = 64
+
+// CandidateSet is a set of non-negative ints - the candidate values of a
+// cell, or the cells a value can go in. The zero value is the empty set.
+// Operations never change the set they are called on, so a set can be
+// shared freely.
+type CandidateSet struct {
+	lo uint64
+	hi []uint64
+}
+
+// NewCandidateSet returns the set holding the given values.
+func NewCandidateSet(values ...int) CandidateSet {
+	var result CandidateSet
+	for _, value := range values {
+		result = result.Add(value)
+	}
+	return result
+}
+
+// valueSet returns every value that can go in a cluster of n cells - 1 thru n
+func valueSet(n int) CandidateSet {
+	return rangeSet(1, n)
+}
+
+// indexSet returns every index in a cluster of n cells - 0 thru n-1
+func indexSet(n int) CandidateSet {
+	return rangeSet(0, n-1)
+}
+
+// rangeSet returns the set of from thru to
+func rangeSet(from, to int) CandidateSet {
+	var result CandidateSet
+	if to < from {
+		return result
+	}
+	if to < wordBits {
+		result.lo = (^uint64(0) >> uint(wordBits-1-to)) &^ (uint64(1)<<uint(from) - 1)
+		return result
+	}
+	for value := from; value <= to; value++ {
+		result = result.Add(value)
+	}
+	return result
+}
+
+// word returns the i-th word of the set
+func (c CandidateSet) word(i int) uint64 {
+	if i == 0 {
+		return c.lo
+	}
+	if i-1 < len(c.hi) {
+		return c.hi[i-1]
+	}
+	return 0
+}
+
+// words returns the number of words the set uses
+func (c CandidateSet) words() int {
+	return len(c.hi) + 1
+}
+
+// build makes a set out of n words, dropping any empty words off the top
+func build(n int, word func(i int) uint64) CandidateSet {
+	result := CandidateSet{lo: word(0)}
+	top := 0
+	for i := 1; i < n; i++ {
+		if word(i) != 0 {
+			top = i
+		}
+	}
+	if top > 0 {
+		result.hi = make([]uint64, top)
+		for i := range result.hi {
+			result.hi[i] = word(i + 1)
+		}
+	}
+	return result
+}
+
+// Has returns true if value is in the set.
+func (c CandidateSet) Has(value int) bool {
+	if value < 0 {
+		return false
+	}
+	return c.word(value/wordBits)&(1<<uint(value%wordBits)) != 0
+}
+
+// Add returns the set with value added.
+func (c CandidateSet) Add(value int) CandidateSet {
+	if value < 0 {
+		panic(fmt.Sprintf("can't add %d to a CandidateSet", value))
+	}
+	if value < wordBits {
+		c.lo |= 1 << uint(value)
+		return c
+	}
+	n := c.words()
+	if need := value/wordBits + 1; need > n {
+		n = need
+	}
+	return build(n, func(i int) uint64 {
+		if i == value/wordBits {
+			return c.word(i) | 1<<uint(value%wordBits)
+		}
+		return c.word(i)
+	})
+}
+
+// Remove returns the set without value.
+func (c CandidateSet) Remove(value int) CandidateSet {
+	if !c.Has(value) {
+		return c
+	}
+	if value < wordBits {
+		c.lo &^= 1 << uint(value)
+		return c
+	}
+	return build(c.words(), func(i int) uint64 {
+		if i == value/wordBits {
+			return c.word(i) &^ (1 << uint(value%wordBits))
+		}
+		return c.word(i)
+	})
+}
+
+// Union returns every value in either set.
+func (c CandidateSet) Union(o CandidateSet) CandidateSet {
+	if c.hi == nil && o.hi == nil {
+		return CandidateSet{lo: c.lo | o.lo}
+	}
+	n := c.words()
+	if o.words() > n {
+		n = o.words()
+	}
+	return build(n, func(i int) uint64 { return c.word(i) | o.word(i) })
+}
+
+// Difference returns every value in c that is not in o.
+func (c CandidateSet) Difference(o CandidateSet) CandidateSet {
+	if c.hi == nil {
+		return CandidateSet{lo: c.lo &^ o.lo}
+	}
+	return build(c.words(), func(i int) uint64 { return c.word(i) &^ o.word(i) })
+}
+
+// Intersect returns every value in both sets.
+func (c CandidateSet) Intersect(o CandidateSet) CandidateSet {
+	if c.hi == nil || o.hi == nil {
+		return CandidateSet{lo: c.lo & o.lo}
+	}
+	return build(c.words(), func(i int) uint64 { return c.word(i) & o.word(i) })
+}
+
+// Count returns the number of values in the set.
+func (c CandidateSet) Count() int {
+	total := bits.OnesCount64(c.lo)
+	for _, each := range c.hi {
+		total += bits.OnesCount64(each)
+	}
+	return total
+}
+
+// Empty returns true if the set has no values.
+func (c CandidateSet) Empty() bool {
+	return c.lo == 0 && c.hi == nil
+}
+
+// Equal returns true if both sets hold the same values.
+func (c CandidateSet) Equal(o CandidateSet) bool {
+	if c.lo != o.lo || len(c.hi) != len(o.hi) {
+		return false
+	}
+	for i := range c.hi {
+		if c.hi[i] != o.hi[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// SubsetOf returns true if every value in c is also in o.
+func (c CandidateSet) SubsetOf(o CandidateSet) bool {
+	return c.Difference(o).Empty()
+}
+
+// Each calls f with every value in the set, smallest first.
+func (c CandidateSet) Each(f func(value int)) {
+	for i := 0; i < c.words(); i++ {
+		for word := c.word(i); word != 0; word &= word - 1 {
+			f(i*wordBits + bits.TrailingZeros64(word))
+		}
+	}
+}
+
+// Values returns every value in the set, smallest first.
+func (c CandidateSet) Values() []int {
+	result := make([]int, 0, c.Count())
+	c.Each(func(value int) {
+		result = append(result, value)
+	})
+	return result
+}
+
+// Min returns the smallest value in the set, or -1 if it is empty.
+func (c CandidateSet) Min() int {
+	for i := 0; i < c.words(); i++ {
+		if word := c.word(i); word != 0 {
+			return i*wordBits + bits.TrailingZeros64(word)
+		}
+	}
+	return -1
+}
+
+// Max returns the largest value in the set, or -1 if it is empty.
+func (c CandidateSet) Max() int {
+	for i := c.words() - 1; i >= 0; i-- {
+		if word := c.word(i); word != 0 {
+			return i*wordBits + wordBits - 1 - bits.LeadingZeros64(word)
+		}
+	}
+	return -1
+}
+
+func (c CandidateSet) String() string {
+	return fmt.Sprint(c.Values())
+}
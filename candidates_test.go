@@ -0,0 +1,216 @@
+package sudoku
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCandidateSet(t *testing.T) {
+	var tests = []struct {
+		a, b                   []int
+		union, diff, intersect []int
+	}{
+		{
+			[]int{1, 2, 3}, []int{3, 4},
+			[]int{1, 2, 3, 4}, []int{1, 2}, []int{3},
+		}, {
+			[]int{}, []int{1, 9},
+			[]int{1, 9}, []int{}, []int{},
+		}, {
+			[]int{0, 63, 64, 100}, []int{63, 200},
+			[]int{0, 63, 64, 100, 200}, []int{0, 64, 100}, []int{63},
+		}, {
+			[]int{130}, []int{130},
+			[]int{130}, []int{}, []int{130},
+		},
+	}
+
+	for id, testRun := range tests {
+		a, b := NewCandidateSet(testRun.a...), NewCandidateSet(testRun.b...)
+		assert.Equal(t, testRun.union, a.Union(b).Values(), "test %d - wrong union", id)
+		assert.Equal(t, testRun.diff, a.Difference(b).Values(), "test %d - wrong difference", id)
+		assert.Equal(t, testRun.intersect, a.Intersect(b).Values(), "test %d - wrong intersection", id)
+		assert.Equal(t, len(testRun.a), a.Count(), "test %d - wrong count", id)
+		assert.True(t, a.Intersect(b).SubsetOf(a), "test %d - intersection not a subset", id)
+
+		// emptied out sets are the same as the zero value
+		assert.True(t, a.Difference(a).Equal(CandidateSet{}), "test %d - not empty", id)
+		assert.Equal(t, CandidateSet{}, a.Difference(a), "test %d - not normalized", id)
+	}
+}
+
+func TestCandidateSetAddRemove(t *testing.T) {
+	var c CandidateSet
+	assert.True(t, c.Empty())
+	assert.Equal(t, -1, c.Min())
+	assert.Equal(t, -1, c.Max())
+
+	c = c.Add(5).Add(70).Add(5)
+	assert.Equal(t, []int{5, 70}, c.Values())
+	assert.True(t, c.Has(70))
+	assert.False(t, c.Has(6))
+	assert.False(t, c.Has(-1))
+	assert.Equal(t, 5, c.Min())
+	assert.Equal(t, 70, c.Max())
+
+	d := c.Remove(70)
+	assert.Equal(t, []int{5}, d.Values())
+	assert.Equal(t, []int{5, 70}, c.Values(), "remove changed the original")
+	assert.Equal(t, NewCandidateSet(5), d)
+	assert.Equal(t, "[5]", d.String())
+}
+
+func TestCandidateSetRanges(t *testing.T) {
+	assert.Equal(t, []int{1, 2, 3, 4}, valueSet(4).Values())
+	assert.Equal(t, []int{0, 1, 2, 3}, indexSet(4).Values())
+	assert.Equal(t, []int{}, valueSet(0).Values())
+	assert.Equal(t, 63, valueSet(63).Count())
+	assert.Equal(t, 64, indexSet(64).Count())
+	assert.Equal(t, 100, valueSet(100).Count())
+	assert.Equal(t, 100, valueSet(100).Max())
+}
+
+// the []int helpers cells and clusters used before CandidateSet, kept as the
+// baseline the benchmarks are measured against
+
+func dedupArr(arr []int) []int {
+	localArr := make([]int, len(arr))
+	copy(localArr, arr)
+	sort.Ints(localArr)
+	for i := 0; i < len(localArr)-1; {
+		if localArr[i] == localArr[i+1] {
+			localArr = append(localArr[:i], localArr[i+1:]...)
+		} else {
+			i++
+		}
+	}
+	return localArr
+}
+
+// preforms a union of a and b and removes anhy duplicates
+func addArr(a, b []int) []int {
+	// cap a so the append never writes into the caller's backing array
+	return dedupArr(append(a[:len(a):len(a)], b...))
+}
+
+// Subtracts b from a - removing any intersections from a and returning
+func subArr(a, b []int) []int {
+	b = dedupArr(b)
+	a = dedupArr(a)
+	var i, j int
+	for i < len(a) && j < len(b) {
+		if a[i] < b[j] {
+			i++
+		} else if a[i] > b[j] {
+			j++
+		} else if i+1 < len(a) {
+			a = append(a[:i], a[i+1:]...)
+		} else {
+			a = a[:i]
+		}
+	}
+	return a
+}
+
+func andArr(a, b []int) []int {
+	output := []int{}
+	a = dedupArr(a)
+	b = dedupArr(b)
+	var i, j int
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			output = append(output, a[i])
+			i++
+		} else if a[i] < b[j] {
+			i++
+		} else {
+			j++
+		}
+	}
+	return output
+}
+
+// the sides benchmarked - up to 63 every value fits in the first word of a
+// set, past that the rest spill over into hi
+var benchSides = []int{9, 16, 25, 36, 64, 100}
+
+// slices and sets holding the same values, for a cluster of n cells
+func benchInputs(n int) ([]int, []int, CandidateSet, CandidateSet) {
+	var a, b []int
+	for i := 1; i <= n; i++ {
+		if i%2 == 0 {
+			a = append(a, i)
+		}
+		if i%3 != 0 {
+			b = append(b, i)
+		}
+	}
+	return a, b, NewCandidateSet(a...), NewCandidateSet(b...)
+}
+
+func BenchmarkUnion(b *testing.B) {
+	for _, n := range benchSides {
+		sa, sb, ca, cb := benchInputs(n)
+		b.Run(fmt.Sprintf("slice-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				addArr(sa, sb)
+			}
+		})
+		b.Run(fmt.Sprintf("set-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ca.Union(cb)
+			}
+		})
+	}
+}
+
+func BenchmarkDifference(b *testing.B) {
+	for _, n := range benchSides {
+		sa, sb, ca, cb := benchInputs(n)
+		b.Run(fmt.Sprintf("slice-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				subArr(sa, sb)
+			}
+		})
+		b.Run(fmt.Sprintf("set-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ca.Difference(cb)
+			}
+		})
+	}
+}
+
+func BenchmarkIntersect(b *testing.B) {
+	for _, n := range benchSides {
+		sa, sb, ca, cb := benchInputs(n)
+		b.Run(fmt.Sprintf("slice-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				andArr(sa, sb)
+			}
+		})
+		b.Run(fmt.Sprintf("set-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ca.Intersect(cb)
+			}
+		})
+	}
+}
+
+func BenchmarkCount(b *testing.B) {
+	for _, n := range benchSides {
+		sa, _, ca, _ := benchInputs(n)
+		b.Run(fmt.Sprintf("slice-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = len(dedupArr(sa))
+			}
+		})
+		b.Run(fmt.Sprintf("set-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ca.Count()
+			}
+		})
+	}
+}
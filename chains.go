@@ -0,0 +1,374 @@
+package sudoku
+
+// Chains link candidates - a value in a cell - together. Two candidates are
+// strongly linked when one of them must be true: the only two places left
+// for a value in a house, or the only two values left in a cell. They are
+// weakly linked when they can't both be true: the same value in cells that
+// see each other, or two values of the same cell. A chain that alternates
+// strong and weak links, starting and ending with a strong one, proves that
+// one of its ends is true - so any candidate weakly linked to both ends is
+// false. An X-chain is such a chain for one value alone. A chain whose ends
+// are weakly linked to each other closes into a loop, and then every weak
+// link of the loop is strong too - an X-cycle for one value.
+//
+// Simple coloring follows the strong links of one value alone, coloring the
+// candidates along them in turn. One of the colors is true - a candidate that
+// sees both colors is false, and a color that sees itself is false.
+
+import (
+	"fmt"
+)
+
+// the most links a chain can have when the settings don't say
+const defaultChainLength = 12
+
+// candidate is a value in a cell, as a link in a chain
+type candidate struct {
+	location coord
+	value    int
+}
+
+// Candidate is a value in a cell, as a link in a chain.
+type Candidate struct {
+	Cell  Position
+	Value int
+}
+
+func (c Candidate) String() string {
+	return fmt.Sprintf("%d%v", c.Value, c.Cell)
+}
+
+// chainNode is a value in a cell of a boardWhole cluster
+type chainNode struct {
+	cell  int
+	value int
+}
+
+// chainGraph holds the candidates of a board and the strong links between
+// them. at[cell][value] is the index of a candidate, and has[value] the cells
+// a value is left in.
+type chainGraph struct {
+	cells  cluster
+	view   sight
+	nodes  []chainNode
+	at     []map[int]int
+	has    []CandidateSet
+	strong [][]int
+	// conjugate holds just the strong links between places in a house
+	conjugate [][]int
+	// done, if set, cuts the search for chains short once it is closed
+	done <-chan struct{}
+}
+
+// newChainGraph links up the candidates of the cells of a boardWhole cluster
+func newChainGraph(cells cluster, view sight, side int) *chainGraph {
+	g := &chainGraph{cells: cells, view: view, at: make([]map[int]int, len(cells)), has: make([]CandidateSet, side+1)}
+	for i, each := range cells {
+		if each.actual != 0 {
+			continue
+		}
+		g.at[i] = make(map[int]int)
+		each.possible.Each(func(value int) {
+			g.at[i][value] = len(g.nodes)
+			g.nodes = append(g.nodes, chainNode{cell: i, value: value})
+			g.has[value] = g.has[value].Add(i)
+		})
+	}
+	g.strong = make([][]int, len(g.nodes))
+	g.conjugate = make([][]int, len(g.nodes))
+
+	linked := make(map[[2]int]bool)
+	link := func(a, b int, conjugate bool) {
+		if linked[[2]int{a, b}] {
+			return
+		}
+		linked[[2]int{a, b}], linked[[2]int{b, a}] = true, true
+		g.strong[a] = append(g.strong[a], b)
+		g.strong[b] = append(g.strong[b], a)
+		if conjugate {
+			g.conjugate[a] = append(g.conjugate[a], b)
+			g.conjugate[b] = append(g.conjugate[b], a)
+		}
+	}
+
+	// the index of each house - entries with two places are strong links
+	for _, house := range view.houses {
+		members := house.Values()
+		houseCells := make(cluster, len(members))
+		for i, each := range members {
+			houseCells[i] = cells[each]
+		}
+		for value, places := range indexCluster(houseCells) {
+			if places.Count() == 2 {
+				a, b := members[places.Min()], members[places.Max()]
+				link(g.at[a][value], g.at[b][value], true)
+			}
+		}
+	}
+	// cells with two values left
+	for i, each := range cells {
+		if each.actual == 0 && each.possible.Count() == 2 {
+			link(g.at[i][each.possible.Min()], g.at[i][each.possible.Max()], false)
+		}
+	}
+	return g
+}
+
+// weak calls f with every candidate weakly linked to candidate n
+func (g *chainGraph) weak(n int, f func(m int)) {
+	node := g.nodes[n]
+	g.cells[node.cell].possible.Each(func(value int) {
+		if value != node.value {
+			f(g.at[node.cell][value])
+		}
+	})
+	g.view.peers[node.cell].Intersect(g.has[node.value]).Each(func(cell int) {
+		f(g.at[cell][node.value])
+	})
+}
+
+// weaklyLinked returns true if candidates a and b can't both be true
+func (g *chainGraph) weaklyLinked(a, b chainNode) bool {
+	if a.cell == b.cell {
+		return a.value != b.value
+	}
+	return a.value == b.value && g.view.peers[a.cell].Has(b.cell)
+}
+
+// seeingBoth calls f with every candidate outside skip weakly linked to both
+// a and b
+func (g *chainGraph) seeingBoth(a, b chainNode, skip map[int]bool, f func(m int)) {
+	g.weak(g.at[a.cell][a.value], func(m int) {
+		if !skip[m] && g.weaklyLinked(g.nodes[m], b) {
+			f(m)
+		}
+	})
+}
+
+// chainMoves returns the chain rules for the whole board
+func chainMoves(whole *wholeBoard, start board, opts settings) moves {
+	var looked bool
+	var view sight
+	maxLinks := opts.chainLength
+	if maxLinks < 1 {
+		maxLinks = defaultChainLength
+	}
+	return func(cells cluster) ([]change, error) {
+		if opts.level < ruleLevel(RuleAIC, 3) {
+			return nil, nil
+		}
+		if !looked {
+			var err error
+			if view, err = whole.look(start); err != nil {
+				return nil, err
+			}
+			looked = true
+		}
+		g := newChainGraph(cells, view, start.side())
+		g.done = opts.done
+
+		var changes []change
+		dropped := make(map[int]bool)
+		// drop makes candidate m false, as proved by the chain
+		drop := func(m int, rule Rule, chain []int, cause []coord) {
+			if dropped[m] {
+				return
+			}
+			dropped[m] = true
+			var links []candidate
+			for _, each := range chain {
+				node := g.nodes[each]
+				links = append(links, candidate{location: cells[node.cell].location, value: node.value})
+			}
+			node := g.nodes[m]
+			changes = append(changes, change{
+				cell:  cell{location: cells[node.cell].location, excluded: NewCandidateSet(node.value)},
+				rule:  rule,
+				cause: cause,
+				chain: links})
+		}
+		g.color(drop)
+		// chains of one value first, so that they are found as such
+		g.chains(maxLinks, true, drop)
+		g.chains(maxLinks, false, drop)
+		return changes, nil
+	}
+}
+
+// color tries simple coloring on every group of candidates of a value tied
+// together by strong links in houses
+func (g *chainGraph) color(drop func(m int, rule Rule, chain []int, cause []coord)) {
+	colors := make([]int, len(g.nodes))
+	for first := range g.nodes {
+		if stopped(g.done) {
+			return
+		}
+		if colors[first] != 0 || len(g.conjugate[first]) == 0 {
+			continue
+		}
+		// color the group by turns, 1 and 2
+		group := []int{first}
+		colors[first] = 1
+		for next := 0; next < len(group); next++ {
+			for _, m := range g.conjugate[group[next]] {
+				if colors[m] == 0 {
+					colors[m] = 3 - colors[group[next]]
+					group = append(group, m)
+				}
+			}
+		}
+		if len(group) < 3 {
+			// a lone strong link is a pointing pair or a hidden pair
+			continue
+		}
+		var cause []coord
+		for _, each := range group {
+			cause = append(cause, g.cells[g.nodes[each].cell].location)
+		}
+
+		// a color that sees itself is false
+		for _, a := range group {
+			for _, b := range group {
+				if a != b && colors[a] == colors[b] && g.weaklyLinked(g.nodes[a], g.nodes[b]) {
+					for _, each := range group {
+						if colors[each] == colors[a] {
+							drop(each, RuleColoring, group, cause)
+						}
+					}
+				}
+			}
+		}
+		// a candidate that sees both colors is false
+		in := make(map[int]bool)
+		for _, each := range group {
+			in[each] = true
+		}
+		value := g.nodes[first].value
+		g.has[value].Each(func(cell int) {
+			m := g.at[cell][value]
+			if in[m] {
+				return
+			}
+			var seen [3]bool
+			for _, each := range group {
+				if g.weaklyLinked(g.nodes[each], g.nodes[m]) {
+					seen[colors[each]] = true
+				}
+			}
+			if seen[1] && seen[2] {
+				drop(m, RuleColoring, group, cause)
+			}
+		})
+	}
+}
+
+// chains looks for the shortest chain from every candidate to every other,
+// of up to maxLinks links, and drops whatever each proves false. With
+// oneValue set, it only follows links between places of the same value.
+func (g *chainGraph) chains(maxLinks int, oneValue bool, drop func(m int, rule Rule, chain []int, cause []coord)) {
+	// a state is a candidate, and whether the chain got there by a strong
+	// link - from a strong arrival the chain goes on weakly, and the other
+	// way round
+	states := 2 * len(g.nodes)
+	parent := make([]int, states)
+	depth := make([]int, states)
+	seen := make([]int, states)
+	strongLinks := g.strong
+	if oneValue {
+		strongLinks = g.conjugate
+	}
+	for first := range g.nodes {
+		if stopped(g.done) {
+			return
+		}
+		if len(strongLinks[first]) == 0 {
+			continue
+		}
+		stamp := first + 1
+		queue := []int{2 * first}
+		seen[2*first], depth[2*first], parent[2*first] = stamp, 0, -1
+		for next := 0; next < len(queue); next++ {
+			state := queue[next]
+			n, strong := state/2, state%2 == 1
+			if depth[state] >= maxLinks {
+				continue
+			}
+			visit := func(m int) {
+				if oneValue && g.nodes[m].value != g.nodes[n].value {
+					return
+				}
+				to := 2 * m
+				if !strong {
+					to++
+				}
+				if seen[to] == stamp {
+					return
+				}
+				seen[to], depth[to], parent[to] = stamp, depth[state]+1, state
+				queue = append(queue, to)
+				if to%2 == 1 && depth[to] >= 3 {
+					g.proven(first, to, parent, drop)
+				}
+			}
+			if strong {
+				g.weak(n, visit)
+			} else {
+				for _, m := range strongLinks[n] {
+					visit(m)
+				}
+			}
+		}
+	}
+}
+
+// proven drops whatever the chain ending at state proves false - the chain
+// starts at candidate first, and follows parent back from state
+func (g *chainGraph) proven(first, state int, parent []int, drop func(m int, rule Rule, chain []int, cause []coord)) {
+	var chain []int
+	for at := state; at >= 0; at = parent[at] {
+		chain = append([]int{at / 2}, chain...)
+	}
+	// a chain that comes back on itself proves nothing more than a shorter
+	// one
+	in := make(map[int]bool)
+	oneValue := true
+	for _, each := range chain {
+		if in[each] {
+			return
+		}
+		in[each] = true
+		oneValue = oneValue && g.nodes[each].value == g.nodes[first].value
+	}
+	// the cells of the chain, each once
+	var cause []coord
+	for i, each := range chain {
+		if i == 0 || g.nodes[each].cell != g.nodes[chain[i-1]].cell {
+			cause = append(cause, g.cells[g.nodes[each].cell].location)
+		}
+	}
+
+	a, b := g.nodes[first], g.nodes[chain[len(chain)-1]]
+	if !g.weaklyLinked(a, b) {
+		rule := RuleAIC
+		if oneValue {
+			rule = RuleXChain
+		}
+		g.seeingBoth(a, b, in, func(m int) {
+			drop(m, rule, chain, cause)
+		})
+		return
+	}
+
+	// the ends are weakly linked, closing a loop - every weak link of the
+	// loop, the closing one too, is strong
+	rule := RuleAIC
+	if oneValue {
+		rule = RuleXCycle
+	}
+	for i := 1; i < len(chain); i += 2 {
+		u, v := g.nodes[chain[i]], g.nodes[chain[(i+1)%len(chain)]]
+		g.seeingBoth(u, v, in, func(m int) {
+			drop(m, rule, chain, cause)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package sudoku
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withoutChains returns a board without the chain rules, keeping the wings
+func withoutChains(b board) board {
+	b.whole = &wholeBoard{cells: b.whole.cells, index: b.whole.index, rules: 1}
+	return b
+}
+
+func TestChainMoves(t *testing.T) {
+	empty := NewBoard(3).puzzle
+	excluded := func(c coord, value int) cell { return cell{location: c, excluded: NewCandidateSet(value)} }
+	colExcept := func(col int, rows ...int) []coord {
+		var result []coord
+		for _, each := range rowExcept(col, rows...) {
+			result = append(result, coord{x: each.y, y: each.x})
+		}
+		return result
+	}
+
+	// 1 is only at r1c1 and r1c5 in the top row, r1c5 and r5c5 in the
+	// middle column, and r5c5 and r5c2 in the middle row
+	coloring := withoutValue(t, empty, 1, rowExcept(0, 0, 4)...)
+	coloring = withoutValue(t, coloring, 1, colExcept(4, 0, 4)...)
+	coloring = withoutValue(t, coloring, 1, rowExcept(4, 1, 4)...)
+	colored := []coord{{0, 0}, {0, 4}, {4, 4}, {4, 1}}
+	coloredChain := []candidate{{coord{0, 0}, 1}, {coord{0, 4}, 1}, {coord{4, 4}, 1}, {coord{4, 1}, 1}}
+
+	// 1 is only at r1c1 and r1c5 in the top row, and r3c6 and r7c6 in the
+	// sixth column - r1c5 and r3c6 share a box
+	xChain := withoutValue(t, empty, 1, rowExcept(0, 0, 4)...)
+	xChain = withoutValue(t, xChain, 1, colExcept(5, 2, 6)...)
+
+	// r1c1 holds 1 or 2, r1c5 2 or 3, and 3 is only at r5c5 and r5c1 in the
+	// middle row
+	aic := withCandidates(t, empty, coord{0, 0}, 1, 2)
+	aic = withCandidates(t, aic, coord{0, 4}, 2, 3)
+	aic = withoutValue(t, aic, 3, rowExcept(4, 0, 4)...)
+
+	var tests = []struct {
+		puzzle  board
+		opts    settings
+		changes []change
+	}{
+		{coloring, settings{level: Hard}, []change{
+			{cell: excluded(coord{1, 1}, 1), rule: RuleColoring, cause: colored, chain: coloredChain},
+			{cell: excluded(coord{2, 1}, 1), rule: RuleColoring, cause: colored, chain: coloredChain},
+			{cell: excluded(coord{3, 0}, 1), rule: RuleColoring, cause: colored, chain: coloredChain},
+			{cell: excluded(coord{5, 0}, 1), rule: RuleColoring, cause: colored, chain: coloredChain},
+		}},
+		{xChain, settings{level: Hard}, []change{
+			{cell: excluded(coord{6, 0}, 1), rule: RuleXChain,
+				cause: []coord{{0, 0}, {0, 4}, {2, 5}, {6, 5}},
+				chain: []candidate{{coord{0, 0}, 1}, {coord{0, 4}, 1}, {coord{2, 5}, 1}, {coord{6, 5}, 1}}},
+		}},
+		{aic, settings{level: Hard}, []change{
+			{cell: excluded(coord{4, 0}, 1), rule: RuleAIC,
+				cause: []coord{{0, 0}, {0, 4}, {4, 4}, {4, 0}},
+				chain: []candidate{{coord{0, 0}, 1}, {coord{0, 0}, 2}, {coord{0, 4}, 2}, {coord{0, 4}, 3},
+					{coord{4, 4}, 3}, {coord{4, 0}, 3}}},
+		}},
+		// the chain is five links long
+		{aic, settings{level: Hard, chainLength: 3}, nil},
+		// chains are a Hard rule
+		{coloring, settings{level: Medium}, nil},
+		{empty, settings{level: Hard}, nil},
+	}
+
+	for id, testRun := range tests {
+		whole, err := pickCluster(testRun.puzzle, clusterRef{orient: boardWhole, index: 1})
+		assert.NoError(t, err)
+		changes, err := chainMoves(empty.whole, empty, testRun.opts)(whole)
+		assert.NoError(t, err, "test %d - unexpected error", id)
+		assert.Equal(t, testRun.changes, changes, "test %d - wrong changes", id)
+	}
+}
+
+func TestChainCycle(t *testing.T) {
+	empty := NewBoard(3).puzzle
+	// 1 is only at columns 1 and 5 of the top and middle rows - a loop
+	// taking 1 out of the rest of both columns
+	cycle := withoutValue(t, empty, 1, rowExcept(0, 0, 4)...)
+	cycle = withoutValue(t, cycle, 1, rowExcept(4, 0, 4)...)
+
+	whole, err := pickCluster(cycle, clusterRef{orient: boardWhole, index: 1})
+	assert.NoError(t, err)
+	changes, err := chainMoves(empty.whole, empty, settings{level: Hard})(whole)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 14)
+	for _, each := range changes {
+		assert.Equal(t, RuleXCycle, each.rule)
+		assert.Contains(t, []int{0, 4}, each.location.y, "only the columns of the loop lose 1")
+		assert.Len(t, each.chain, 4)
+	}
+}
+
+// a puzzle that needs alternating inference chains
+const (
+	chainPuzzle   = "6495................56.8..7...8.3..9....4...8.....95.4..7..56...5..2.8..83....27."
+	chainSolution = "649571382178234956325698417461853729593742168782169534217485693956327841834916275"
+)
+
+func TestSolveChains(t *testing.T) {
+	b, err := Parse(strings.NewReader(chainPuzzle))
+	assert.NoError(t, err)
+	level, report := Grade(b)
+	assert.Equal(t, Hard, level)
+	assert.Equal(t, Solved, report.Status)
+	assert.Zero(t, report.Guesses)
+	assert.NotZero(t, report.Counts[RuleAIC], "chains should be used")
+
+	events := make(chan Event, 10000)
+	s := NewFromBoard(b)
+	s.Trace = events
+	result, err := s.Solve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, lineValues(chainSolution), result.Values)
+	close(events)
+	for step := range events {
+		if step.Rule == RuleAIC {
+			assert.True(t, len(step.Chain) >= 4 && len(step.Chain)%2 == 0, "a chain starts and ends with a strong link")
+		}
+	}
+
+	// without the chains, the rules stall
+	stalled, err := propagate(context.Background(), withoutChains(b.puzzle), settings{level: Hard})
+	assert.NoError(t, err)
+	assert.False(t, stalled.solved(), "the puzzle should need the chains")
+}
@@ -0,0 +1,368 @@
+package sudoku
+
+// Outside clues sit beside the grid and say something about the cells they
+// look along - a row or column for sandwiches, skyscrapers and X-sums, a
+// diagonal for little killers. A clue is a cluster like any other as far as
+// the pipeline goes, its cells in order from the clue, and its rule keeps
+// every cell to the values that some way of meeting the clue puts there.
+
+import (
+	"fmt"
+)
+
+// ClueKind is a kind of clue outside the grid.
+type ClueKind int
+
+const (
+	// Sandwich clues give the sum of the values between the 1 and the
+	// largest value of a row or column.
+	Sandwich ClueKind = iota
+	// LittleKiller clues give the sum of the values along a diagonal, where
+	// values may repeat.
+	LittleKiller
+	// Skyscraper clues give how many cells of a row or column can be seen
+	// from the clue, taking each value as the height of a building that hides
+	// the lower ones behind it.
+	Skyscraper
+	// XSum clues give the sum of the first X cells of a row or column from
+	// the clue, where X is the value of the first cell.
+	XSum
+)
+
+var clueNames = map[ClueKind]string{
+	Sandwich:     "sandwich",
+	LittleKiller: "littlekiller",
+	Skyscraper:   "skyscraper",
+	XSum:         "xsum",
+}
+
+func (k ClueKind) String() string {
+	if name, ok := clueNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("ClueKind(%d)", int(k))
+}
+
+// rule returns the rule that deductions from a kind of clue are tagged with
+func (k ClueKind) rule() Rule {
+	switch k {
+	case Sandwich:
+		return RuleSandwich
+	case LittleKiller:
+		return RuleLittleKiller
+	case Skyscraper:
+		return RuleSkyscraper
+	default:
+		return RuleXSum
+	}
+}
+
+// clue is a clue outside the grid, and the cells it looks along - in order,
+// starting from the one next to the clue
+type clue struct {
+	kind  ClueKind
+	value int
+	step  coord
+	cells []coord
+}
+
+// clueList lists the boardClue clusters of a shape. at[c] holds the clues
+// coord c is looked at by.
+type clueList struct {
+	clues []clue
+	at    map[coord][]int
+}
+
+// with returns the clues with one more added on the end - the clues it is
+// called on are left alone, other boards may share them
+func (l *clueList) with(added clue) *clueList {
+	result := &clueList{at: make(map[coord][]int)}
+	if l != nil {
+		result.clues = append(result.clues, l.clues...)
+		for at, indexes := range l.at {
+			result.at[at] = append([]int{}, indexes...)
+		}
+	}
+	index := len(result.clues)
+	result.clues = append(result.clues, added)
+	for _, each := range added.cells {
+		result.at[each] = append(result.at[each], index)
+	}
+	return result
+}
+
+// moves returns the rules for clue index
+func (l *clueList) moves(index, side int, opts settings) moves {
+	each := l.clues[index]
+	rule := each.kind.rule()
+	return func(cells cluster) ([]change, error) {
+		var keep []CandidateSet
+		switch each.kind {
+		case Sandwich:
+			keep = sandwichSupport(each.value, side, cells)
+		case LittleKiller:
+			values := []CandidateSet{NewCandidateSet(each.value)}
+			for _, c := range cells {
+				values = append(values, cellValues(c))
+			}
+			keep = arrowBounds(values, side)[1:]
+		case Skyscraper:
+			keep = skyscraperSupport(each.value, side, cells)
+		default:
+			keep = xSumSupport(each.value, side, cells)
+		}
+
+		var changes []change
+		for i, c := range cells {
+			kept := cellValues(c).Intersect(keep[i])
+			if kept.Empty() {
+				return nil, fmt.Errorf("%w: nothing fits %v for the %v clue of %d at %v", ErrContradiction,
+					position(c.location), each.kind, each.value, position(each.cells[0]))
+			}
+			if c.actual != 0 || opts.level < ruleLevel(rule, len(cells)) {
+				continue
+			}
+			if drop := c.possible.Difference(kept); !drop.Empty() {
+				changes = append(changes, change{
+					cell:  cell{location: c.location, excluded: drop},
+					rule:  rule,
+					cause: each.cells})
+			}
+		}
+		return changes, nil
+	}
+}
+
+// restrict returns a copy of some cells with only the values in keep left -
+// a solved cell with a value outside keep comes back with nothing
+func restrict(cells cluster, keep CandidateSet) cluster {
+	result := make(cluster, len(cells))
+	for i, each := range cells {
+		result[i] = each
+		if each.actual != 0 && !keep.Has(each.actual) {
+			result[i].actual, result[i].possible = 0, CandidateSet{}
+		} else {
+			result[i].possible = each.possible.Intersect(keep)
+		}
+	}
+	return result
+}
+
+// sandwichSupport tries every place for the 1 and the largest value of a
+// house, and every way of filling the cells between them that adds up to
+// sum, and returns the values each cell took in at least one of them
+func sandwichSupport(sum, side int, cells cluster) []CandidateSet {
+	support := make([]CandidateSet, len(cells))
+	crust := NewCandidateSet(1, side)
+	filling := valueSet(side).Difference(crust)
+	for low, lowCell := range cells {
+		if !cellValues(lowCell).Has(1) {
+			continue
+		}
+		for high, highCell := range cells {
+			if high == low || !cellValues(highCell).Has(side) {
+				continue
+			}
+			from, to := low, high
+			if from > to {
+				from, to = to, from
+			}
+			between, ok := cageSupport(sum, side, restrict(cells[from+1:to], filling))
+			if !ok {
+				continue
+			}
+			support[low] = support[low].Add(1)
+			support[high] = support[high].Add(side)
+			for i := range cells {
+				switch {
+				case i > from && i < to:
+					support[i] = support[i].Union(between[i-from-1])
+				case i != low && i != high:
+					support[i] = support[i].Union(filling)
+				}
+			}
+		}
+	}
+	return support
+}
+
+// xSumSupport tries every value for the first cell, and every way of filling
+// that many cells that adds up to sum, and returns the values each cell took
+// in at least one of them
+func xSumSupport(sum, side int, cells cluster) []CandidateSet {
+	support := make([]CandidateSet, len(cells))
+	cellValues(cells[0]).Each(func(count int) {
+		if count > len(cells) {
+			return
+		}
+		first := restrict(cells[:count], valueSet(side))
+		first[0] = restrict(first[:1], NewCandidateSet(count))[0]
+		counted, ok := cageSupport(sum, side, first)
+		if !ok {
+			return
+		}
+		for i := range cells {
+			if i < count {
+				support[i] = support[i].Union(counted[i])
+			} else {
+				support[i] = support[i].Union(valueSet(side))
+			}
+		}
+	})
+	return support
+}
+
+// skyscraperSupport tries every way of filling a house, counting the cells
+// that stand taller than every cell in front of them, and returns the values
+// each cell took in some way that sees visible cells. Each set of values
+// used, tallest value and count so far is only worked out once.
+func skyscraperSupport(visible, side int, cells cluster) []CandidateSet {
+	values := make([]CandidateSet, len(cells))
+	for i, each := range cells {
+		values[i] = cellValues(each)
+	}
+	type state struct {
+		used    uint64
+		tallest int
+		seen    int
+	}
+	feasible := make(map[state]bool)
+	support := make([]CandidateSet, len(cells))
+
+	var fill func(depth int, used CandidateSet, tallest, seen int) bool
+	fill = func(depth int, used CandidateSet, tallest, seen int) bool {
+		if seen > visible {
+			return false
+		}
+		// every cell left could be seen at most, once for each value taller
+		// than the tallest so far
+		if taller := valueSet(side).Difference(used).Difference(valueSet(tallest)).Count(); seen+taller < visible {
+			return false
+		}
+		if depth == len(cells) {
+			return seen == visible
+		}
+		// the values used are only a key while they fit in one word
+		key := state{used: used.lo, tallest: tallest, seen: seen}
+		if known, ok := feasible[key]; ok && side < wordBits {
+			return known
+		}
+		found := false
+		values[depth].Difference(used).Each(func(value int) {
+			nextTallest, nextSeen := tallest, seen
+			if value > tallest {
+				nextTallest, nextSeen = value, seen+1
+			}
+			if fill(depth+1, used.Add(value), nextTallest, nextSeen) {
+				support[depth] = support[depth].Add(value)
+				found = true
+			}
+		})
+		feasible[key] = found
+		return found
+	}
+	fill(0, CandidateSet{}, 0, 0)
+	return support
+}
+
+// Clue is a clue outside the grid. Start is the first cell the clue looks
+// at, and Step the way it looks from there, one cell at a time - {0, 1} for
+// a clue left of a row, {-1, 1} for a little killer below the grid looking
+// up and to the right.
+type Clue struct {
+	Kind  ClueKind
+	Value int
+	Start Position
+	Step  Position
+}
+
+// AddClue puts a clue outside the grid. Sandwiches, skyscrapers and X-sums
+// look along a whole row or column, so Start must be at one end of it and
+// Step straight along it. Little killers look along a diagonal, from Start
+// to the edge of the grid.
+func (b *Board) AddClue(kind ClueKind, value int, start, step Position) error {
+	if _, ok := clueNames[kind]; !ok {
+		return fmt.Errorf("no such clue %v", kind)
+	}
+	from, by := coord{x: start.Row, y: start.Col}, coord{x: step.Row, y: step.Col}
+	if !b.puzzle.onBoard(from) {
+		return fmt.Errorf("cell %v is off the board", start)
+	}
+	if b.puzzle.onBoard(coord{x: from.x - by.x, y: from.y - by.y}) {
+		return fmt.Errorf("cell %v is not at the edge of the grid", start)
+	}
+	diagonal := by.x*by.x == 1 && by.y*by.y == 1
+	straight := by.x*by.x+by.y*by.y == 1
+	if kind == LittleKiller && !diagonal || kind != LittleKiller && !straight {
+		return fmt.Errorf("a %v clue can't look along %v", kind, step)
+	}
+
+	added := clue{kind: kind, value: value, step: by}
+	for at := from; b.puzzle.onBoard(at); at = (coord{x: at.x + by.x, y: at.y + by.y}) {
+		added.cells = append(added.cells, at)
+	}
+	if kind != LittleKiller && !b.puzzle.isHouse(added.cells) {
+		return fmt.Errorf("the cells from %v are not a whole row or column", start)
+	}
+
+	side := b.puzzle.side()
+	low, high := 0, 0
+	switch kind {
+	case Sandwich:
+		high = side*(side+1)/2 - 1 - side
+	case LittleKiller:
+		low, high = len(added.cells), len(added.cells)*side
+	case Skyscraper:
+		low, high = 1, side
+	case XSum:
+		low, high = 1, side*(side+1)/2
+	}
+	if value < low || value > high {
+		return fmt.Errorf("a %v clue of %d is out of range", kind, value)
+	}
+
+	b.puzzle.clues = b.puzzle.clues.with(added)
+	return nil
+}
+
+// isHouse returns true if some row or column holds just the coords given
+func (s shape) isHouse(cells []coord) bool {
+	refs, err := s.clustersAt(cells[0])
+	if err != nil {
+		return false
+	}
+	empty := s.empty()
+	for _, ref := range refs {
+		if ref.orient != boardRow && ref.orient != boardCol {
+			continue
+		}
+		house, err := pickCluster(empty, ref)
+		if err != nil || len(house) != len(cells) {
+			continue
+		}
+		in := make(map[coord]bool)
+		for _, each := range house {
+			in[each.location] = true
+		}
+		all := true
+		for _, each := range cells {
+			all = all && in[each]
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// Clues returns every clue outside the grid, in the order they were added.
+func (b *Board) Clues() []Clue {
+	if b.puzzle.clues == nil {
+		return nil
+	}
+	result := make([]Clue, len(b.puzzle.clues.clues))
+	for i, each := range b.puzzle.clues.clues {
+		result[i] = Clue{Kind: each.kind, Value: each.value, Start: position(each.cells[0]), Step: position(each.step)}
+	}
+	return result
+}
@@ -0,0 +1,224 @@
+package sudoku
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	sandwichPuzzle       = "........................2................................1......................."
+	sandwichSolution     = "743928651126357849589416273468231597215794368397685124972163485834572916651849732"
+	littleKillerPuzzle   = "9..8......51..........9..51...7.....5.9....3....................4....325.....5..9"
+	littleKillerSolution = "932851647651472983487396251813764592569128734274539816795213468146987325328645179"
+	skyscraperPuzzle     = ".............2..............7.............2...1................3..........4......"
+	skyscraperSolution   = "748915623136428957259376418472139586983657241615284739861742395327591864594863172"
+	xSumPuzzle           = ".............7.3......3.........................................................."
+	xSumSolution         = "923461578856279314174835962419352687362187495785946123597623841241598736638714259"
+)
+
+// clueValue works out a clue the slow way, from a solution
+func clueValue(kind ClueKind, values [][]int, start, step coord) int {
+	var seen []int
+	for at := start; at.x >= 0 && at.x < len(values) && at.y >= 0 && at.y < len(values); at = (coord{x: at.x + step.x, y: at.y + step.y}) {
+		seen = append(seen, values[at.x][at.y])
+	}
+	result := 0
+	switch kind {
+	case Sandwich:
+		inside := false
+		for _, each := range seen {
+			if each == 1 || each == len(values) {
+				inside = !inside
+			} else if inside {
+				result += each
+			}
+		}
+	case LittleKiller:
+		for _, each := range seen {
+			result += each
+		}
+	case Skyscraper:
+		tallest := 0
+		for _, each := range seen {
+			if each > tallest {
+				tallest = each
+				result++
+			}
+		}
+	case XSum:
+		for _, each := range seen[:seen[0]] {
+			result += each
+		}
+	}
+	return result
+}
+
+// addClues puts clues of a kind around a 9x9, going by the solution -
+// little killers down every other diagonal from the top and up from the
+// bottom, skyscrapers on every side, the rest left of every row and above
+// every column
+func addClues(t *testing.T, b *Board, kind ClueKind, solution string) {
+	values := lineValues(solution)
+	add := func(start, step coord) {
+		if err := b.AddClue(kind, clueValue(kind, values, start, step), position(start), position(step)); err != nil {
+			t.Fatalf("could not add clue - %v", err)
+		}
+	}
+	for i := 0; i < 9; i++ {
+		switch {
+		case kind == LittleKiller:
+			if i > 0 && i < 8 && i%2 == 0 {
+				add(coord{0, i}, coord{1, 1})
+				add(coord{8, i}, coord{-1, -1})
+			}
+		case kind == Skyscraper:
+			add(coord{i, 0}, coord{0, 1})
+			add(coord{0, i}, coord{1, 0})
+			add(coord{i, 8}, coord{0, -1})
+			add(coord{8, i}, coord{-1, 0})
+		default:
+			add(coord{i, 0}, coord{0, 1})
+			add(coord{0, i}, coord{1, 0})
+		}
+	}
+}
+
+func TestClueSupport(t *testing.T) {
+	open := func(values ...int) cell { return cell{possible: NewCandidateSet(values...)} }
+	solved := func(value int) cell { return cell{actual: value} }
+	all := valueSet(4)
+
+	var tests = []struct {
+		kind  ClueKind
+		value int
+		cells cluster
+		keep  []CandidateSet
+	}{
+		// a sandwich of 0 in a 4x4 puts the 1 and 4 side by side
+		{Sandwich, 0, cluster{solved(1), open(2, 3, 4), open(2, 3, 4), open(2, 3, 4)},
+			[]CandidateSet{NewCandidateSet(1), NewCandidateSet(4), NewCandidateSet(2, 3), NewCandidateSet(2, 3)}},
+		// a sandwich of 5 needs both 2 and 3 inside
+		{Sandwich, 5, cluster{open(1, 4), open(2, 3), open(2, 3), open(1, 4)},
+			[]CandidateSet{NewCandidateSet(1, 4), NewCandidateSet(2, 3), NewCandidateSet(2, 3), NewCandidateSet(1, 4)}},
+		{Skyscraper, 4, cluster{open(1, 2, 3, 4), open(1, 2, 3, 4), open(1, 2, 3, 4), open(1, 2, 3, 4)},
+			[]CandidateSet{NewCandidateSet(1), NewCandidateSet(2), NewCandidateSet(3), NewCandidateSet(4)}},
+		{Skyscraper, 1, cluster{open(1, 2, 3, 4), open(1, 2, 3, 4), open(1, 2, 3, 4), open(1, 2, 3, 4)},
+			[]CandidateSet{NewCandidateSet(4), NewCandidateSet(1, 2, 3), NewCandidateSet(1, 2, 3), NewCandidateSet(1, 2, 3)}},
+		// a 1 counts only itself, for 6 a 2 needs a 4 after it, and a 3 a 1 and 2
+		{XSum, 1, cluster{open(1, 2, 3), open(1, 2, 3, 4), open(1, 2, 3, 4), open(1, 2, 3, 4)},
+			[]CandidateSet{NewCandidateSet(1), all, all, all}},
+		{XSum, 6, cluster{open(1, 2, 3), open(1, 2, 3, 4), open(1, 2, 3, 4), open(1, 2, 3, 4)},
+			[]CandidateSet{NewCandidateSet(2, 3), NewCandidateSet(1, 2, 4), all, all}},
+	}
+
+	for id, testRun := range tests {
+		var keep []CandidateSet
+		switch testRun.kind {
+		case Sandwich:
+			keep = sandwichSupport(testRun.value, 4, testRun.cells)
+		case Skyscraper:
+			keep = skyscraperSupport(testRun.value, 4, testRun.cells)
+		default:
+			keep = xSumSupport(testRun.value, 4, testRun.cells)
+		}
+		for i := range keep {
+			assert.True(t, testRun.keep[i].Equal(keep[i]), "test %d - cell %d kept %v, expected %v", id, i, keep[i], testRun.keep[i])
+		}
+	}
+}
+
+func TestAddClue(t *testing.T) {
+	b := NewBoard(2)
+	assert.Error(t, b.AddClue(Sandwich, 5, Position{0, 1}, Position{0, 1}), "not at the edge")
+	assert.Error(t, b.AddClue(Sandwich, 5, Position{0, 0}, Position{1, 1}), "a sandwich looks straight")
+	assert.Error(t, b.AddClue(LittleKiller, 5, Position{0, 0}, Position{0, 1}), "a little killer looks diagonally")
+	assert.Error(t, b.AddClue(Skyscraper, 5, Position{0, 0}, Position{0, 1}), "too many to see")
+	assert.Error(t, b.AddClue(Sandwich, 6, Position{0, 0}, Position{0, 1}), "too big a sandwich")
+	assert.Error(t, b.AddClue(LittleKiller, 1, Position{0, 1}, Position{1, 1}), "too small for three cells")
+	assert.Error(t, b.AddClue(XSum, 5, Position{4, 0}, Position{0, 1}), "off the board")
+	assert.Error(t, b.AddClue(ClueKind(9), 5, Position{0, 0}, Position{0, 1}), "no such clue")
+	assert.NoError(t, b.AddClue(Sandwich, 5, Position{0, 3}, Position{0, -1}))
+	assert.NoError(t, b.AddClue(LittleKiller, 6, Position{3, 1}, Position{-1, 1}))
+	assert.Equal(t, []Clue{
+		{Sandwich, 5, Position{0, 3}, Position{0, -1}},
+		{LittleKiller, 6, Position{3, 1}, Position{-1, 1}},
+	}, b.Clues())
+	assert.Equal(t, []coord{{0, 3}, {0, 2}, {0, 1}, {0, 0}}, b.puzzle.clues.clues[0].cells, "in order from the clue")
+	assert.Equal(t, []coord{{3, 1}, {2, 2}, {1, 3}}, b.puzzle.clues.clues[1].cells)
+
+	jigsaw, err := NewJigsawBoard(regionIds("AAB", "ABB", "CCC"))
+	assert.NoError(t, err)
+	assert.NoError(t, jigsaw.AddClue(Skyscraper, 2, Position{2, 0}, Position{0, 1}), "rows are rows")
+}
+
+func TestSolveClues(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var tests = []struct {
+		kind             ClueKind
+		puzzle, solution string
+	}{
+		{Sandwich, sandwichPuzzle, sandwichSolution},
+		{LittleKiller, littleKillerPuzzle, littleKillerSolution},
+		{Skyscraper, skyscraperPuzzle, skyscraperSolution},
+		{XSum, xSumPuzzle, xSumSolution},
+	}
+
+	for id, testRun := range tests {
+		b, err := Parse(strings.NewReader(testRun.puzzle))
+		if !assert.NoError(t, err, "test %d - could not parse", id) {
+			continue
+		}
+		addClues(t, b, testRun.kind, testRun.solution)
+		result, err := NewFromBoard(b).Solve(ctx)
+		assert.NoError(t, err, "test %d - could not solve", id)
+		assert.Equal(t, Solved, result.Status, "test %d - puzzle should be solved", id)
+		assert.Equal(t, lineValues(testRun.solution), result.Values, "test %d - wrong solution", id)
+		assert.True(t, IsUnique(b), "test %d - puzzle should be unique", id)
+	}
+
+	b, err := Parse(strings.NewReader(sandwichPuzzle))
+	assert.NoError(t, err)
+	addClues(t, b, Sandwich, sandwichSolution)
+	_, report := Grade(b)
+	assert.Equal(t, 0, report.Guesses, "the sandwiches should be enough")
+	assert.NotZero(t, report.Counts[RuleSandwich])
+}
+
+func TestClueContradiction(t *testing.T) {
+	// fine as a plain puzzle, but the row can't be seen that way
+	b := NewBoard(2)
+	assert.NoError(t, b.Set(0, 0, 4))
+	assert.NoError(t, b.AddClue(Skyscraper, 2, Position{0, 0}, Position{0, 1}))
+
+	_, err := NewFromBoard(b).Solve(context.Background())
+	assert.ErrorIs(t, err, ErrContradiction)
+}
+
+func TestFormatClues(t *testing.T) {
+	in := `sandwich: r1c1 right 12
+littlekiller: r9c2 upright 20
+skyscraper: r1c9 down 3
+xsum: r4c9 left 15
+` + strings.Repeat(".", 81) + "\n"
+	b, err := Parse(strings.NewReader(in))
+	if !assert.NoError(t, err, "could not parse") {
+		return
+	}
+	assert.Len(t, b.Clues(), 4)
+
+	var out bytes.Buffer
+	assert.NoError(t, Format(&out, b, LineStyle))
+	assert.Equal(t, in, out.String(), "did not round trip")
+
+	for id, each := range []string{"sandwich: r1c1 right", "sandwich: r1c1 across 12", "xsum: 1,1 left 12", "skyscraper: r1c1 right three"} {
+		_, err := Parse(strings.NewReader(each + "\n" + strings.Repeat(".", 81)))
+		assert.IsType(t, &ParseError{}, err, "test %d - should not parse", id)
+	}
+}
@@ -0,0 +1,268 @@
+package sudoku
+
+// A puzzle made of houses alone is an exact cover problem - pick one value
+// for every cell so that every house holds every value once. Each value a
+// cell can hold is a row of a matrix, with a column for the cell and one for
+// the value in each house the cell sits in, and a solution is a set of rows
+// that fills every column exactly once. Knuth's Algorithm X finds those sets,
+// always trying the column with the fewest rows left, and dancing links keep
+// the matrix cheap to take apart and put back together as it goes. It makes
+// no deductions, so there is nothing to trace or grade - but it is far faster
+// at finding and counting solutions than the rules are.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupported is returned when a Solver's Backend can't handle the kind of
+// puzzle it was given.
+var ErrUnsupported = errors.New("the backend can't solve this kind of puzzle")
+
+// how many steps the exact cover search takes between looks at its context
+const linksCheck = 1024
+
+// Backend picks how a Solver searches for solutions.
+type Backend int
+
+const (
+	// Propagation runs the rules over the puzzle, and guesses when they
+	// stall. It handles every kind of puzzle.
+	Propagation Backend = iota
+	// DancingLinks solves the puzzle as an exact cover problem. It only
+	// handles puzzles made of rows, columns, boxes or regions, and extra
+	// clusters - anything else is ErrUnsupported.
+	DancingLinks
+)
+
+func (b Backend) String() string {
+	switch b {
+	case Propagation:
+		return "propagation"
+	case DancingLinks:
+		return "dancingLinks"
+	default:
+		return fmt.Sprintf("Backend(%d)", int(b))
+	}
+}
+
+// exactCover is a matrix of dancing links. Node 0 is the root, and nodes 1
+// thru the number of columns are the column headers - every other node is a
+// 1 in the matrix, linked to its neighbors in its row and its column.
+// size[c] is the number of rows left in column c, and row[i] the row node i
+// sits in - each row places candidates[row] on the board.
+type exactCover struct {
+	left, right, up, down []int
+	column, row           []int
+	size                  []int
+	candidates            []candidate
+}
+
+// newExactCover builds the matrix for a board - every value left in every
+// cell, or just its value if it has one
+func newExactCover(in board) (*exactCover, error) {
+	for _, orient := range []int{boardCage, boardPair, boardLine, boardClue} {
+		if in.clusterCount(orient) > 0 {
+			return nil, ErrUnsupported
+		}
+	}
+	side := in.side()
+	index := make(map[coord]int)
+	var cells []coord
+	for x := 0; x < in.rows(); x++ {
+		for y := 0; y < in.cols(); y++ {
+			if at := (coord{x: x, y: y}); in.onBoard(at) {
+				index[at] = len(cells)
+				cells = append(cells, at)
+			}
+		}
+	}
+	// the houses each cell sits in
+	houses := 0
+	at := make([][]int, len(cells))
+	for orient := boardRow; orient <= boardExtra; orient++ {
+		for pos := 0; pos < in.clusterCount(orient); pos++ {
+			house, err := pickCluster(in, clusterRef{orient: orient, index: pos})
+			if err != nil {
+				return nil, err
+			}
+			for _, each := range house {
+				i := index[each.location]
+				at[i] = append(at[i], houses)
+			}
+			houses++
+		}
+	}
+
+	m := newMatrix(len(cells) + houses*side)
+	for i, c := range cells {
+		values := in.clusters[c.x][c.y].possible
+		if actual := in.clusters[c.x][c.y].actual; actual != 0 {
+			values = NewCandidateSet(actual)
+		}
+		values.Each(func(value int) {
+			columns := []int{i}
+			for _, house := range at[i] {
+				columns = append(columns, len(cells)+house*side+value-1)
+			}
+			m.addRow(candidate{location: c, value: value}, columns)
+		})
+	}
+	return m, nil
+}
+
+// newMatrix returns an empty matrix with some columns
+func newMatrix(columns int) *exactCover {
+	m := &exactCover{size: make([]int, columns+1)}
+	for i := 0; i <= columns; i++ {
+		m.left = append(m.left, (i+columns)%(columns+1))
+		m.right = append(m.right, (i+1)%(columns+1))
+		m.up = append(m.up, i)
+		m.down = append(m.down, i)
+		m.column = append(m.column, i)
+		m.row = append(m.row, -1)
+	}
+	return m
+}
+
+// addRow adds a row for a candidate, with a 1 in each of some columns -
+// counted from 0
+func (m *exactCover) addRow(c candidate, columns []int) {
+	first := len(m.left)
+	for i, each := range columns {
+		node, header := first+i, each+1
+		m.left = append(m.left, first+(i+len(columns)-1)%len(columns))
+		m.right = append(m.right, first+(i+1)%len(columns))
+		m.up = append(m.up, m.up[header])
+		m.down = append(m.down, header)
+		m.column = append(m.column, header)
+		m.row = append(m.row, len(m.candidates))
+		m.down[m.up[header]], m.up[header] = node, node
+		m.size[header]++
+	}
+	m.candidates = append(m.candidates, c)
+}
+
+// cover takes a column out of the matrix, along with every row that has a 1
+// in it
+func (m *exactCover) cover(c int) {
+	m.right[m.left[c]], m.left[m.right[c]] = m.right[c], m.left[c]
+	for i := m.down[c]; i != c; i = m.down[i] {
+		for j := m.right[i]; j != i; j = m.right[j] {
+			m.down[m.up[j]], m.up[m.down[j]] = m.down[j], m.up[j]
+			m.size[m.column[j]]--
+		}
+	}
+}
+
+// uncover puts back a column taken out by cover
+func (m *exactCover) uncover(c int) {
+	for i := m.up[c]; i != c; i = m.up[i] {
+		for j := m.left[i]; j != i; j = m.left[j] {
+			m.size[m.column[j]]++
+			m.down[m.up[j]], m.up[m.down[j]] = j, j
+		}
+	}
+	m.right[m.left[c]], m.left[m.right[c]] = c, c
+}
+
+// search hands every exact cover of the matrix to found, as the rows picked,
+// until found returns false. The matrix is left as it was. The error is only
+// ever ctx's.
+func (m *exactCover) search(ctx context.Context, found func(rows []int) bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var picked []int
+	steps := 0
+	// solve returns false once the search should stop
+	var solve func() (bool, error)
+	solve = func() (bool, error) {
+		if m.right[0] == 0 {
+			return found(picked), nil
+		}
+		if steps++; steps%linksCheck == 0 {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+		}
+		// the column with the fewest rows left
+		c := m.right[0]
+		for j := m.right[c]; j != 0; j = m.right[j] {
+			if m.size[j] < m.size[c] {
+				c = j
+			}
+		}
+		m.cover(c)
+		more := true
+		var err error
+		for r := m.down[c]; r != c && more; r = m.down[r] {
+			picked = append(picked, m.row[r])
+			for j := m.right[r]; j != r; j = m.right[j] {
+				m.cover(m.column[j])
+			}
+			more, err = solve()
+			for j := m.left[r]; j != r; j = m.left[j] {
+				m.uncover(m.column[j])
+			}
+			picked = picked[:len(picked)-1]
+		}
+		m.uncover(c)
+		return more, err
+	}
+	_, err := solve()
+	return err
+}
+
+// grid returns the solution made by some rows, on a board of the shape of in
+func (m *exactCover) grid(in board, rows []int) Grid {
+	result := Grid{Status: Solved, Values: make([][]int, len(in.clusters)), shape: in.shape}
+	for x, row := range in.clusters {
+		result.Values[x] = make([]int, len(row))
+	}
+	for _, each := range rows {
+		c := m.candidates[each]
+		result.Values[c.location.x][c.location.y] = c.value
+	}
+	return result
+}
+
+// links runs the exact cover search over the puzzle, handing each solution
+// to found, if set, until n are found - or every one, if n is 0. It returns
+// the number of solutions found.
+func (s *Solver) links(ctx context.Context, n int, found func(Grid)) (int, error) {
+	m, err := newExactCover(s.puzzle.puzzle)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	err = m.search(ctx, func(rows []int) bool {
+		count++
+		if found != nil {
+			found(m.grid(s.puzzle.puzzle, rows))
+		}
+		return n < 1 || count < n
+	})
+	return count, err
+}
+
+// solveLinks is Solve for the DancingLinks backend
+func (s *Solver) solveLinks(ctx context.Context) (Grid, error) {
+	var solution Grid
+	count, err := s.links(ctx, 1, func(found Grid) {
+		solution = found
+	})
+	switch {
+	case err != nil:
+		result := makeGrid(s.puzzle.puzzle)
+		result.Status = Stalled
+		return result, err
+	case count == 0:
+		result := makeGrid(s.puzzle.puzzle)
+		result.Status = Contradiction
+		return result, fmt.Errorf("%w: no way to fill the houses", ErrContradiction)
+	default:
+		return solution, nil
+	}
+}
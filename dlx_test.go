@@ -0,0 +1,126 @@
+package sudoku
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSolveLinks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var tests = []struct {
+		puzzle, solution string
+		add              func(*Board) error
+	}{
+		{chainPuzzle, chainSolution, nil},
+		{sixPuzzle, sixSolution, nil},
+		{twelvePuzzle, twelveSolution, nil},
+		{xPuzzle, xSolution, (*Board).AddDiagonals},
+		{windokuPuzzle, windokuSolution, (*Board).AddWindows},
+		{samuraiPuzzle, samuraiSolution, nil},
+	}
+
+	for id, testRun := range tests {
+		b, err := Parse(strings.NewReader(testRun.puzzle))
+		if !assert.NoError(t, err, "test %d - could not parse", id) {
+			continue
+		}
+		if testRun.add != nil {
+			assert.NoError(t, testRun.add(b), "test %d - could not add clusters", id)
+		}
+		want, err := Parse(strings.NewReader(testRun.solution))
+		assert.NoError(t, err, "test %d - could not parse the solution", id)
+
+		s := NewFromBoard(b)
+		s.Backend = DancingLinks
+		result, err := s.Solve(ctx)
+		assert.NoError(t, err, "test %d - could not solve", id)
+		assert.Equal(t, Solved, result.Status, "test %d - puzzle should be solved", id)
+		assert.Equal(t, makeGrid(want.puzzle).Values, result.Values, "test %d - wrong solution", id)
+	}
+}
+
+func TestCountLinks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	chain, err := Parse(strings.NewReader(chainPuzzle))
+	assert.NoError(t, err)
+	broken := NewBoard(3)
+	assert.NoError(t, broken.Set(0, 0, 5))
+	assert.NoError(t, broken.Set(0, 8, 5))
+	diagonal := NewBoard(2)
+	assert.NoError(t, diagonal.AddDiagonals())
+
+	var tests = []struct {
+		b     *Board
+		n     int
+		count int
+	}{
+		{NewBoard(2), 0, 288},
+		// stops early
+		{NewBoard(2), 10, 10},
+		{diagonal, 0, 48},
+		{chain, 0, 1},
+		{broken, 0, 0},
+	}
+
+	for id, testRun := range tests {
+		for _, backend := range []Backend{Propagation, DancingLinks} {
+			s := NewFromBoard(testRun.b)
+			s.Backend = backend
+			count, err := s.Count(ctx, testRun.n)
+			assert.NoError(t, err, "test %d - %v failed", id, backend)
+			assert.Equal(t, testRun.count, count, "test %d - %v counted wrong", id, backend)
+		}
+	}
+
+	s := New(2)
+	s.Backend = DancingLinks
+	solutions, err := s.Solutions(ctx, 3)
+	assert.NoError(t, err)
+	if assert.Len(t, solutions, 3) {
+		assert.NotEqual(t, solutions[0].Values, solutions[1].Values, "the same solution twice")
+		assert.Equal(t, Solved, solutions[2].Status)
+	}
+}
+
+func TestLinksProblems(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := New(3)
+	s.Backend = DancingLinks
+	assert.NoError(t, s.Set(0, 0, 5))
+	assert.NoError(t, s.Set(0, 8, 5))
+	result, err := s.Solve(ctx)
+	assert.True(t, errors.Is(err, ErrContradiction), "expected a contradiction, got %v", err)
+	assert.Equal(t, Contradiction, result.Status)
+	assert.Equal(t, 5, result.Values[0][8], "given value lost")
+
+	killer := NewBoard(3)
+	loadCages(t, killer, []string{"3 r1c1 r1c2"})
+	s = NewFromBoard(killer)
+	s.Backend = DancingLinks
+	result, err = s.Solve(ctx)
+	assert.Equal(t, ErrUnsupported, err)
+	assert.Equal(t, Stalled, result.Status)
+	_, err = s.Count(ctx, 0)
+	assert.Equal(t, ErrUnsupported, err)
+
+	cancelled, stop := context.WithCancel(context.Background())
+	stop()
+	s = loadLine(t, easyPuzzle)
+	s.Backend = DancingLinks
+	_, err = s.Solve(cancelled)
+	assert.Equal(t, context.Canceled, err)
+
+	assert.Equal(t, "dancingLinks", DancingLinks.String())
+	assert.Equal(t, "Backend(7)", Backend(7).String())
+}
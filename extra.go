@@ -0,0 +1,151 @@
+package sudoku
+
+// Variants like X-Sudoku and Windoku add clusters on top of the rows, columns
+// and boxes - each one more set of cells that holds every value once. The
+// rules don't care where a cluster's cells are, so an extra cluster gets a
+// worker of its own like any other.
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// extraClusters lists the boardExtra clusters of a shape. cells[i] holds the
+// coords of cluster i, and at[c] the clusters coord c sits in.
+type extraClusters struct {
+	cells [][]coord
+	at    map[coord][]int
+}
+
+// with returns the extra clusters with one more added on the end - the
+// clusters it is called on are left alone, other boards may share them
+func (e *extraClusters) with(cells []coord) *extraClusters {
+	result := &extraClusters{at: make(map[coord][]int)}
+	if e != nil {
+		result.cells = append(result.cells, e.cells...)
+		for at, indexes := range e.at {
+			result.at[at] = append([]int{}, indexes...)
+		}
+	}
+	index := len(result.cells)
+	result.cells = append(result.cells, cells)
+	for _, each := range cells {
+		result.at[each] = append(result.at[each], index)
+	}
+	return result
+}
+
+// AddCluster adds a cluster of cells that must hold every value once, on top
+// of the rows, columns and boxes. There must be as many cells as there are
+// rows, and no two the same.
+func (b *Board) AddCluster(cells []Position) error {
+	side := b.puzzle.side()
+	if len(cells) != side {
+		return fmt.Errorf("cluster has %d cells, expected %d", len(cells), side)
+	}
+
+	seen := make(map[coord]bool)
+	var coords []coord
+	for _, each := range cells {
+		at := coord{x: each.Row, y: each.Col}
+		if !b.puzzle.onBoard(at) {
+			return fmt.Errorf("cell %v is off the board", each)
+		}
+		if seen[at] {
+			return fmt.Errorf("cell %v is in the cluster twice", each)
+		}
+		seen[at] = true
+		coords = append(coords, at)
+	}
+	sort.Slice(coords, func(i, j int) bool {
+		return coords[i].x < coords[j].x || coords[i].x == coords[j].x && coords[i].y < coords[j].y
+	})
+
+	if extras := b.puzzle.extras; extras != nil {
+		for _, existing := range extras.cells {
+			if sameCoords(existing, coords) {
+				return errors.New("the board already has that cluster")
+			}
+		}
+	}
+	b.puzzle.extras = b.puzzle.extras.with(coords)
+	return nil
+}
+
+// sameCoords returns true if both sorted lists hold the same coords
+func sameCoords(a, b []coord) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// AddDiagonals adds the two main diagonals as clusters, making the puzzle an
+// X-Sudoku.
+func (b *Board) AddDiagonals() error {
+	if b.puzzle.layout != nil {
+		return errors.New("a puzzle of several grids has no main diagonals")
+	}
+	side := b.puzzle.side()
+	down, up := make([]Position, side), make([]Position, side)
+	for i := 0; i < side; i++ {
+		down[i] = Position{Row: i, Col: i}
+		up[i] = Position{Row: i, Col: side - 1 - i}
+	}
+	if err := b.AddCluster(down); err != nil {
+		return err
+	}
+	return b.AddCluster(up)
+}
+
+// AddWindows adds the windows of a Windoku as clusters - boxes set in from
+// the edge of the board by one cell, with a gap of one cell between them. On
+// a 9x9 there are four.
+func (b *Board) AddWindows() error {
+	if b.Jigsaw() {
+		return errors.New("a jigsaw puzzle has no boxes to set windows between")
+	}
+	if b.puzzle.layout != nil {
+		return errors.New("a puzzle of several grids has no one set of windows")
+	}
+	width, height := b.Box()
+	side := b.puzzle.side()
+	added := false
+	for row := 1; row+height <= side; row += height + 1 {
+		for col := 1; col+width <= side; col += width + 1 {
+			var window []Position
+			for x := row; x < row+height; x++ {
+				for y := col; y < col+width; y++ {
+					window = append(window, Position{Row: x, Col: y})
+				}
+			}
+			if err := b.AddCluster(window); err != nil {
+				return err
+			}
+			added = true
+		}
+	}
+	if !added {
+		return errors.New("the board is too small for windows")
+	}
+	return nil
+}
+
+// Extras returns the cells of every cluster added on top of the rows, columns
+// and boxes, in the order they were added.
+func (b *Board) Extras() [][]Position {
+	if b.puzzle.extras == nil {
+		return nil
+	}
+	result := make([][]Position, len(b.puzzle.extras.cells))
+	for i, cells := range b.puzzle.extras.cells {
+		result[i] = positions(cells)
+	}
+	return result
+}
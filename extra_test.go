@@ -0,0 +1,124 @@
+package sudoku
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	xPuzzle         = ".....7...5....3...739.8............6.7...24..3..64.......16.....4.9....1........5"
+	xSolution       = "412597368568413927739286514824759136671832459395641872957168243243975681186324795"
+	windokuPuzzle   = "........8.........9...68..1.5.2...7...79..5......1.9..8.1..........4......4..9..."
+	windokuSolution = "412597368568123497973468251359286174187934526246715983891372645625841739734659812"
+)
+
+func TestSolveExtras(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var tests = []struct {
+		puzzle, solution string
+		add              func(*Board) error
+		extras           int
+	}{
+		{xPuzzle, xSolution, (*Board).AddDiagonals, 2},
+		{windokuPuzzle, windokuSolution, (*Board).AddWindows, 4},
+	}
+
+	for id, testRun := range tests {
+		b, err := Parse(strings.NewReader(testRun.puzzle))
+		if !assert.NoError(t, err, "test %d - could not parse", id) {
+			continue
+		}
+		assert.False(t, IsUnique(b), "test %d - the extra clusters should matter", id)
+
+		assert.NoError(t, testRun.add(b), "test %d - could not add clusters", id)
+		assert.Len(t, b.Extras(), testRun.extras, "test %d - wrong number of clusters", id)
+		result, err := NewFromBoard(b).Solve(ctx)
+		assert.NoError(t, err, "test %d - could not solve", id)
+		assert.Equal(t, Solved, result.Status, "test %d - puzzle should be solved", id)
+		assert.Equal(t, lineValues(testRun.solution), result.Values, "test %d - wrong solution", id)
+		assert.True(t, IsUnique(b), "test %d - puzzle should be unique", id)
+	}
+}
+
+func TestExtraContradiction(t *testing.T) {
+	// fine as a plain puzzle, but the 1s share a diagonal
+	b := NewBoard(2)
+	assert.NoError(t, b.Set(0, 0, 1))
+	assert.NoError(t, b.Set(3, 3, 1))
+	assert.NoError(t, b.AddDiagonals())
+
+	_, err := NewFromBoard(b).Solve(context.Background())
+	assert.ErrorIs(t, err, ErrContradiction)
+}
+
+func TestAddCluster(t *testing.T) {
+	b := NewBoard(2)
+	assert.Error(t, b.AddCluster([]Position{{0, 0}, {1, 1}, {2, 2}}), "too few cells")
+	assert.Error(t, b.AddCluster([]Position{{0, 0}, {1, 1}, {2, 2}, {4, 4}}), "off the board")
+	assert.Error(t, b.AddCluster([]Position{{0, 0}, {1, 1}, {2, 2}, {2, 2}}), "the same cell twice")
+	assert.NoError(t, b.AddCluster([]Position{{0, 0}, {1, 1}, {2, 2}, {3, 3}}))
+	assert.Error(t, b.AddCluster([]Position{{3, 3}, {2, 2}, {1, 1}, {0, 0}}), "the same cluster twice")
+	assert.Error(t, b.AddDiagonals(), "the diagonal is already there")
+
+	jigsaw, err := NewJigsawBoard(regionIds("AAB", "ABB", "CCC"))
+	assert.NoError(t, err)
+	assert.Error(t, jigsaw.AddWindows(), "windows need boxes")
+	assert.Nil(t, jigsaw.Extras())
+}
+
+func TestClustersAt(t *testing.T) {
+	b := NewBoard(3)
+	assert.NoError(t, b.AddDiagonals())
+	assert.NoError(t, b.AddWindows())
+
+	var tests = []struct {
+		at   coord
+		refs []clusterRef
+	}{
+		{coord{x: 0, y: 1}, []clusterRef{{boardRow, 0}, {boardCol, 1}, {boardSquare, 0}}},
+		{coord{x: 4, y: 4}, []clusterRef{{boardRow, 4}, {boardCol, 4}, {boardSquare, 4}, {boardExtra, 0}, {boardExtra, 1}}},
+		{coord{x: 1, y: 1}, []clusterRef{{boardRow, 1}, {boardCol, 1}, {boardSquare, 0}, {boardExtra, 0}, {boardExtra, 2}}},
+		{coord{x: 6, y: 7}, []clusterRef{{boardRow, 6}, {boardCol, 7}, {boardSquare, 8}, {boardExtra, 5}}},
+	}
+
+	for id, testRun := range tests {
+		refs, err := b.puzzle.clustersAt(testRun.at)
+		assert.NoError(t, err, "test %d - bad coord", id)
+		assert.Equal(t, testRun.refs, withoutDerived(refs), "test %d - wrong clusters", id)
+		for _, ref := range refs {
+			picked, err := pickCluster(b.puzzle, ref)
+			assert.NoError(t, err, "test %d - bad cluster", id)
+			assert.Contains(t, picked, b.puzzle.clusters[testRun.at.x][testRun.at.y], "test %d - %v not in %v", id, testRun.at, ref)
+		}
+	}
+}
+
+func TestFormatExtras(t *testing.T) {
+	in := `extra: r1c1 r2c2 r3c3 r4c4
+extra: r1c4 r2c3 r3c2 r4c1
+1...............
+`
+	b, err := Parse(strings.NewReader(in))
+	if !assert.NoError(t, err, "could not parse") {
+		return
+	}
+	diagonals := NewBoard(2)
+	assert.NoError(t, diagonals.AddDiagonals())
+	assert.Equal(t, diagonals.Extras(), b.Extras(), "should be the diagonals")
+
+	var out bytes.Buffer
+	assert.NoError(t, Format(&out, b, LineStyle))
+	assert.Equal(t, in, out.String(), "did not round trip")
+
+	for id, each := range []string{"extra: r1c1 r2c2 r3c3", "extra: r1c1 r2c2 r3c3 4,4", "extra: r1c1 r1c1 r3c3 r4c4"} {
+		_, err := Parse(strings.NewReader(each + "\n" + strings.Repeat(".", 16)))
+		assert.IsType(t, &ParseError{}, err, "test %d - should not parse", id)
+	}
+}
@@ -0,0 +1,276 @@
+package sudoku
+
+// Fish look at one value over several rows at once. If the only places left
+// for a value in n rows sit in just n columns, each of those columns takes
+// its value from one of the rows, so the rest of the columns lose it - an
+// X-Wing for two rows, a Swordfish for three, a Jellyfish for four. The same
+// goes for columns over rows. A finned fish has a few places left over, the
+// fins, all in one box - either a fin holds the value or the fish does, so
+// the cells of the columns in that box lose it. A sashimi fish is a finned
+// fish missing a corner, and is found the same way.
+//
+// No cluster of a grid sees more than one row, so every grid gets a fish
+// cluster of all its cells for each way round, with a worker to look at
+// every row or column of it at once.
+
+import (
+	"fmt"
+)
+
+// the most rows or columns a fish is made of
+const fishLimit = 4
+
+// fish is every cell of a grid, base house by base house. base[i], cover[i]
+// and box[i] are the base house, cover house and square of the grid that
+// cell i sits in - rows, columns and squares when the base houses are rows,
+// and columns, rows and squares when they are columns. spread is the most
+// cover houses any one square reaches into, and across[k] the cells of
+// cover house k.
+type fish struct {
+	cells  []coord
+	base   []int
+	cover  []int
+	box    []int
+	spread int
+	across [][]int
+}
+
+// fishList lists the boardFish clusters of a shape. at[c] holds the fish
+// clusters coord c sits in.
+type fishList struct {
+	fish []fish
+	at   map[coord][]int
+}
+
+// newFishList builds a fish cluster of rows and one of columns for every grid
+// of a shape
+func newFishList(s shape) *fishList {
+	empty := s.empty()
+	side := s.side()
+	grids := 1
+	if s.layout != nil {
+		grids = len(s.layout.corners)
+	}
+
+	result := &fishList{at: make(map[coord][]int)}
+	for g := 0; g < grids; g++ {
+		for _, orient := range []int{boardRow, boardCol} {
+			// which house of each kind in the grid every cell sits in
+			houses := make([]map[coord]int, boardSquare+1)
+			for kind := boardRow; kind <= boardSquare; kind++ {
+				houses[kind] = make(map[coord]int)
+				for pos := 0; pos < side; pos++ {
+					house, err := pickCluster(empty, clusterRef{orient: kind, index: g*side + pos})
+					if err != nil {
+						continue
+					}
+					for _, each := range house {
+						houses[kind][each.location] = pos
+					}
+				}
+			}
+			other := boardCol
+			if orient == boardCol {
+				other = boardRow
+			}
+
+			var school fish
+			for pos := 0; pos < side; pos++ {
+				house, err := pickCluster(empty, clusterRef{orient: orient, index: g*side + pos})
+				if err != nil {
+					continue
+				}
+				for _, each := range house {
+					school.cells = append(school.cells, each.location)
+					school.base = append(school.base, pos)
+					school.cover = append(school.cover, houses[other][each.location])
+					school.box = append(school.box, houses[boardSquare][each.location])
+				}
+			}
+
+			reach := make([]CandidateSet, side)
+			school.across = make([][]int, side)
+			for i := range school.cells {
+				reach[school.box[i]] = reach[school.box[i]].Add(school.cover[i])
+				school.across[school.cover[i]] = append(school.across[school.cover[i]], i)
+			}
+			for _, each := range reach {
+				if each.Count() > school.spread {
+					school.spread = each.Count()
+				}
+			}
+
+			index := len(result.fish)
+			result.fish = append(result.fish, school)
+			for _, each := range school.cells {
+				result.at[each] = append(result.at[each], index)
+			}
+		}
+	}
+	return result
+}
+
+// fishRule returns the rule for a fish of n rows or columns
+func fishRule(n int, finned bool) Rule {
+	switch {
+	case finned:
+		return RuleFinnedFish
+	case n == 2:
+		return RuleXWing
+	case n == 3:
+		return RuleSwordfish
+	default:
+		return RuleJellyfish
+	}
+}
+
+// moves returns the rules for fish cluster index
+func (l *fishList) moves(index, side int, opts settings) moves {
+	school := l.fish[index]
+	return func(cells cluster) ([]change, error) {
+		if opts.level < ruleLevel(RuleXWing, 2) {
+			return nil, nil
+		}
+		finned := opts.level >= ruleLevel(RuleFinnedFish, 2)
+
+		// for each base house, the places left for a value, the cover houses
+		// and boxes they sit in, and the cover houses of the places in each
+		// box - a base house and a cover house share one cell, so the rest
+		// of the cover houses are those of the places outside the box
+		places := make([]CandidateSet, side)
+		covers := make([]CandidateSet, side)
+		boxes := make([]CandidateSet, side)
+		// the base houses with a place left in each cover house
+		reached := make([]CandidateSet, side)
+		inBox := make([][]CandidateSet, side)
+		for house := range inBox {
+			inBox[house] = make([]CandidateSet, side)
+		}
+
+		var changes []change
+		for value := 1; value <= side; value++ {
+			solved := make([]bool, side)
+			for house := 0; house < side; house++ {
+				places[house], covers[house], boxes[house] = CandidateSet{}, CandidateSet{}, CandidateSet{}
+				reached[house] = CandidateSet{}
+				for box := range inBox[house] {
+					inBox[house][box] = CandidateSet{}
+				}
+			}
+			for i, each := range cells {
+				house := school.base[i]
+				switch {
+				case each.actual == value:
+					solved[house] = true
+				case each.actual == 0 && each.possible.Has(value):
+					places[house] = places[house].Add(i)
+					covers[house] = covers[house].Add(school.cover[i])
+					boxes[house] = boxes[house].Add(school.box[i])
+					inBox[house][school.box[i]] = inBox[house][school.box[i]].Add(school.cover[i])
+					reached[school.cover[i]] = reached[school.cover[i]].Add(house)
+				}
+			}
+			var open []int
+			for house := 0; house < side; house++ {
+				if !solved[house] && !places[house].Empty() {
+					open = append(open, house)
+				}
+			}
+
+			// the cells that have lost the value already, so no two fish take
+			// it out of the same cell
+			var dropped CandidateSet
+			var err error
+			// swim tries every set of n open base houses, from open[from] on,
+			// with cover the cover houses of those chosen so far
+			var swim func(n, from int, chosen []int, cover CandidateSet)
+			swim = func(n, from int, chosen []int, cover CandidateSet) {
+				// fins sit in one box, which only spans so many cover houses
+				if err != nil || cover.Count() > n+school.spread || !finned && cover.Count() > n {
+					return
+				}
+				if len(chosen) < n {
+					for next := from; next < len(open); next++ {
+						swim(n, next+1, append(chosen, open[next]), cover.Union(covers[open[next]]))
+					}
+					return
+				}
+				if cover.Count() < n {
+					err = fmt.Errorf("%w: %d houses have room for %d only in %d houses", ErrContradiction,
+						n, value, cover.Count())
+					return
+				}
+
+				var bases CandidateSet
+				for _, house := range chosen {
+					bases = bases.Add(house)
+				}
+				// nothing to do unless the value is left somewhere in the
+				// cover houses outside the base houses
+				outside := false
+				cover.Each(func(house int) {
+					outside = outside || !reached[house].SubsetOf(bases)
+				})
+				if !outside {
+					return
+				}
+
+				// catch takes the value out of every cell of the cover houses
+				// outside the base houses, in box if box isn't -1
+				catch := func(cover CandidateSet, box int, rule Rule) {
+					var cause []coord
+					cover.Each(func(house int) {
+						for _, i := range school.across[house] {
+							each := cells[i]
+							if bases.Has(school.base[i]) || box >= 0 && school.box[i] != box {
+								continue
+							}
+							if each.actual != 0 || !each.possible.Has(value) || dropped.Has(i) {
+								continue
+							}
+							if cause == nil {
+								var found CandidateSet
+								for _, house := range chosen {
+									found = found.Union(places[house])
+								}
+								cause = locations(found, cells)
+							}
+							dropped = dropped.Add(i)
+							changes = append(changes, change{
+								cell:  cell{location: each.location, excluded: NewCandidateSet(value)},
+								rule:  rule,
+								cause: cause})
+						}
+					})
+				}
+
+				if cover.Count() == n {
+					catch(cover, -1, fishRule(n, false))
+					return
+				}
+				// every box the fins could sit in - the places outside it
+				// must make a fish on their own
+				var touched CandidateSet
+				for _, house := range chosen {
+					touched = touched.Union(boxes[house])
+				}
+				touched.Each(func(box int) {
+					var core CandidateSet
+					for _, house := range chosen {
+						core = core.Union(covers[house].Difference(inBox[house][box]))
+					}
+					if core.Count() == n {
+						catch(core, box, fishRule(n, true))
+					}
+				})
+			}
+			for n := 2; n <= fishLimit && n <= len(open); n++ {
+				swim(n, 0, nil, CandidateSet{})
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return changes, nil
+	}
+}
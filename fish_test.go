@@ -0,0 +1,148 @@
+package sudoku
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// a puzzle that needs a swordfish, a jellyfish and finned fish
+const (
+	fishPuzzle   = "19.....4.68.3.......7.8.3..3..1..6..9....87......4...........2.2.5.....3.4..1...8"
+	fishSolution = "193562847682374915457981362374125689921638754568749231839457126215896473746213598"
+)
+
+// withoutValue returns a board with value taken out of some cells
+func withoutValue(t *testing.T, in board, value int, cells ...coord) board {
+	for _, each := range cells {
+		var err error
+		in, _, err = changeBoard(in, cell{location: each, excluded: NewCandidateSet(value)})
+		assert.NoError(t, err)
+	}
+	return in
+}
+
+// rowExcept returns the coords of a row but for some columns
+func rowExcept(row int, cols ...int) []coord {
+	var result []coord
+	for y := 0; y < 9; y++ {
+		skip := false
+		for _, each := range cols {
+			skip = skip || each == y
+		}
+		if !skip {
+			result = append(result, coord{row, y})
+		}
+	}
+	return result
+}
+
+func TestNewFishList(t *testing.T) {
+	samurai, err := NewLayoutBoard(3, 3, SamuraiCorners(3))
+	assert.NoError(t, err)
+
+	var tests = []struct {
+		b      *Board
+		fish   int
+		cells  int
+		spread []int
+	}{
+		{NewBoard(3), 2, 81, []int{3, 3}},
+		// a box reaches three columns, but only two rows
+		{NewBoxBoard(3, 2), 2, 36, []int{3, 2}},
+		{samurai, 10, 81, []int{3, 3, 3, 3, 3, 3, 3, 3, 3, 3}},
+	}
+
+	for id, testRun := range tests {
+		list := testRun.b.puzzle.fish
+		assert.Len(t, list.fish, testRun.fish, "test %d - wrong number of fish", id)
+		for i, each := range list.fish {
+			assert.Len(t, each.cells, testRun.cells, "test %d - wrong number of cells", id)
+			assert.Equal(t, testRun.spread[i], each.spread, "test %d - wrong spread", id)
+		}
+	}
+
+	// the fish of rows, then the fish of columns
+	list := NewBoard(3).puzzle.fish
+	assert.Equal(t, coord{0, 1}, list.fish[0].cells[1])
+	assert.Equal(t, 1, list.fish[0].cover[1])
+	assert.Equal(t, coord{1, 0}, list.fish[1].cells[1])
+	assert.Equal(t, 1, list.fish[1].cover[1])
+	assert.Equal(t, 1, list.fish[1].box[3*9], "column 4 starts in the second box")
+}
+
+func TestFishMoves(t *testing.T) {
+	empty := NewBoard(3).puzzle
+	excluded := func(c coord) cell { return cell{location: c, excluded: NewCandidateSet(5)} }
+	xWing := []coord{{0, 1}, {0, 6}, {4, 1}, {4, 6}}
+
+	var tests = []struct {
+		puzzle  board
+		level   Difficulty
+		changes []change
+	}{
+		// 5 is only in columns 2 and 7 of rows 1 and 5
+		{withoutValue(t, withoutValue(t, empty, 5, rowExcept(0, 1, 6)...), 5, rowExcept(4, 1, 6)...), Hard,
+			func() []change {
+				var result []change
+				for _, col := range []int{1, 6} {
+					for _, row := range []int{1, 2, 3, 5, 6, 7, 8} {
+						result = append(result, change{cell: excluded(coord{row, col}), rule: RuleXWing, cause: xWing})
+					}
+				}
+				return result
+			}()},
+		// the same, but for a fin at r5c9 - only the cells of column 7 in
+		// the box of the fin lose 5
+		{withoutValue(t, withoutValue(t, empty, 5, rowExcept(0, 1, 6)...), 5, rowExcept(4, 1, 6, 8)...), Hard,
+			[]change{
+				{cell: excluded(coord{3, 6}), rule: RuleFinnedFish, cause: append(xWing[:4:4], coord{4, 8})},
+				{cell: excluded(coord{5, 6}), rule: RuleFinnedFish, cause: append(xWing[:4:4], coord{4, 8})},
+			}},
+		// fish are a Hard rule
+		{withoutValue(t, withoutValue(t, empty, 5, rowExcept(0, 1, 6)...), 5, rowExcept(4, 1, 6)...), Medium,
+			nil},
+		// nothing to go on
+		{empty, Hard, nil},
+	}
+
+	for id, testRun := range tests {
+		rows, err := pickCluster(testRun.puzzle, clusterRef{orient: boardFish, index: 0})
+		assert.NoError(t, err)
+		changes, err := empty.fish.moves(0, 9, settings{level: testRun.level})(rows)
+		assert.NoError(t, err, "test %d - unexpected error", id)
+		assert.Equal(t, testRun.changes, changes, "test %d - wrong changes", id)
+	}
+
+	// rows 1 and 5 both need 5 in column 2
+	broken := withoutValue(t, withoutValue(t, empty, 5, rowExcept(0, 1)...), 5, rowExcept(4, 1)...)
+	rows, err := pickCluster(broken, clusterRef{orient: boardFish, index: 0})
+	assert.NoError(t, err)
+	_, err = empty.fish.moves(0, 9, settings{level: Hard})(rows)
+	assert.True(t, errors.Is(err, ErrContradiction), "two rows can't share one column")
+}
+
+func TestSolveFish(t *testing.T) {
+	b, err := Parse(strings.NewReader(fishPuzzle))
+	assert.NoError(t, err)
+	level, report := Grade(b)
+	assert.Equal(t, Hard, level)
+	assert.Equal(t, Solved, report.Status)
+	assert.Zero(t, report.Guesses)
+	assert.NotZero(t, report.Counts[RuleSwordfish], "a swordfish should be used")
+	assert.NotZero(t, report.Counts[RuleFinnedFish], "a finned fish should be used")
+
+	result, err := NewFromBoard(b).Solve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, lineValues(fishSolution), result.Values)
+
+	// without the fish, the rules stall - chains find fish too
+	puzzle := withoutChains(b.puzzle)
+	puzzle.fish = nil
+	stalled, err := propagate(context.Background(), puzzle, settings{level: Hard})
+	assert.NoError(t, err)
+	assert.False(t, stalled.solved(), "the puzzle should need the fish")
+}
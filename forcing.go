@@ -0,0 +1,275 @@
+package sudoku
+
+// When every other rule has stalled, a value can be tried out - placed on a
+// scratch copy of the board, with the rules run over the copy. A value that
+// breaks the copy can't be true (contradiction forcing, or nishio). Trying
+// every value left in a cell, the cell must hold one of them, so whatever all
+// the trials that didn't break agree on is true (cell forcing) - and the same
+// goes for every place left for a value in a house (unit forcing).
+//
+// Trials can be costly, so they are bounded: the trials of a board share a
+// budget, and the trials themselves only try values out as deep as the
+// settings allow. A cell or house the budget runs out on proves nothing.
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// how many values the trials over a board can try out when the settings
+// don't say
+const defaultForcingTrials = 100
+
+// Trial is a value tried out by a forcing rule, and what came of it.
+type Trial struct {
+	// Assumption is the value placed.
+	Assumption Candidate
+	// Path holds the deductions the rules made after the value was placed,
+	// in order - up to the one the trials agree on, or all of them if the
+	// trial broke the board. Their Depth is 0.
+	Path []Event
+	// Contradiction is the problem the trial ran into, or nil if it ran
+	// into none.
+	Contradiction error
+}
+
+// branch is a trial, and the board it left
+type branch struct {
+	trial Trial
+	out   board
+	// finished is false if the trial was cut off
+	finished bool
+}
+
+// upTo returns the trial, with its path cut short after the deduction that
+// placed value in the cell at, or excluded the values of excluded
+func (t Trial) upTo(at Position, value int, excluded CandidateSet) Trial {
+	var lost CandidateSet
+	for i, each := range t.Path {
+		if each.Cell != at {
+			continue
+		}
+		lost = lost.Union(NewCandidateSet(each.Excluded...))
+		if value != 0 && each.Value == value || value == 0 && excluded.SubsetOf(lost) {
+			t.Path = t.Path[:i+1]
+			return t
+		}
+	}
+	return t
+}
+
+// try places a value on a board, and runs the rules over it
+func try(ctx context.Context, in board, at coord, value int, opts settings) branch {
+	result := branch{trial: Trial{Assumption: Candidate{Cell: position(at), Value: value}}}
+	trialOpts := opts
+	trialOpts.forcingDepth--
+	trialOpts.trace = func(u change, before, after cell) {
+		result.trial.Path = append(result.trial.Path, deductionEvent(u, before, after, 0))
+	}
+
+	placed, _, err := changeBoard(in, cell{location: at, actual: value})
+	if err == nil {
+		result.out, err = propagate(ctx, placed, trialOpts)
+	}
+	switch {
+	case errors.Is(err, ErrContradiction):
+		result.trial.Contradiction = err
+		result.finished = true
+	case err == nil:
+		result.finished = true
+	}
+	return result
+}
+
+// rebuild returns a copy of a board holding the cells of a boardWhole cluster
+func rebuild(in board, cells cluster) board {
+	out := board{shape: in.shape, clusters: make([]cluster, len(in.clusters))}
+	for i, row := range in.clusters {
+		out.clusters[i] = append(cluster{}, row...)
+	}
+	for _, each := range cells {
+		out.clusters[each.location.x][each.location.y] = each
+	}
+	return out
+}
+
+// forcer holds the trials run over the cells of a boardWhole cluster, and
+// what they force. branches[i][value] is the trial of value in cell i.
+type forcer struct {
+	cells    cluster
+	view     sight
+	branches []map[int]branch
+	changes  []change
+	dropped  []CandidateSet
+	placed   []bool
+}
+
+// newForcer sets up to try values out in the cells of a boardWhole cluster
+func newForcer(cells cluster, view sight) *forcer {
+	return &forcer{
+		cells:    cells,
+		view:     view,
+		branches: make([]map[int]branch, len(cells)),
+		dropped:  make([]CandidateSet, len(cells)),
+		placed:   make([]bool, len(cells)),
+	}
+}
+
+// tried returns the trials of some values in cell i - a value not tried yet
+// gives a trial that never finished
+func (f *forcer) tried(i int, values ...int) []branch {
+	var result []branch
+	for _, value := range values {
+		result = append(result, f.branches[i][value])
+	}
+	return result
+}
+
+// contradictions takes every value out of cell i that broke the board
+func (f *forcer) contradictions(i int) {
+	for _, each := range f.tried(i, f.cells[i].possible.Values()...) {
+		if each.trial.Contradiction == nil {
+			continue
+		}
+		value := each.trial.Assumption.Value
+		f.dropped[i] = f.dropped[i].Add(value)
+		f.changes = append(f.changes, change{
+			cell:   cell{location: f.cells[i].location, excluded: NewCandidateSet(value)},
+			rule:   RuleContradiction,
+			cause:  []coord{f.cells[i].location},
+			trials: []Trial{each.trial}})
+	}
+}
+
+// units tries unit forcing on the places of each value of cell i in its
+// houses - once every place has been tried
+func (f *forcer) units(i int) {
+	for _, house := range f.view.houses {
+		if !house.Has(i) {
+			continue
+		}
+		f.cells[i].possible.Each(func(value int) {
+			var tried []branch
+			var cause []coord
+			house.Each(func(j int) {
+				if f.cells[j].actual == 0 && f.cells[j].possible.Has(value) {
+					tried = append(tried, f.tried(j, value)...)
+					cause = append(cause, f.cells[j].location)
+				}
+			})
+			f.agree(tried, RuleUnitForcing, cause)
+		})
+	}
+}
+
+// agree makes whatever change every trial that didn't break agrees on - the
+// values tried are all of those left for some cell or place
+func (f *forcer) agree(tried []branch, rule Rule, cause []coord) {
+	var kept []branch
+	for _, each := range tried {
+		if !each.finished {
+			return
+		}
+		if each.trial.Contradiction == nil {
+			kept = append(kept, each)
+		}
+	}
+	if len(kept) < 2 {
+		// a single value left will be found by the other rules
+		return
+	}
+	for j, target := range f.cells {
+		if target.actual != 0 || f.placed[j] {
+			continue
+		}
+		at := target.location
+		value := kept[0].out.clusters[at.x][at.y].actual
+		excluded := kept[0].out.clusters[at.x][at.y].excluded
+		for _, each := range kept[1:] {
+			got := each.out.clusters[at.x][at.y]
+			if got.actual != value {
+				value = 0
+			}
+			excluded = excluded.Intersect(got.excluded)
+		}
+		u := cell{location: at, actual: value}
+		if value == 0 {
+			u.excluded = excluded.Difference(target.excluded).Difference(f.dropped[j])
+			if u.excluded.Empty() {
+				continue
+			}
+			f.dropped[j] = f.dropped[j].Union(u.excluded)
+		} else {
+			f.placed[j] = true
+		}
+		var trials []Trial
+		for _, each := range tried {
+			if each.trial.Contradiction != nil {
+				trials = append(trials, each.trial)
+			} else {
+				trials = append(trials, each.trial.upTo(position(at), u.actual, u.excluded))
+			}
+		}
+		f.changes = append(f.changes, change{cell: u, rule: rule, cause: cause, trials: trials})
+	}
+}
+
+// forcing returns the forcing rules for the whole board
+func (w *wholeBoard) forcing(ctx context.Context, start board, opts settings) moves {
+	var looked bool
+	var view sight
+	limit := opts.forcingTrials
+	if limit <= 0 {
+		limit = defaultForcingTrials
+	}
+	return func(cells cluster) ([]change, error) {
+		if opts.forcingDepth < 1 || opts.level < ruleLevel(RuleContradiction, 1) {
+			return nil, nil
+		}
+		if !looked {
+			var err error
+			if view, err = w.look(start); err != nil {
+				return nil, err
+			}
+			looked = true
+		}
+		current := rebuild(start, cells)
+		f := newForcer(cells, view)
+
+		// the cells with the fewest values left are tried first, as those
+		// are the most likely to force anything
+		var open []int
+		for i, each := range cells {
+			if each.actual == 0 {
+				open = append(open, i)
+			}
+		}
+		sort.SliceStable(open, func(i, j int) bool {
+			return cells[open[i]].possible.Count() < cells[open[j]].possible.Count()
+		})
+
+		// each cell in turn, until a trial forces anything - the cheaper
+		// rules can take it from there
+		trials := 0
+		for _, i := range open {
+			if len(f.changes) > 0 || trials >= limit || ctx.Err() != nil {
+				break
+			}
+			f.branches[i] = make(map[int]branch)
+			cells[i].possible.Each(func(value int) {
+				if trials < limit && ctx.Err() == nil {
+					trials++
+					f.branches[i][value] = try(ctx, current, cells[i].location, value, opts)
+				}
+			})
+			f.contradictions(i)
+			f.agree(f.tried(i, cells[i].possible.Values()...), RuleCellForcing, []coord{cells[i].location})
+			f.units(i)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return f.changes, nil
+	}
+}
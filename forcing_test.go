@@ -0,0 +1,208 @@
+package sudoku
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// escargot stalls every rule but forcing
+const escargot = "1....7.9..3..2...8..96..5....53..9...1..8...26....4...3......1..4......7..7...3.."
+
+func TestTrialUpTo(t *testing.T) {
+	at := Position{Row: 1, Col: 1}
+	trial := Trial{Path: []Event{
+		{Cell: Position{Row: 0, Col: 1}, Excluded: []int{2}},
+		{Cell: at, Excluded: []int{2}},
+		{Cell: at, Excluded: []int{3}},
+		{Cell: at, Value: 4},
+	}}
+
+	var tests = []struct {
+		value    int
+		excluded CandidateSet
+		length   int
+	}{
+		{0, NewCandidateSet(2), 2},
+		{0, NewCandidateSet(2, 3), 3},
+		{4, CandidateSet{}, 4},
+		// never found, so the whole path is kept
+		{1, CandidateSet{}, 4},
+		{0, NewCandidateSet(1), 4},
+	}
+
+	for id, testRun := range tests {
+		cut := trial.upTo(at, testRun.value, testRun.excluded)
+		assert.Len(t, cut.Path, testRun.length, "test %d - wrong path", id)
+	}
+	assert.Len(t, trial.Path, 4, "the trial itself should be left alone")
+}
+
+func TestForcing(t *testing.T) {
+	empty := NewBoard(2).puzzle
+	expert := settings{level: Expert, forcingDepth: 1}
+
+	// r1c1 holds 1 or 2, and r1c2 and r1c3 both 1 or 3 - 1 in r1c1 leaves
+	// them both 3
+	broken := withCandidates(t, empty, coord{0, 0}, 1, 2)
+	broken = withCandidates(t, broken, coord{0, 1}, 1, 3)
+	broken = withCandidates(t, broken, coord{0, 2}, 1, 3)
+
+	// either value of r2c3, 1 or 3, takes 1 out of r4c2
+	agreed := empty
+	for _, each := range []cell{
+		{location: coord{3, 2}, actual: 2},
+		{location: coord{2, 3}, actual: 3},
+		{location: coord{2, 0}, excluded: NewCandidateSet(4)},
+		{location: coord{0, 0}, excluded: NewCandidateSet(4)},
+		{location: coord{1, 1}, excluded: NewCandidateSet(2)},
+	} {
+		var err error
+		agreed, _, err = changeBoard(agreed, each)
+		assert.NoError(t, err)
+	}
+	agreed, err := propagate(context.Background(), agreed, settings{level: Expert})
+	assert.NoError(t, err)
+
+	var tests = []struct {
+		puzzle  board
+		opts    settings
+		changes []change
+		tried   [][]int
+	}{
+		{broken, expert, []change{
+			{cell: cell{location: coord{0, 0}, excluded: NewCandidateSet(1)}, rule: RuleContradiction,
+				cause: []coord{{0, 0}}},
+		}, [][]int{{1}}},
+		{agreed, expert, []change{
+			{cell: cell{location: coord{3, 1}, excluded: NewCandidateSet(1)}, rule: RuleCellForcing,
+				cause: []coord{{1, 2}}},
+		}, [][]int{{1, 3}}},
+		// forcing is an Expert rule, and off without any depth
+		{broken, settings{level: Hard, forcingDepth: 1}, nil, nil},
+		{broken, settings{level: Expert}, nil, nil},
+		// out of trials before the first cell is done
+		{agreed, settings{level: Expert, forcingDepth: 1, forcingTrials: 1}, nil, nil},
+	}
+
+	for id, testRun := range tests {
+		cells, err := pickCluster(testRun.puzzle, clusterRef{orient: boardForcing})
+		assert.NoError(t, err)
+		changes, err := empty.whole.forcing(context.Background(), testRun.puzzle, testRun.opts)(cells)
+		assert.NoError(t, err, "test %d - unexpected error", id)
+
+		var tried [][]int
+		for i := range changes {
+			var values []int
+			for _, each := range changes[i].trials {
+				values = append(values, each.Assumption.Value)
+				if each.Contradiction != nil {
+					assert.True(t, errors.Is(each.Contradiction, ErrContradiction), "test %d - wrong problem", id)
+					continue
+				}
+				last := each.Path[len(each.Path)-1]
+				assert.Equal(t, position(changes[i].location), last.Cell, "test %d - the path should end at the change", id)
+			}
+			tried = append(tried, values)
+			changes[i].trials = nil
+		}
+		assert.Equal(t, testRun.changes, changes, "test %d - wrong changes", id)
+		assert.Equal(t, testRun.tried, tried, "test %d - wrong trials", id)
+	}
+}
+
+func TestForcerUnits(t *testing.T) {
+	empty := NewBoard(2).puzzle
+	view, err := empty.whole.look(empty)
+	assert.NoError(t, err)
+	cells, err := pickCluster(empty, clusterRef{orient: boardForcing})
+	assert.NoError(t, err)
+	// 1 is left in r1c1 and r1c3 of the top row
+	for _, i := range []int{1, 3} {
+		cells[i].excluded, cells[i].possible = NewCandidateSet(1), cells[i].possible.Remove(1)
+	}
+
+	// both trials place 3 in r2c2 and take 2 out of r4c4
+	outcome := func(at coord) (board, Trial) {
+		out := empty
+		trial := Trial{Assumption: Candidate{Cell: position(at), Value: 1}}
+		for _, each := range []cell{
+			{location: at, actual: 1},
+			{location: coord{1, 1}, actual: 3},
+			{location: coord{3, 3}, excluded: NewCandidateSet(2)},
+			{location: coord{2, 0}, excluded: NewCandidateSet(4)},
+		} {
+			var err error
+			out, _, err = changeBoard(out, each)
+			assert.NoError(t, err)
+			if each.location != at {
+				trial.Path = append(trial.Path, Event{Cell: position(each.location), Value: each.actual,
+					Excluded: each.excluded.Values()})
+			}
+		}
+		return out, trial
+	}
+	f := newForcer(cells, view)
+	for _, i := range []int{0, 2} {
+		out, trial := outcome(cells[i].location)
+		f.branches[i] = map[int]branch{1: {trial: trial, out: out, finished: true}}
+	}
+
+	// every change the trials make but placing 1 is agreed on
+	cause := []coord{{0, 0}, {0, 2}}
+	f.units(2)
+	if assert.Len(t, f.changes, 3) {
+		assert.Equal(t, change{cell: cell{location: coord{1, 1}, actual: 3}, rule: RuleUnitForcing, cause: cause},
+			withoutTrials(f.changes[0]))
+		assert.Equal(t, change{cell: cell{location: coord{2, 0}, excluded: NewCandidateSet(4)}, rule: RuleUnitForcing,
+			cause: cause}, withoutTrials(f.changes[1]))
+		assert.Equal(t, change{cell: cell{location: coord{3, 3}, excluded: NewCandidateSet(2)}, rule: RuleUnitForcing,
+			cause: cause}, withoutTrials(f.changes[2]))
+		for id, length := range []int{1, 3, 2} {
+			for _, each := range f.changes[id].trials {
+				assert.Len(t, each.Path, length, "change %d - wrong path", id)
+			}
+		}
+	}
+}
+
+// withoutTrials returns a change without the trials behind it
+func withoutTrials(u change) change {
+	u.trials = nil
+	return u
+}
+
+func TestSolveForcing(t *testing.T) {
+	b, err := Parse(strings.NewReader(escargot))
+	assert.NoError(t, err)
+	s := NewFromBoard(b)
+	s.MaxGuesses = -1
+	_, err = s.Solve(context.Background())
+	assert.True(t, errors.Is(err, ErrGuessLimit), "the other rules should stall")
+
+	events := make(chan Event, 10000)
+	s.ForcingDepth = 1
+	s.Trace = events
+	result, err := s.Solve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, Solved, result.Status)
+	close(events)
+	forced := 0
+	for step := range events {
+		assert.Equal(t, Deduction, step.Kind, "%v", step)
+		if step.Rule != RuleContradiction {
+			continue
+		}
+		forced++
+		if assert.Len(t, step.Trials, 1, "%v", step) {
+			trial := step.Trials[0]
+			assert.Equal(t, Candidate{Cell: step.Cell, Value: step.Excluded[0]}, trial.Assumption)
+			assert.True(t, errors.Is(trial.Contradiction, ErrContradiction), "%v", step)
+			assert.NotEmpty(t, trial.Path, "%v", step)
+		}
+	}
+	assert.NotZero(t, forced, "the trace should show values tried out")
+}
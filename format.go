@@ -0,0 +1,767 @@
+package sudoku
+
+// Reads and writes puzzles as text.
+//
+// Two formats are understood:
+// * The single line format - every cell in order, one character each, as in
+//   "53..7....6..195..."
+// * The grid format - one row per line, optionally broken up with `|`, `-`,
+//   `+` and spaces, as in "5 3 . | . 7 . | . . ."
+//
+// Values above 9 are written as letters, A for 10 thru Z for 35. Unknown
+// cells are written as `.`, and either `.` or `0` is read as unknown.
+//
+// A jigsaw puzzle has its region map straight after it, in the same format -
+// each region marked with a character of its own, as in
+// "AAABBBCCC" for the first row of a 9x9.
+//
+// A box line before the puzzle gives the width and height of its boxes, as in
+// "box: 2x3", for boxes other than the ones the side gets by default.
+//
+// A puzzle of several grids, like a Samurai, starts with a layout line giving
+// the box and the top left cell of each grid, as in
+// "layout: 3x3 r1c1 r1c13 r7c7 r13c1 r13c13". The cells that follow are
+// read in order, skipping the gaps between grids - in the grid format the
+// gaps are left blank.
+//
+// Other lines of the same `name: args` form add rules on top of the cells:
+// * "constraints: anti-knight non-consecutive" - constraints between pairs
+// * "white: r1c1-r1c2 r4c5-r5c5" - markers between cells side by side, one
+//   line for each of white, black, x, v and greater, with the greater cell
+//   first
+// * "negative: white black" - markers whose absence counts too
+// * "thermo: r1c1 r2c2 r3c3" - a line drawn over the grid, one to a line, for
+//   each of thermo, arrow, palindrome and whisper, from the start of the line
+// * "sandwich: r1c1 right 12" - a clue outside the grid, one to a line, for
+//   each of sandwich, littlekiller, skyscraper and xsum - the first cell the
+//   clue looks at, the way it looks, and its value
+// * "cage: 15 r1c1 r1c2" - a killer cage, one to a line, its sum then its cells
+// * "extra: r1c1 r2c2 r3c3 ..." - a cluster on top of the rows, columns and
+//   boxes, one to a line, every cell of it
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Style picks the text format Format writes.
+type Style int
+
+const (
+	// LineStyle writes the whole puzzle on one line.
+	LineStyle Style = iota
+	// GridStyle writes one row per line, with separators between the squares.
+	GridStyle
+)
+
+// the largest side a puzzle can have and still be written one character a cell
+const maxTextSide = 35
+
+// ParseError is returned by Parse for input it can't read. Line and Col count
+// from 1.
+type ParseError struct {
+	Line int
+	Col  int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Col, e.Msg)
+}
+
+// a token is a single cell read from the input, and where it was read
+type token struct {
+	char  rune
+	value int
+	line  int
+	col   int
+}
+
+// a directive is a `name: args` line, giving the puzzle more than its cells
+type directive struct {
+	name string
+	args string
+	line int
+}
+
+// parseDirective splits a `name: args` line, returning false if the line
+// isn't one
+func parseDirective(text string, line int) (directive, bool) {
+	text = strings.TrimSpace(text)
+	colon := strings.IndexByte(text, ':')
+	if colon < 1 {
+		return directive{}, false
+	}
+	for _, each := range text[:colon] {
+		if !unicode.IsLower(each) {
+			return directive{}, false
+		}
+	}
+	return directive{name: text[:colon], args: strings.TrimSpace(text[colon+1:]), line: line}, true
+}
+
+// parseValue turns a single character into a value, 0 for unknown
+func parseValue(r rune) (int, bool) {
+	switch {
+	case r == '.' || r == '0':
+		return 0, true
+	case r >= '1' && r <= '9':
+		return int(r - '0'), true
+	case r >= 'A' && r <= 'Z':
+		return int(r-'A') + 10, true
+	case r >= 'a' && r <= 'z':
+		return int(r-'a') + 10, true
+	default:
+		return 0, false
+	}
+}
+
+// formatValue turns a value into a single character, `.` for unknown
+func formatValue(value int) byte {
+	switch {
+	case value < 1:
+		return '.'
+	case value <= 9:
+		return byte('0' + value)
+	default:
+		return byte('A' + value - 10)
+	}
+}
+
+// isSeparator returns true for the characters that only break up a grid
+func isSeparator(r rune) bool {
+	return strings.ContainsRune(" \t|-+=", r)
+}
+
+// sizeForSide returns the size of a square for a given side, or false if the
+// side doesn't make a square
+func sizeForSide(side int) (int, bool) {
+	for size := 1; size*size <= side; size++ {
+		if size*size == side {
+			return size, true
+		}
+	}
+	return 0, false
+}
+
+// boxForSide returns the box shape for a given side - the squarest box that
+// fits, laid on its side, so 6 gives 3x2 and 12 gives 4x3. Returns false if the
+// only box that fits is a single row.
+func boxForSide(side int) (width, height int, ok bool) {
+	height = 1
+	for h := 2; h*h <= side; h++ {
+		if side%h == 0 {
+			height = h
+		}
+	}
+	if height == 1 && side != 1 {
+		return 0, 0, false
+	}
+	return side / height, height, true
+}
+
+// readTokens reads every cell out of the input, one slice per line that has
+// cells on it, along with any directives. Lines starting with # are skipped.
+func readTokens(r io.Reader) ([][]token, []directive, int, error) {
+	var rows [][]token
+	var directives []directive
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(text), "#") {
+			continue
+		}
+		if each, ok := parseDirective(text, line); ok {
+			directives = append(directives, each)
+			continue
+		}
+		var row []token
+		col := 0
+		for _, each := range text {
+			col++
+			if isSeparator(each) {
+				continue
+			}
+			value, ok := parseValue(each)
+			if !ok {
+				return nil, nil, line, &ParseError{Line: line, Col: col,
+					Msg: fmt.Sprintf("unexpected character %q", each)}
+			}
+			row = append(row, token{char: each, value: value, line: line, col: col})
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, line, err
+	}
+	return rows, directives, line, nil
+}
+
+// Parse reads a puzzle in either the single line or the grid format. The size
+// of the puzzle is worked out from the number of cells. Sides that aren't
+// square get boxes wider than they are tall - 6x6 puzzles get 3x2 boxes, and
+// 12x12 puzzles 4x3 boxes, unless a box line says otherwise. A region map
+// after the puzzle makes it a jigsaw, and a layout line before it a puzzle of
+// several grids.
+func Parse(r io.Reader) (*Board, error) {
+	rows, directives, lastLine, err := readTokens(r)
+	if err != nil {
+		return nil, err
+	}
+	var layout, box *directive
+	var rest []directive
+	for i, each := range directives {
+		var found **directive
+		switch each.name {
+		case "layout":
+			found = &layout
+		case "box":
+			found = &box
+		default:
+			rest = append(rest, each)
+			continue
+		}
+		if *found != nil {
+			return nil, &ParseError{Line: each.line, Col: 1, Msg: "more than one " + each.name}
+		}
+		*found = &directives[i]
+	}
+
+	var result *Board
+	switch {
+	case layout != nil && box != nil:
+		return nil, &ParseError{Line: box.line, Col: 1, Msg: "a layout gives its own box"}
+	case layout != nil:
+		result, err = parseLayout(*layout, rows, lastLine)
+	default:
+		result, err = parseGrid(rows, lastLine, box)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, each := range rest {
+		if err := applyDirective(result, each); err != nil {
+			return nil, &ParseError{Line: each.line, Col: 1, Msg: err.Error()}
+		}
+	}
+	return result, nil
+}
+
+// applyDirective adds what a directive line says to a puzzle - constraints
+// between pairs of cells, markers, markers that are negative, a line, a clue
+// outside the grid, a cage, or an extra cluster
+func applyDirective(b *Board, d directive) error {
+	fields := strings.Fields(d.args)
+	if len(fields) == 0 {
+		return fmt.Errorf("nothing after %q", d.name)
+	}
+	switch d.name {
+	case "constraints":
+		for _, each := range fields {
+			if err := b.AddConstraint(each); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "negative":
+		for _, each := range fields {
+			kind, ok := markerByName(each)
+			if !ok {
+				return fmt.Errorf("no such marker %q", each)
+			}
+			if err := b.AddNegative(kind); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "cage":
+		var sum int
+		if n, _ := fmt.Sscan(fields[0], &sum); n != 1 {
+			return fmt.Errorf("bad sum %q", fields[0])
+		}
+		cells, err := parseCells(fields[1:])
+		if err != nil {
+			return err
+		}
+		return b.AddCage(sum, cells)
+	case "extra":
+		cells, err := parseCells(fields)
+		if err != nil {
+			return err
+		}
+		return b.AddCluster(cells)
+	}
+
+	for kind, name := range clueNames {
+		if name != d.name {
+			continue
+		}
+		var start Position
+		var value int
+		if len(fields) != 3 {
+			return fmt.Errorf("a clue needs a cell, a direction and a value")
+		}
+		if n, _ := fmt.Sscanf(fields[0], "r%dc%d", &start.Row, &start.Col); n != 2 {
+			return fmt.Errorf("bad cell %q", fields[0])
+		}
+		step, ok := clueDirections[fields[1]]
+		if !ok {
+			return fmt.Errorf("bad direction %q", fields[1])
+		}
+		if n, _ := fmt.Sscan(fields[2], &value); n != 1 {
+			return fmt.Errorf("bad value %q", fields[2])
+		}
+		return b.AddClue(kind, value, Position{Row: start.Row - 1, Col: start.Col - 1}, position(step))
+	}
+	for kind, name := range lineNames {
+		if name != d.name {
+			continue
+		}
+		cells, err := parseCells(fields)
+		if err != nil {
+			return err
+		}
+		return b.AddLine(kind, cells)
+	}
+
+	kind, ok := markerByName(d.name)
+	if !ok {
+		return fmt.Errorf("unknown directive %q", d.name)
+	}
+	for _, each := range fields {
+		var a, c Position
+		if n, _ := fmt.Sscanf(each, "r%dc%d-r%dc%d", &a.Row, &a.Col, &c.Row, &c.Col); n != 4 {
+			return fmt.Errorf("bad pair of cells %q", each)
+		}
+		a, c = Position{Row: a.Row - 1, Col: a.Col - 1}, Position{Row: c.Row - 1, Col: c.Col - 1}
+		if err := b.AddMarker(kind, a, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseCells reads cells written as in "r1c2", counting from 1
+func parseCells(fields []string) ([]Position, error) {
+	var cells []Position
+	for _, each := range fields {
+		var at Position
+		if n, _ := fmt.Sscanf(each, "r%dc%d", &at.Row, &at.Col); n != 2 {
+			return nil, fmt.Errorf("bad cell %q", each)
+		}
+		cells = append(cells, Position{Row: at.Row - 1, Col: at.Col - 1})
+	}
+	return cells, nil
+}
+
+// clueDirections names the ways a clue can look into the grid
+var clueDirections = map[string]coord{
+	"right":     {0, 1},
+	"left":      {0, -1},
+	"down":      {1, 0},
+	"up":        {-1, 0},
+	"downright": {1, 1},
+	"downleft":  {1, -1},
+	"upright":   {-1, 1},
+	"upleft":    {-1, -1},
+}
+
+// markerByName returns the kind of marker with a name
+func markerByName(name string) (Marker, bool) {
+	for kind, each := range markerNames {
+		if each == name {
+			return kind, true
+		}
+	}
+	return 0, false
+}
+
+// parseGrid reads a puzzle of one grid, in either the single line or the grid
+// format, with boxes from the box line if there is one
+func parseGrid(rows [][]token, lastLine int, box *directive) (*Board, error) {
+	var err error
+	var cells, regions []token
+	var side int
+	switch {
+	case len(rows) == 0:
+		return nil, &ParseError{Line: lastLine + 1, Col: 1, Msg: "no puzzle found"}
+	case len(rows) == 1 || linePair(rows):
+		// the single line format - every cell in one go, then the regions
+		cells = rows[0]
+		var ok bool
+		if side, ok = sizeForSide(len(cells)); !ok {
+			return nil, &ParseError{Line: cells[0].line, Col: cells[len(cells)-1].col,
+				Msg: fmt.Sprintf("%d cells is not a square puzzle", len(cells))}
+		}
+		if len(rows) == 2 {
+			regions = rows[1]
+		}
+	default:
+		// the grid format - a row per line, then a row of regions per line
+		side = len(rows[0])
+		for _, row := range rows {
+			if len(row) != side {
+				return nil, &ParseError{Line: row[0].line, Col: row[len(row)-1].col,
+					Msg: fmt.Sprintf("row has %d cells, expected %d", len(row), side)}
+			}
+		}
+		if len(rows) != side && len(rows) != side*2 {
+			last := rows[len(rows)-1]
+			return nil, &ParseError{Line: last[0].line, Col: last[len(last)-1].col,
+				Msg: fmt.Sprintf("found %d rows, expected %d", len(rows), side)}
+		}
+		for i, row := range rows {
+			if i < side {
+				cells = append(cells, row...)
+			} else {
+				regions = append(regions, row...)
+			}
+		}
+	}
+
+	var result *Board
+	switch {
+	case regions != nil && box != nil:
+		return nil, &ParseError{Line: box.line, Col: 1, Msg: "a jigsaw has regions, not boxes"}
+	case regions != nil:
+		if result, err = parseRegions(regions, side); err != nil {
+			return nil, err
+		}
+	case box != nil:
+		var width, height int
+		if n, _ := fmt.Sscanf(box.args, "%dx%d", &width, &height); n != 2 || width < 1 || height < 1 {
+			return nil, &ParseError{Line: box.line, Col: 1, Msg: fmt.Sprintf("bad box %q", box.args)}
+		}
+		if width*height != side {
+			return nil, &ParseError{Line: box.line, Col: 1,
+				Msg: fmt.Sprintf("a box of %dx%d doesn't fit a side of %d cells", width, height, side)}
+		}
+		result = NewBoxBoard(width, height)
+	default:
+		width, height, ok := boxForSide(side)
+		if !ok {
+			return nil, &ParseError{Line: cells[0].line, Col: cells[0].col,
+				Msg: fmt.Sprintf("a side of %d cells can't be split into boxes", side)}
+		}
+		result = NewBoxBoard(width, height)
+	}
+
+	for i, each := range cells {
+		if each.value == 0 {
+			continue
+		}
+		if err := result.Set(i/side, i%side, each.value); err != nil {
+			return nil, &ParseError{Line: each.line, Col: each.col, Msg: err.Error()}
+		}
+	}
+	return result, nil
+}
+
+// parseLayout reads a puzzle of several grids - the layout line gives the
+// shape, then the cells fill it in order
+func parseLayout(layout directive, rows [][]token, lastLine int) (*Board, error) {
+	fields := strings.Fields(layout.args)
+	fail := func(msg string) error {
+		return &ParseError{Line: layout.line, Col: 1, Msg: msg}
+	}
+	if len(fields) < 2 {
+		return nil, fail("a layout needs a box and at least one grid")
+	}
+	var width, height int
+	if n, _ := fmt.Sscanf(fields[0], "%dx%d", &width, &height); n != 2 {
+		return nil, fail(fmt.Sprintf("bad box %q", fields[0]))
+	}
+	var corners []Position
+	for _, field := range fields[1:] {
+		var at Position
+		if n, _ := fmt.Sscanf(field, "r%dc%d", &at.Row, &at.Col); n != 2 {
+			return nil, fail(fmt.Sprintf("bad corner %q", field))
+		}
+		corners = append(corners, Position{Row: at.Row - 1, Col: at.Col - 1})
+	}
+	result, err := NewLayoutBoard(width, height, corners)
+	if err != nil {
+		return nil, fail(err.Error())
+	}
+	if result.puzzle.side() > maxTextSide {
+		return nil, fail(fmt.Sprintf("a side of %d cells is too large to write out", result.puzzle.side()))
+	}
+
+	var cells []token
+	for _, row := range rows {
+		cells = append(cells, row...)
+	}
+	var open []coord
+	for x := 0; x < result.puzzle.rows(); x++ {
+		for y := 0; y < result.puzzle.cols(); y++ {
+			if result.puzzle.onBoard(coord{x: x, y: y}) {
+				open = append(open, coord{x: x, y: y})
+			}
+		}
+	}
+	if len(cells) != len(open) {
+		at := token{line: lastLine + 1, col: 1}
+		if len(cells) > 0 {
+			at = cells[len(cells)-1]
+		}
+		return nil, &ParseError{Line: at.line, Col: at.col,
+			Msg: fmt.Sprintf("found %d cells, expected %d", len(cells), len(open))}
+	}
+	for i, each := range cells {
+		if each.value == 0 {
+			continue
+		}
+		if err := result.Set(open[i].x, open[i].y, each.value); err != nil {
+			return nil, &ParseError{Line: each.line, Col: each.col, Msg: err.Error()}
+		}
+	}
+	return result, nil
+}
+
+// linePair returns true for a puzzle and region map each on a line of their
+// own - as opposed to the first two rows of a grid
+func linePair(rows [][]token) bool {
+	if len(rows) != 2 || len(rows[0]) != len(rows[1]) || len(rows[0]) < 9 {
+		return false
+	}
+	_, ok := sizeForSide(len(rows[0]))
+	return ok
+}
+
+// parseRegions makes an empty jigsaw puzzle out of a region map, giving each
+// character a region in the order they turn up
+func parseRegions(regions []token, side int) (*Board, error) {
+	labels := make(map[rune]int)
+	ids := make([][]int, side)
+	for i, each := range regions {
+		id, ok := labels[each.char]
+		if !ok {
+			id = len(labels)
+			labels[each.char] = id
+		}
+		ids[i/side] = append(ids[i/side], id)
+	}
+	if len(labels) != side {
+		return nil, &ParseError{Line: regions[0].line, Col: regions[0].col,
+			Msg: fmt.Sprintf("found %d regions, expected %d", len(labels), side)}
+	}
+
+	result, err := NewJigsawBoard(ids)
+	if err != nil {
+		at := regions[0]
+		if regionErr, ok := err.(*regionError); ok {
+			at = regions[regionErr.at.x*side+regionErr.at.y]
+		}
+		return nil, &ParseError{Line: at.line, Col: at.col, Msg: err.Error()}
+	}
+	return result, nil
+}
+
+// formatRegion turns a region into a single character
+func formatRegion(id int) byte {
+	if id < 26 {
+		return byte('A' + id)
+	}
+	return byte('a' + id - 26)
+}
+
+// formatLayout writes out a puzzle of several grids - the cells in order on
+// one line, or the canvas a row per line with the gaps left blank
+func formatLayout(out *strings.Builder, b *Board, style Style) {
+	rows, cols := b.Canvas()
+	for x := 0; x < rows; x++ {
+		var row strings.Builder
+		for y := 0; y < cols; y++ {
+			onBoard := b.OnBoard(x, y)
+			if style == LineStyle {
+				if onBoard {
+					row.WriteByte(formatValue(b.Get(x, y)))
+				}
+				continue
+			}
+			if y > 0 {
+				row.WriteByte(' ')
+			}
+			if onBoard {
+				row.WriteByte(formatValue(b.Get(x, y)))
+			} else {
+				row.WriteByte(' ')
+			}
+		}
+		out.WriteString(strings.TrimRight(row.String(), " "))
+		if style == GridStyle {
+			out.WriteByte('\n')
+		}
+	}
+	if style == LineStyle {
+		out.WriteByte('\n')
+	}
+}
+
+// formatDirectives writes a line for everything about a puzzle that isn't in
+// its cells - the layout or a box other than the one the side gets by
+// default, then constraints between pairs of cells, then
+// markers a line for each kind, then markers that are negative, then a line
+// for each line drawn over the grid, for each clue outside it, for each cage
+// and for each extra cluster
+func formatDirectives(out *strings.Builder, b *Board) {
+	if corners := b.Corners(); corners != nil {
+		width, height := b.Box()
+		out.WriteString(fmt.Sprintf("layout: %dx%d", width, height))
+		for _, each := range corners {
+			out.WriteString(" " + each.String())
+		}
+		out.WriteByte('\n')
+	} else if !b.Jigsaw() {
+		width, height := b.Box()
+		if defaultWidth, defaultHeight, _ := boxForSide(width * height); width != defaultWidth || height != defaultHeight {
+			out.WriteString(fmt.Sprintf("box: %dx%d\n", width, height))
+		}
+	}
+	if names := b.Constraints(); names != nil {
+		out.WriteString("constraints: " + strings.Join(names, " ") + "\n")
+	}
+	markers := b.Markers()
+	for kind := WhiteDot; kind <= GreaterThan; kind++ {
+		var pairs []string
+		for _, each := range markers {
+			if each.Kind == kind {
+				pairs = append(pairs, each.A.String()+"-"+each.B.String())
+			}
+		}
+		if pairs != nil {
+			out.WriteString(kind.String() + ": " + strings.Join(pairs, " ") + "\n")
+		}
+	}
+	if negative := b.Negative(); negative != nil {
+		names := make([]string, len(negative))
+		for i, each := range negative {
+			names[i] = each.String()
+		}
+		out.WriteString("negative: " + strings.Join(names, " ") + "\n")
+	}
+	for _, each := range b.Lines() {
+		out.WriteString(each.Kind.String() + ":")
+		for _, at := range each.Cells {
+			out.WriteString(" " + at.String())
+		}
+		out.WriteByte('\n')
+	}
+	for _, each := range b.Clues() {
+		for name, step := range clueDirections {
+			if position(step) == each.Step {
+				out.WriteString(fmt.Sprintf("%v: %v %s %d\n", each.Kind, each.Start, name, each.Value))
+			}
+		}
+	}
+	for _, each := range b.Cages() {
+		out.WriteString(fmt.Sprintf("cage: %d", each.Sum))
+		for _, at := range each.Cells {
+			out.WriteString(" " + at.String())
+		}
+		out.WriteByte('\n')
+	}
+	for _, cells := range b.Extras() {
+		out.WriteString("extra:")
+		for _, at := range cells {
+			out.WriteString(" " + at.String())
+		}
+		out.WriteByte('\n')
+	}
+}
+
+// Format writes a puzzle out in the given style. Puzzles with more than 35
+// cells on a side can't be written one character a cell.
+func Format(w io.Writer, b *Board, style Style) error {
+	side := b.puzzle.side()
+	if side > maxTextSide {
+		return fmt.Errorf("a side of %d cells is too large to write out", side)
+	}
+
+	var out strings.Builder
+	formatDirectives(&out, b)
+	switch {
+	case b.puzzle.layout != nil && (style == LineStyle || style == GridStyle):
+		formatLayout(&out, b, style)
+	case style == LineStyle:
+		for x := 0; x < side; x++ {
+			for y := 0; y < side; y++ {
+				out.WriteByte(formatValue(b.Get(x, y)))
+			}
+		}
+		out.WriteByte('\n')
+		if b.Jigsaw() {
+			for x := 0; x < side; x++ {
+				for y := 0; y < side; y++ {
+					out.WriteByte(formatRegion(b.Region(x, y)))
+				}
+			}
+			out.WriteByte('\n')
+		}
+	case style == GridStyle && b.Jigsaw():
+		// regions don't line up, so the region map goes underneath instead
+		// of dividers
+		for x := 0; x < side; x++ {
+			for y := 0; y < side; y++ {
+				if y > 0 {
+					out.WriteByte(' ')
+				}
+				out.WriteByte(formatValue(b.Get(x, y)))
+			}
+			out.WriteByte('\n')
+		}
+		out.WriteByte('\n')
+		for x := 0; x < side; x++ {
+			for y := 0; y < side; y++ {
+				if y > 0 {
+					out.WriteByte(' ')
+				}
+				out.WriteByte(formatRegion(b.Region(x, y)))
+			}
+			out.WriteByte('\n')
+		}
+	case style == GridStyle:
+		boxWidth, boxHeight := b.Box()
+		// line the `+` up under the `|` - the outside boxes have no space
+		// on their outer edge
+		segments := make([]string, side/boxWidth)
+		for i := range segments {
+			width := boxWidth*2 + 1
+			if i == 0 {
+				width--
+			}
+			if i == len(segments)-1 {
+				width--
+			}
+			segments[i] = strings.Repeat("-", width)
+		}
+		divider := strings.Join(segments, "+") + "\n"
+		for x := 0; x < side; x++ {
+			if x > 0 && x%boxHeight == 0 {
+				out.WriteString(divider)
+			}
+			for y := 0; y < side; y++ {
+				if y > 0 && y%boxWidth == 0 {
+					out.WriteString(" |")
+				}
+				if y > 0 {
+					out.WriteByte(' ')
+				}
+				out.WriteByte(formatValue(b.Get(x, y)))
+			}
+			out.WriteByte('\n')
+		}
+	default:
+		return fmt.Errorf("unknown style %d", style)
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
@@ -0,0 +1,203 @@
+package sudoku
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const easyGrid = `5 3 . | . 7 . | . . .
+6 . . | 1 9 5 | . . .
+. 9 8 | . . . | . 6 .
+------+-------+------
+8 . . | . 6 . | . . 3
+4 . . | 8 . 3 | . . 1
+7 . . | . 2 . | . . 6
+------+-------+------
+. 6 . | . . . | 2 8 .
+. . . | 4 1 9 | . . 5
+. . . | . 8 . | . 7 9
+`
+
+func TestParse(t *testing.T) {
+	// a 16x16 with every value down the diagonal
+	sixteen := ""
+	for i, each := range "123456789ABCDEFG" {
+		sixteen += strings.Repeat(".", i) + string(each) + strings.Repeat(".", 15-i)
+	}
+
+	var tests = []struct {
+		in            string
+		width, height int
+		line          string
+	}{
+		{
+			easyPuzzle,
+			3, 3, strings.Replace(easyPuzzle, "0", ".", -1) + "\n",
+		}, {
+			easyGrid,
+			3, 3, strings.Replace(easyPuzzle, "0", ".", -1) + "\n",
+		}, {
+			"# a comment\n1.3.\n..1.\n\n.1..\n4..1\n",
+			2, 2, "1.3...1..1..4..1\n",
+		}, {
+			sixteen,
+			4, 4, sixteen + "\n",
+		}, {
+			sixPuzzle,
+			3, 2, sixPuzzle + "\n",
+		}, {
+			twelvePuzzle,
+			4, 3, twelvePuzzle + "\n",
+		}, {
+			strings.Repeat(".", 64),
+			4, 2, strings.Repeat(".", 64) + "\n",
+		}, {
+			"box: 2x3\n" + sixPuzzle,
+			2, 3, "box: 2x3\n" + sixPuzzle + "\n",
+		}, {
+			"box: 3x2\n" + sixPuzzle,
+			3, 2, sixPuzzle + "\n",
+		}, {
+			jigsawPuzzle + "\n" + jigsawRegions,
+			0, 0, jigsawPuzzle + "\n" + jigsawRegions + "\n",
+		},
+	}
+
+	for id, testRun := range tests {
+		b, err := Parse(strings.NewReader(testRun.in))
+		if !assert.NoError(t, err, "test %d - could not parse", id) {
+			continue
+		}
+		width, height := b.Box()
+		assert.Equal(t, testRun.width, width, "test %d - wrong box width", id)
+		assert.Equal(t, testRun.height, height, "test %d - wrong box height", id)
+
+		var out bytes.Buffer
+		assert.NoError(t, Format(&out, b, LineStyle))
+		assert.Equal(t, testRun.line, out.String(), "test %d - wrong output", id)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	var tests = []struct {
+		in        string
+		line, col int
+	}{
+		{"", 1, 1},
+		{"12345678", 1, 8},
+		{strings.Repeat(".", 49), 1, 1},
+		{"1...\n....\n....\n....\nAABB\nABBA\nCCDD\nCCDD\n", 6, 4},
+		{"1...\n....\n....\n....\nAABB\nAABB\nCCDD\nCCDE\n", 5, 1},
+		{"1.3.\n..x.\n.1..\n4..1\n", 2, 3},
+		{"1.3.\n..1\n.1..\n4..1\n", 2, 3},
+		{"1.3.\n..1.\n.1..\n", 3, 4},
+		{"1.3.\n..1.\n.1..\n4..5\n", 4, 4},
+		{"box: 2x3\n" + strings.Repeat(".", 16), 1, 1},
+		{"box: two\n" + strings.Repeat(".", 16), 1, 1},
+		{"box: 2x2\nbox: 2x2\n" + strings.Repeat(".", 16), 2, 1},
+		{"box: 3x3\nlayout: 3x3 r1c1\n" + strings.Repeat(".", 81), 1, 1},
+		{"1...\n....\n....\n....\nAABB\nAABB\nCCDD\nCCDD\nbox: 2x2\n", 9, 1},
+	}
+
+	for id, testRun := range tests {
+		_, err := Parse(strings.NewReader(testRun.in))
+		parseErr, ok := err.(*ParseError)
+		if !assert.True(t, ok, "test %d - expected a ParseError, got %v", id, err) {
+			continue
+		}
+		assert.Equal(t, testRun.line, parseErr.Line, "test %d - wrong line", id)
+		assert.Equal(t, testRun.col, parseErr.Col, "test %d - wrong column", id)
+	}
+}
+
+func TestFormatGrid(t *testing.T) {
+	b, err := Parse(strings.NewReader(easyPuzzle))
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	assert.NoError(t, Format(&out, b, GridStyle))
+	assert.Equal(t, easyGrid, out.String(), "wrong grid")
+
+	// and the grid reads back in as the same puzzle
+	again, err := Parse(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, b, again, "grid did not round trip")
+}
+
+func TestFormatGridSmall(t *testing.T) {
+	b, err := Parse(strings.NewReader("1.3...1..1..4..1"))
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	assert.NoError(t, Format(&out, b, GridStyle))
+	assert.Equal(t, "1 . | 3 .\n. . | 1 .\n----+----\n. 1 | . .\n4 . | . 1\n", out.String())
+}
+
+func TestFormatGridBoxes(t *testing.T) {
+	b, err := Parse(strings.NewReader(sixPuzzle))
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	assert.NoError(t, Format(&out, b, GridStyle))
+	assert.Equal(t, `. . . | . . .
+. . . | . . 6
+------+------
+1 . . | . 2 5
+. 2 . | 1 . .
+------+------
+. . 4 | . . .
+. 6 2 | . 3 .
+`, out.String())
+
+	again, err := Parse(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, b, again, "grid did not round trip")
+
+	// boxes standing up need the box line to read back the same way
+	b, err = Parse(strings.NewReader("box: 2x3\n" + sixPuzzle))
+	assert.NoError(t, err)
+	out.Reset()
+	assert.NoError(t, Format(&out, b, GridStyle))
+	assert.Equal(t, `box: 2x3
+. . | . . | . .
+. . | . . | . 6
+1 . | . . | 2 5
+----+-----+----
+. 2 | . 1 | . .
+. . | 4 . | . .
+. 6 | 2 . | 3 .
+`, out.String())
+
+	again, err = Parse(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, b, again, "grid did not round trip")
+}
+
+func TestFormatGridJigsaw(t *testing.T) {
+	in := `1 . . 3 . .
+. 2 . . . .
+. . 4 . . .
+. . 6 . . .
+3 . . . . .
+. 5 . . . .
+
+A A B B B B
+A A A C C B
+D A C C C B
+D D D C E E
+D F F F E E
+D F F F E E
+`
+	b, err := Parse(strings.NewReader(in))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, b.Jigsaw())
+
+	var out bytes.Buffer
+	assert.NoError(t, Format(&out, b, GridStyle))
+	assert.Equal(t, in, out.String(), "grid did not round trip")
+}
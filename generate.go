@@ -0,0 +1,193 @@
+package sudoku
+
+// Puzzles are generated by solving an empty board with the guesses shuffled,
+// giving a random complete grid, then taking givens away for as long as the
+// puzzle keeps exactly one solution and stays no harder than asked for.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+// ErrNoPuzzle is returned when no puzzle of the requested difficulty turned
+// up in the attempts allowed.
+var ErrNoPuzzle = errors.New("no puzzle found at the requested difficulty")
+
+// Symmetry says which givens are taken away together.
+type Symmetry int
+
+const (
+	// NoSymmetry takes givens away one at a time.
+	NoSymmetry Symmetry = iota
+	// Rotational keeps the givens the same when the board is turned half
+	// way round.
+	Rotational
+	// Mirror keeps the givens the same when the board is flipped left to
+	// right.
+	Mirror
+)
+
+func (s Symmetry) String() string {
+	switch s {
+	case NoSymmetry:
+		return "none"
+	case Rotational:
+		return "rotational"
+	case Mirror:
+		return "mirror"
+	default:
+		return fmt.Sprintf("Symmetry(%d)", int(s))
+	}
+}
+
+// partners returns every coord that goes together with at under a symmetry,
+// on a board rows by cols cells
+func (s Symmetry) partners(at coord, rows, cols int) []coord {
+	var other coord
+	switch s {
+	case Rotational:
+		other = coord{x: rows - 1 - at.x, y: cols - 1 - at.y}
+	case Mirror:
+		other = coord{x: at.x, y: cols - 1 - at.y}
+	default:
+		return []coord{at}
+	}
+	if other == at {
+		return []coord{at}
+	}
+	return []coord{at, other}
+}
+
+// Generator makes new puzzles. The same settings always make the same puzzle.
+type Generator struct {
+	// Size is the width of a box - 3 for the common 9x9 puzzle.
+	Size int
+	// Height is the height of a box, 0 for boxes as tall as they are wide.
+	Height int
+	// Symmetry is the pattern the givens keep.
+	Symmetry Symmetry
+	// Difficulty is the grade the puzzle should have.
+	Difficulty Difficulty
+	// Seed picks the puzzle.
+	Seed int64
+	// Attempts is the number of complete grids to try before giving up, 0
+	// for 10.
+	Attempts int
+}
+
+// Generate makes a puzzle with exactly one solution, graded at the
+// Generator's Difficulty.
+func (g Generator) Generate(ctx context.Context) (*Board, error) {
+	if g.Size < 1 || g.Height < 0 {
+		return nil, fmt.Errorf("a box of %dx%d is too small", g.Size, g.Height)
+	}
+	attempts := g.Attempts
+	if attempts < 1 {
+		attempts = 10
+	}
+	random := rand.New(rand.NewSource(g.Seed))
+
+	for i := 0; i < attempts; i++ {
+		full, err := randomGrid(ctx, createBoard(g.box()), random)
+		if err != nil {
+			return nil, err
+		}
+		puzzle, err := g.removeGivens(ctx, full, random)
+		if err != nil {
+			return nil, err
+		}
+		level, report := GradeContext(ctx, puzzle)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if level == g.Difficulty && report.Status == Solved {
+			return puzzle, nil
+		}
+	}
+	return nil, ErrNoPuzzle
+}
+
+// box returns the width and height of a box
+func (g Generator) box() (int, int) {
+	if g.Height == 0 {
+		return g.Size, g.Size
+	}
+	return g.Size, g.Height
+}
+
+// randomGrid solves an empty board, trying the guesses in a random order -
+// with the easiest rules alone, as on a board this open the harder ones cost
+// far more than the guesses they save
+func randomGrid(ctx context.Context, empty board, random *rand.Rand) (board, error) {
+	var result board
+	search := searcher{level: Easy, random: random}
+	search.found = func(found board) bool {
+		result = found
+		return false
+	}
+	if _, err := search.search(ctx, empty, 0); err != errStopped {
+		return board{}, err
+	}
+	return result, nil
+}
+
+// removeGivens takes givens away from a complete grid in a random order, as
+// long as the puzzle left over still passes keepable
+func (g Generator) removeGivens(ctx context.Context, full board, random *rand.Rand) (*Board, error) {
+	rows, cols := full.rows(), full.cols()
+	values := make([][]int, rows)
+	for x := range values {
+		values[x] = make([]int, cols)
+		for y := range values[x] {
+			values[x][y] = full.clusters[x][y].actual
+		}
+	}
+
+	order := random.Perm(rows * cols)
+	for _, each := range order {
+		at := coord{x: each / cols, y: each % cols}
+		if values[at.x][at.y] == 0 {
+			continue
+		}
+
+		group := g.Symmetry.partners(at, rows, cols)
+		removed := make([]int, len(group))
+		for i, partner := range group {
+			removed[i] = values[partner.x][partner.y]
+			values[partner.x][partner.y] = 0
+		}
+
+		keep, err := g.keepable(ctx, fillBoard(full.shape, values))
+		if err != nil {
+			return nil, err
+		}
+		if !keep {
+			// put them back
+			for i, partner := range group {
+				values[partner.x][partner.y] = removed[i]
+			}
+		}
+	}
+	return &Board{puzzle: fillBoard(full.shape, values)}, nil
+}
+
+// keepable says if a puzzle still has one solution, and is no harder than the
+// Generator's Difficulty
+func (g Generator) keepable(ctx context.Context, puzzle board) (bool, error) {
+	// dancing links counts the solutions far faster than the rules can - and
+	// most puzzles turned down have more than one
+	links := &Solver{puzzle: Board{puzzle: puzzle}, Backend: DancingLinks}
+	count, err := links.Count(ctx, 2)
+	if err != nil || count != 1 || g.Difficulty == Expert {
+		return err == nil && count == 1, err
+	}
+
+	// the rules have to solve it without guessing
+	result, err := propagate(ctx, puzzle, settings{level: g.Difficulty})
+	if err != nil && ctx.Err() == nil {
+		return false, nil
+	}
+	return result.clusters != nil && result.solved(), ctx.Err()
+}
@@ -0,0 +1,59 @@
+package sudoku
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var tests = []Generator{
+		{Size: 2, Symmetry: NoSymmetry, Difficulty: Easy, Seed: 1},
+		{Size: 2, Symmetry: Rotational, Difficulty: Easy, Seed: 2},
+		{Size: 3, Symmetry: Rotational, Difficulty: Easy, Seed: 3},
+		{Size: 3, Symmetry: Mirror, Difficulty: Medium, Seed: 4},
+		{Size: 3, Height: 2, Symmetry: Rotational, Difficulty: Medium, Seed: 5},
+	}
+
+	for id, g := range tests {
+		b, err := g.Generate(ctx)
+		if !assert.NoError(t, err, "test %d - could not generate", id) {
+			continue
+		}
+		width, height := g.box()
+		assert.Equal(t, g.Size, b.Size(), "test %d - wrong size", id)
+		assert.Equal(t, width*height, b.Side(), "test %d - wrong side", id)
+		assert.True(t, IsUnique(b), "test %d - puzzle is not unique", id)
+		level, _ := Grade(b)
+		assert.Equal(t, g.Difficulty, level, "test %d - wrong difficulty", id)
+
+		side := b.Side()
+		for x := 0; x < side; x++ {
+			for y := 0; y < side; y++ {
+				for _, partner := range g.Symmetry.partners(coord{x: x, y: y}, side, side) {
+					assert.Equal(t, b.Get(x, y) == 0, b.Get(partner.x, partner.y) == 0,
+						"test %d - %d,%d breaks the symmetry", id, x, y)
+				}
+			}
+		}
+
+		// the same settings make the same puzzle
+		again, err := g.Generate(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, b, again, "test %d - not reproducible", id)
+	}
+}
+
+func TestSymmetryPartners(t *testing.T) {
+	assert.Equal(t, []coord{{0, 0}}, NoSymmetry.partners(coord{0, 0}, 9, 9))
+	assert.Equal(t, []coord{{0, 1}, {8, 7}}, Rotational.partners(coord{0, 1}, 9, 9))
+	assert.Equal(t, []coord{{4, 4}}, Rotational.partners(coord{4, 4}, 9, 9))
+	assert.Equal(t, []coord{{2, 1}, {2, 7}}, Mirror.partners(coord{2, 1}, 9, 9))
+	assert.Equal(t, []coord{{2, 4}}, Mirror.partners(coord{2, 4}, 9, 9))
+	assert.Equal(t, []coord{{0, 1}, {20, 19}}, Rotational.partners(coord{0, 1}, 21, 21))
+}
@@ -0,0 +1,253 @@
+package sudoku
+
+// Grading solves a puzzle with the cheapest rules first, and only moves on to
+// harder rules once the cheaper ones have stalled. How hard a puzzle is comes
+// down to the hardest rules it needed.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Difficulty rates the rules a puzzle needs.
+type Difficulty int
+
+const (
+	// Easy puzzles need nothing past rules 2 thru 4 - solved values are
+	// excluded, and cells with one value left are solved.
+	Easy Difficulty = iota
+	// Medium puzzles need values with one cell left (rule 6), pairs of cells
+	// or values (rules 5 and 7 on two at a time), or killer cage sums.
+	Medium
+	// Hard puzzles need rules 5 and 7 on three or more at a time, or the 45
+	// rule.
+	Hard
+	// Expert puzzles can't be solved without guessing, or trying values out
+	// with the forcing rules.
+	Expert
+)
+
+func (d Difficulty) String() string {
+	switch d {
+	case Easy:
+		return "easy"
+	case Medium:
+		return "medium"
+	case Hard:
+		return "hard"
+	case Expert:
+		return "expert"
+	default:
+		return fmt.Sprintf("Difficulty(%d)", int(d))
+	}
+}
+
+// subsetLimit returns the most cells or values rules 5 and 7 look at in one go
+// in a cluster with some cells unsolved. A set of n of those cells holding n
+// values leaves the other cells holding the other values, so a set of more
+// than half of them is always found as the smaller set on the other side -
+// by the other rule.
+func subsetLimit(level Difficulty, unsolved int) int {
+	if level <= Medium || unsolved < 4 {
+		return 2
+	}
+	return unsolved / 2
+}
+
+// ruleLevel returns the difficulty of a deduction, going by the rule that made
+// it and the number of cells that justify it
+func ruleLevel(r Rule, cells int) Difficulty {
+	switch r {
+	case RuleSingleCell, RuleCageSum, RulePairSupport, RuleArrow, RuleWhisper,
+		RuleLittleKiller, RulePointing, RuleBoxLine:
+		return Medium
+	case RuleHouseSum, RuleSandwich, RuleSkyscraper, RuleXSum, RuleXWing, RuleSwordfish,
+		RuleJellyfish, RuleFinnedFish, RuleXYWing, RuleXYZWing, RuleWWing,
+		RuleColoring, RuleXChain, RuleXCycle, RuleAIC, RuleUniqueRectangle1, RuleUniqueRectangle2,
+		RuleUniqueRectangle3, RuleUniqueRectangle4, RuleBUG, RuleALSXZ, RuleALSXYWing, RuleSueDeCoq:
+		return Hard
+	case RuleContradiction, RuleCellForcing, RuleUnitForcing:
+		return Expert
+	case RuleCellLimiter, RuleValueLimiter:
+		if cells < 3 {
+			return Medium
+		}
+		return Hard
+	default:
+		return Easy
+	}
+}
+
+// Report says how a puzzle was graded.
+type Report struct {
+	// Status is how far the solve got - anything but Solved means the grade
+	// is only as good as the rules that ran before it stopped.
+	Status Status
+	// Level is the hardest rules used.
+	Level Difficulty
+	// Counts holds the number of changes each rule made on the way to the
+	// solution - changes undone by backing out of a guess are left out.
+	Counts map[Rule]int
+	// Discarded is the number of changes undone by backing out of guesses.
+	Discarded int
+	// Guesses is the number of guesses made, including ones backed out of.
+	Guesses int
+	// Score adds up the Grader's weights for every change and guess.
+	Score int
+}
+
+// Grader grades puzzles, scoring each change by the rule that made it.
+type Grader struct {
+	// Weights holds the score for each change a rule makes - rules that are
+	// missing score nothing.
+	Weights map[Rule]int
+	// GuessWeight is the score for each guess.
+	GuessWeight int
+	// MaxChain limits how many links a chain can have, 0 for the default.
+	MaxChain int
+	// AssumeUnique lets the rules that only hold for puzzles with one
+	// solution run.
+	AssumeUnique bool
+	// ForcingDepth lets the forcing rules run before guessing, nesting
+	// trials up to ForcingDepth deep - 0 leaves them off. ForcingTrials
+	// limits how many values they can try out each time they run, 0 for
+	// 100.
+	ForcingDepth  int
+	ForcingTrials int
+}
+
+// DefaultGrader is the Grader used by Grade.
+var DefaultGrader = Grader{
+	Weights: map[Rule]int{
+		RuleEliminateKnowns:  1,
+		RuleSingleValue:      1,
+		RuleSingleCell:       2,
+		RuleCellLimiter:      5,
+		RuleValueLimiter:     5,
+		RuleCageSum:          3,
+		RuleHouseSum:         5,
+		RulePairExclusion:    1,
+		RulePairSupport:      3,
+		RuleThermo:           2,
+		RuleArrow:            3,
+		RulePalindrome:       2,
+		RuleWhisper:          3,
+		RuleSandwich:         5,
+		RuleLittleKiller:     3,
+		RuleSkyscraper:       5,
+		RuleXSum:             5,
+		RulePointing:         3,
+		RuleBoxLine:          3,
+		RuleXWing:            6,
+		RuleSwordfish:        8,
+		RuleJellyfish:        10,
+		RuleFinnedFish:       8,
+		RuleXYWing:           7,
+		RuleXYZWing:          8,
+		RuleWWing:            8,
+		RuleColoring:         8,
+		RuleXChain:           9,
+		RuleXCycle:           9,
+		RuleAIC:              10,
+		RuleUniqueRectangle1: 6,
+		RuleUniqueRectangle2: 7,
+		RuleUniqueRectangle3: 8,
+		RuleUniqueRectangle4: 7,
+		RuleBUG:              7,
+		RuleALSXZ:            9,
+		RuleALSXYWing:        10,
+		RuleSueDeCoq:         9,
+		RuleContradiction:    20,
+		RuleCellForcing:      25,
+		RuleUnitForcing:      25,
+	},
+	GuessWeight: 50,
+}
+
+// Grade grades a puzzle with the DefaultGrader.
+func Grade(b *Board) (Difficulty, Report) {
+	return DefaultGrader.Grade(b)
+}
+
+// GradeContext grades a puzzle with the DefaultGrader, until ctx is done.
+func GradeContext(ctx context.Context, b *Board) (Difficulty, Report) {
+	return DefaultGrader.GradeContext(ctx, b)
+}
+
+// Grade solves a puzzle with the cheapest rules first, moving to harder rules
+// only when stuck, and reports on the rules it used.
+func (g Grader) Grade(b *Board) (Difficulty, Report) {
+	return g.GradeContext(context.Background(), b)
+}
+
+// GradeContext grades a puzzle as Grade does, until ctx is done - if it is
+// done before the grade is, the report's Status is Stalled.
+func (g Grader) GradeContext(ctx context.Context, b *Board) (Difficulty, Report) {
+	report := Report{Counts: make(map[Rule]int)}
+
+	// the deductions and guesses on the way to where the search is now - a
+	// guess backed out of takes every step since it along with it
+	var path []Event
+	record := func(step Event) {
+		switch step.Kind {
+		case Guess:
+			report.Guesses++
+			report.Level = Expert
+			path = append(path, step)
+		case Deduction:
+			path = append(path, step)
+		case Backtrack:
+			for len(path) > 0 && path[len(path)-1].Depth >= step.Depth {
+				if path[len(path)-1].Kind == Deduction {
+					report.Discarded++
+				}
+				path = path[:len(path)-1]
+			}
+		}
+	}
+
+	current := b.puzzle
+	var err error
+	for level := Easy; level < Expert && err == nil && !current.solved(); level++ {
+		search := searcher{level: level, maxChain: g.MaxChain, unique: g.AssumeUnique, trace: record}
+		current, err = propagate(ctx, current, search.settings(0))
+	}
+	if err == nil && !current.solved() {
+		// the rules have stalled - guess
+		search := searcher{level: Expert, maxChain: g.MaxChain, unique: g.AssumeUnique,
+			forcingDepth: g.ForcingDepth, forcingTrials: g.ForcingTrials, trace: record}
+		search.found = func(found board) bool {
+			current = found
+			return false
+		}
+		if _, err = search.search(ctx, current, 0); err == errStopped {
+			err = nil
+		}
+	}
+
+	for _, step := range path {
+		if step.Kind != Deduction {
+			continue
+		}
+		report.Counts[step.Rule]++
+		if level := ruleLevel(step.Rule, len(step.Cause)); level > report.Level {
+			report.Level = level
+		}
+	}
+
+	switch {
+	case errors.Is(err, ErrContradiction):
+		report.Status = Contradiction
+	case err == nil && current.solved():
+		report.Status = Solved
+	default:
+		report.Status = Stalled
+	}
+
+	for rule, count := range report.Counts {
+		report.Score += g.Weights[rule] * count
+	}
+	report.Score += g.GuessWeight * report.Guesses
+	return report.Level, report
+}
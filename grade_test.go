@@ -0,0 +1,139 @@
+package sudoku
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mediumPuzzle = "000000010400000000020000000000050407008000300001090000300400200050100000000806000"
+
+func TestGrade(t *testing.T) {
+	var tests = []struct {
+		puzzle string
+		level  Difficulty
+	}{
+		{easyPuzzle, Easy},
+		{mediumPuzzle, Medium},
+		{hardPuzzle, Expert},
+	}
+
+	for id, testRun := range tests {
+		b, err := Parse(strings.NewReader(testRun.puzzle))
+		assert.NoError(t, err)
+		level, report := Grade(b)
+		assert.Equal(t, testRun.level, level, "test %d - wrong difficulty", id)
+		assert.Equal(t, level, report.Level, "test %d - report disagrees", id)
+		assert.Equal(t, Solved, report.Status, "test %d - not solved", id)
+		assert.Equal(t, level == Expert, report.Guesses > 0, "test %d - wrong guesses", id)
+	}
+}
+
+func TestGradeEasyRules(t *testing.T) {
+	b, err := Parse(strings.NewReader(easyPuzzle))
+	assert.NoError(t, err)
+	_, report := Grade(b)
+	for rule := range report.Counts {
+		assert.Contains(t, []Rule{RuleSolvedNoPossible, RuleEliminateKnowns, RuleSingleValue},
+			rule, "an easy puzzle should only use the easy rules")
+	}
+}
+
+func TestGradeContradiction(t *testing.T) {
+	b, err := Parse(strings.NewReader("11.............."))
+	assert.NoError(t, err)
+	_, report := Grade(b)
+	assert.Equal(t, Contradiction, report.Status)
+}
+
+// only the changes on the way to the solution count - not the ones undone by
+// backing out of a guess
+func TestGradeBacktracks(t *testing.T) {
+	b, err := Parse(strings.NewReader(hardPuzzle))
+	assert.NoError(t, err)
+	_, report := Grade(b)
+	assert.Equal(t, Solved, report.Status)
+	assert.NotZero(t, report.Discarded, "the hard puzzle should back out of a guess")
+	open := strings.Count(hardPuzzle, "0") + strings.Count(hardPuzzle, ".")
+	assert.True(t, report.Counts[RuleSingleValue]+report.Counts[RuleSingleCell] <= open,
+		"more cells solved than were open")
+
+	cancelled, stop := context.WithCancel(context.Background())
+	stop()
+	_, report = GradeContext(cancelled, b)
+	assert.Equal(t, Stalled, report.Status)
+}
+
+func TestGraderWeights(t *testing.T) {
+	b, err := Parse(strings.NewReader(easyPuzzle))
+	assert.NoError(t, err)
+
+	g := Grader{Weights: map[Rule]int{RuleSingleValue: 1}}
+	_, report := g.Grade(b)
+	assert.Equal(t, report.Counts[RuleSingleValue], report.Score, "only single values score")
+
+	g.Weights[RuleSingleValue] = 10
+	_, weighted := g.Grade(b)
+	assert.Equal(t, report.Score*10, weighted.Score, "weights should scale the score")
+}
+
+func TestSubsetLimit(t *testing.T) {
+	assert.Equal(t, 2, subsetLimit(Medium, 9))
+	assert.Equal(t, 4, subsetLimit(Hard, 9))
+	assert.Equal(t, 12, subsetLimit(Expert, 25))
+	assert.Equal(t, 2, subsetLimit(Hard, 3))
+
+	// five cells holding 1 thru 5 are past the limit, but the other four
+	// hold the rest of the values - found by rule 7 instead
+	var row cluster
+	for i := 0; i < 9; i++ {
+		each := cell{location: coord{0, i}, possible: valueSet(9)}
+		if i < 5 {
+			each.possible = valueSet(5)
+			each.excluded = NewCandidateSet(6, 7, 8, 9)
+		}
+		row = append(row, each)
+	}
+	changes, err := clusterMoves(settings{level: Hard})(row)
+	assert.NoError(t, err)
+	dropped := make(map[coord]CandidateSet)
+	for _, each := range changes {
+		dropped[each.location] = dropped[each.location].Union(each.excluded)
+	}
+	for i := 5; i < 9; i++ {
+		assert.Equal(t, valueSet(5), dropped[coord{0, i}], "cell %d - wrong values dropped", i)
+	}
+}
+
+func TestRuleLevel(t *testing.T) {
+	assert.Equal(t, Easy, ruleLevel(RuleEliminateKnowns, 1))
+	assert.Equal(t, Easy, ruleLevel(RuleSingleValue, 1))
+	assert.Equal(t, Medium, ruleLevel(RuleSingleCell, 8))
+	assert.Equal(t, Medium, ruleLevel(RuleCellLimiter, 2))
+	assert.Equal(t, Hard, ruleLevel(RuleCellLimiter, 3))
+	assert.Equal(t, Hard, ruleLevel(RuleValueLimiter, 4))
+	assert.Equal(t, Medium, ruleLevel(RuleCageSum, 4))
+	assert.Equal(t, Hard, ruleLevel(RuleHouseSum, 1))
+	assert.Equal(t, Easy, ruleLevel(RulePairExclusion, 1))
+	assert.Equal(t, Medium, ruleLevel(RulePairSupport, 1))
+	assert.Equal(t, Easy, ruleLevel(RuleThermo, 4))
+	assert.Equal(t, Medium, ruleLevel(RuleArrow, 3))
+	assert.Equal(t, Hard, ruleLevel(RuleSandwich, 9))
+	assert.Equal(t, Medium, ruleLevel(RuleLittleKiller, 5))
+	assert.Equal(t, Medium, ruleLevel(RulePointing, 3))
+	assert.Equal(t, Medium, ruleLevel(RuleBoxLine, 2))
+	assert.Equal(t, Hard, ruleLevel(RuleXWing, 2))
+	assert.Equal(t, Hard, ruleLevel(RuleFinnedFish, 3))
+	assert.Equal(t, Hard, ruleLevel(RuleXYWing, 3))
+	assert.Equal(t, Hard, ruleLevel(RuleWWing, 4))
+	assert.Equal(t, Hard, ruleLevel(RuleColoring, 4))
+	assert.Equal(t, Hard, ruleLevel(RuleAIC, 6))
+	assert.Equal(t, Hard, ruleLevel(RuleUniqueRectangle1, 4))
+	assert.Equal(t, Hard, ruleLevel(RuleBUG, 2))
+	assert.Equal(t, Hard, ruleLevel(RuleALSXZ, 3))
+	assert.Equal(t, Hard, ruleLevel(RuleSueDeCoq, 4))
+	assert.Equal(t, Expert, ruleLevel(RuleContradiction, 1))
+	assert.Equal(t, Expert, ruleLevel(RuleUnitForcing, 3))
+}
@@ -0,0 +1,145 @@
+package sudoku
+
+// Where a box crosses a row or column, the cells they share tie the two
+// together. If the only places left for a value in the box are on the line,
+// the value goes on the line inside the box, so the rest of the line loses
+// it - a pointing pair. The same goes the other way: if the only places left
+// on the line are in the box, the rest of the box loses it. Neither cluster
+// knows enough alone, so every box and line that share at least two cells
+// get an intersection cluster of their own, with a worker to look at both.
+
+// intersection is a box and a line that cross. cells holds the box, then the
+// rest of the line - box is the number of cells of the box, and overlap the
+// indexes of the box cells that are on the line too.
+type intersection struct {
+	cells   []coord
+	box     int
+	overlap CandidateSet
+}
+
+// intersectionList lists the boardIntersection clusters of a shape. at[c]
+// holds the intersections coord c sits in.
+type intersectionList struct {
+	crossings []intersection
+	at        map[coord][]int
+}
+
+// newIntersectionList finds every box and line of a shape that share at
+// least two cells
+func newIntersectionList(s shape) *intersectionList {
+	empty := s.empty()
+	result := &intersectionList{at: make(map[coord][]int)}
+	for box := 0; box < s.clusterCount(boardSquare); box++ {
+		square, err := pickCluster(empty, clusterRef{orient: boardSquare, index: box})
+		if err != nil {
+			continue
+		}
+		inBox := make(map[coord]bool)
+		// every line crossing the box, in the order they turn up
+		var lines []clusterRef
+		seen := make(map[clusterRef]bool)
+		for _, each := range square {
+			inBox[each.location] = true
+			refs, err := s.clustersAt(each.location)
+			if err != nil {
+				continue
+			}
+			for _, ref := range refs {
+				if (ref.orient == boardRow || ref.orient == boardCol) && !seen[ref] {
+					seen[ref] = true
+					lines = append(lines, ref)
+				}
+			}
+		}
+
+		for _, ref := range lines {
+			line, err := pickCluster(empty, ref)
+			if err != nil {
+				continue
+			}
+			onLine := make(map[coord]bool)
+			for _, each := range line {
+				onLine[each.location] = true
+			}
+			crossing := intersection{box: len(square)}
+			for i, each := range square {
+				crossing.cells = append(crossing.cells, each.location)
+				if onLine[each.location] {
+					crossing.overlap = crossing.overlap.Add(i)
+				}
+			}
+			if crossing.overlap.Count() < 2 {
+				continue
+			}
+			for _, each := range line {
+				if !inBox[each.location] {
+					crossing.cells = append(crossing.cells, each.location)
+				}
+			}
+
+			index := len(result.crossings)
+			result.crossings = append(result.crossings, crossing)
+			for _, each := range crossing.cells {
+				result.at[each] = append(result.at[each], index)
+			}
+		}
+	}
+	return result
+}
+
+// moves returns the rules for intersection index
+func (l *intersectionList) moves(index, side int, opts settings) moves {
+	crossing := l.crossings[index]
+	return func(cells cluster) ([]change, error) {
+		if opts.level < ruleLevel(RulePointing, crossing.overlap.Count()) {
+			return nil, nil
+		}
+
+		var changes []change
+		for value := 1; value <= side; value++ {
+			// the places left for the value - in the box off the line, where
+			// they cross, and on the line off the box
+			var boxOnly, shared, lineOnly CandidateSet
+			solved := false
+			for i, each := range cells {
+				if each.actual == value {
+					solved = true
+					break
+				}
+				if each.actual != 0 || !each.possible.Has(value) {
+					continue
+				}
+				switch {
+				case crossing.overlap.Has(i):
+					shared = shared.Add(i)
+				case i < crossing.box:
+					boxOnly = boxOnly.Add(i)
+				default:
+					lineOnly = lineOnly.Add(i)
+				}
+			}
+			if solved || shared.Empty() {
+				continue
+			}
+
+			var drop CandidateSet
+			rule := RulePointing
+			switch {
+			case boxOnly.Empty() && !lineOnly.Empty():
+				drop = lineOnly
+			case lineOnly.Empty() && !boxOnly.Empty():
+				drop, rule = boxOnly, RuleBoxLine
+			default:
+				continue
+			}
+			cause := locations(shared, cells)
+			drop.Each(func(i int) {
+				changes = append(changes, change{
+					cell:  cell{location: cells[i].location, excluded: NewCandidateSet(value)},
+					rule:  rule,
+					cause: cause})
+			})
+		}
+		return changes, nil
+	}
+}
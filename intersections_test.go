@@ -0,0 +1,113 @@
+package sudoku
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withoutDerived returns the cluster refs that every board finds for itself
+// from its houses - intersections, fish and the whole board - left out
+func withoutDerived(refs []clusterRef) []clusterRef {
+	var result []clusterRef
+	for _, ref := range refs {
+		if ref.orient != boardIntersection && !lateOrient(ref.orient) {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
+
+func TestNewIntersectionList(t *testing.T) {
+	jigsaw, err := NewJigsawBoard(regionIds("AAB", "ABB", "CCC"))
+	assert.NoError(t, err)
+
+	var tests = []struct {
+		b         *Board
+		crossings int
+		cells     int
+		overlap   int
+	}{
+		// every square crosses three rows and three columns
+		{NewBoard(3), 54, 15, 3},
+		{NewBoard(2), 16, 6, 2},
+		// a 3x2 box crosses two rows of three and three columns of two
+		{NewBoxBoard(3, 2), 30, 0, 0},
+		// A crosses the top row and the first column, B the middle row and
+		// the last column, and C is the bottom row
+		{jigsaw, 5, 0, 0},
+	}
+
+	for id, testRun := range tests {
+		crossings := testRun.b.puzzle.intersections.crossings
+		assert.Len(t, crossings, testRun.crossings, "test %d - wrong number of crossings", id)
+		for _, each := range crossings {
+			if testRun.cells > 0 {
+				assert.Len(t, each.cells, testRun.cells, "test %d - wrong number of cells", id)
+				assert.Equal(t, testRun.overlap, each.overlap.Count(), "test %d - wrong overlap", id)
+			}
+			assert.True(t, each.overlap.Count() >= 2, "test %d - too small an overlap", id)
+		}
+	}
+
+	refs, err := NewBoard(3).puzzle.clustersAt(coord{4, 4})
+	assert.NoError(t, err)
+	assert.Len(t, withoutDerived(refs), 3)
+	assert.Len(t, refs, 3+10+2+len(wholeRules)+1, "the 6 crossings of its square, and 2 more on each of its lines, both fish, the whole board and forcing")
+}
+
+func TestIntersectionMoves(t *testing.T) {
+	b := NewBoard(2)
+	list := b.puzzle.intersections
+	// the first square, and the first row across it
+	index := 0
+	crossing := list.crossings[index]
+	assert.Equal(t, []coord{{0, 0}, {0, 1}, {1, 0}, {1, 1}, {0, 2}, {0, 3}}, crossing.cells)
+	assert.Equal(t, NewCandidateSet(0, 1), crossing.overlap)
+
+	at := func(c coord, values ...int) cell { return cell{location: c, possible: NewCandidateSet(values...)} }
+	var tests = []struct {
+		cells   cluster
+		changes []change
+	}{
+		// 4 is only on the top row of the square, so the rest of the row
+		// loses it
+		{cluster{at(coord{0, 0}, 1, 4), at(coord{0, 1}, 3, 4), at(coord{1, 0}, 1, 3), at(coord{1, 1}, 2, 3),
+			at(coord{0, 2}, 1, 2, 3, 4), at(coord{0, 3}, 2, 4)},
+			[]change{
+				{cell: cell{location: coord{0, 2}, excluded: NewCandidateSet(4)}, rule: RulePointing, cause: []coord{{0, 0}, {0, 1}}},
+				{cell: cell{location: coord{0, 3}, excluded: NewCandidateSet(4)}, rule: RulePointing, cause: []coord{{0, 0}, {0, 1}}},
+			}},
+		// 1 is only in the square on the top row, so the rest of the square
+		// loses it
+		{cluster{at(coord{0, 0}, 1, 3), at(coord{0, 1}, 1, 3), at(coord{1, 0}, 1, 3), at(coord{1, 1}, 1, 4),
+			at(coord{0, 2}, 2, 3, 4), at(coord{0, 3}, 2, 3, 4)},
+			[]change{
+				{cell: cell{location: coord{1, 0}, excluded: NewCandidateSet(1)}, rule: RuleBoxLine, cause: []coord{{0, 0}, {0, 1}}},
+				{cell: cell{location: coord{1, 1}, excluded: NewCandidateSet(1)}, rule: RuleBoxLine, cause: []coord{{0, 0}, {0, 1}}},
+			}},
+		// nothing to go on
+		{cluster{at(coord{0, 0}, 1, 2, 3, 4), at(coord{0, 1}, 1, 2, 3, 4), at(coord{1, 0}, 1, 2, 3, 4), at(coord{1, 1}, 1, 2, 3, 4),
+			at(coord{0, 2}, 1, 2, 3, 4), at(coord{0, 3}, 1, 2, 3, 4)},
+			nil},
+	}
+
+	for id, testRun := range tests {
+		changes, err := list.moves(index, 4, settings{level: Medium})(testRun.cells)
+		assert.NoError(t, err, "test %d - unexpected error", id)
+		assert.Equal(t, testRun.changes, changes, "test %d - wrong changes", id)
+
+		easy, err := list.moves(index, 4, settings{level: Easy})(testRun.cells)
+		assert.NoError(t, err, "test %d - unexpected error", id)
+		assert.Nil(t, easy, "test %d - Easy shouldn't look at crossings", id)
+	}
+}
+
+func TestGradePointing(t *testing.T) {
+	b, err := Parse(strings.NewReader(hardPuzzle))
+	assert.NoError(t, err)
+	_, report := Grade(b)
+	assert.Equal(t, Solved, report.Status)
+	assert.NotZero(t, report.Counts[RulePointing]+report.Counts[RuleBoxLine], "the crossings should be used")
+}
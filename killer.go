@@ -0,0 +1,451 @@
+package sudoku
+
+// Killer puzzles add cages - groups of cells that add up to a sum, with no
+// value twice in a cage. A cage is a cluster like any other as far as the
+// pipeline goes, it just has rules of its own: every cell keeps only the
+// values that some way of making up the sum puts there.
+//
+// The 45 rule comes for free out of the same rule. Every row, column and box
+// adds up to the same total, and so does any group of them that don't
+// overlap - neighbouring rows, neighbouring columns, or neighbouring boxes of
+// a band or stack. The cells of a group left over once the cages wholly
+// inside it are taken away (the innies) must make up the rest of the total.
+// When every cell of a group is in a cage, the cells of those cages sticking
+// out of it (the outies) must make up what the cages hold past the total.
+// Either gets a cage of its own - but only when its cells all sit in one
+// house, as a cage can't hold a value twice. Innies and outies of the same
+// group are never weighed against each other, and boards spread over several
+// grids only look at their houses one at a time.
+
+import (
+	"fmt"
+	"sort"
+)
+
+// the most cells left over in a house that still get a house sum cage - past
+// this the sums rarely rule anything out
+const maxHouseSumCells = 4
+
+// cage is a set of cells that must add up to sum. rule is the rule that
+// deductions from the cage are tagged with - RuleCageSum for the cages of the
+// puzzle, RuleHouseSum for the ones the 45 rule makes.
+type cage struct {
+	cells []coord
+	sum   int
+	rule  Rule
+}
+
+// cageList lists the boardCage clusters of a shape. The cages of the puzzle
+// come first, given of them, then the cages made by the 45 rule. at[c] holds
+// the cages coord c sits in.
+type cageList struct {
+	cages []cage
+	given int
+	at    map[coord][]int
+}
+
+// newCageList indexes the cages of a puzzle, and adds the cages the 45 rule
+// makes from them
+func newCageList(s shape, given []cage) *cageList {
+	result := &cageList{cages: append([]cage{}, given...), given: len(given), at: make(map[coord][]int)}
+	result.cages = append(result.cages, houseSumCages(s, given)...)
+	for index, each := range result.cages {
+		for _, at := range each.cells {
+			result.at[at] = append(result.at[at], index)
+		}
+	}
+	return result
+}
+
+// houseSumCages returns a cage for the innies and the outies of every group of
+// houses the 45 rule looks at, where they sit in one house
+func houseSumCages(s shape, given []cage) []cage {
+	side := s.side()
+	total := side * (side + 1) / 2
+	empty := s.empty()
+
+	var result []cage
+	seen := make(map[string]bool)
+	add := func(cells []coord, sum int) {
+		if len(cells) == 0 || len(cells) > maxHouseSumCells || !oneHouse(s, cells) {
+			return
+		}
+		sort.Slice(cells, func(i, j int) bool {
+			return cells[i].x < cells[j].x || cells[i].x == cells[j].x && cells[i].y < cells[j].y
+		})
+		if key := fmt.Sprint(cells); !seen[key] {
+			seen[key] = true
+			result = append(result, cage{cells: cells, sum: sum, rule: RuleHouseSum})
+		}
+	}
+
+	for _, group := range houseGroups(s) {
+		inGroup := make(map[coord]bool)
+		var cells []coord
+		for _, ref := range group {
+			house, err := pickCluster(empty, ref)
+			if err != nil {
+				return result
+			}
+			for _, each := range house {
+				inGroup[each.location] = true
+				cells = append(cells, each.location)
+			}
+		}
+
+		// the cages wholly inside the group, and the ones that stick out
+		inside, touching := make(map[coord]bool), make(map[coord]bool)
+		insideSum, touchingSum := 0, 0
+		var outies []coord
+		for _, each := range given {
+			var in, out []coord
+			for _, at := range each.cells {
+				if inGroup[at] {
+					in = append(in, at)
+				} else {
+					out = append(out, at)
+				}
+			}
+			if len(in) == 0 {
+				continue
+			}
+			for _, at := range in {
+				touching[at] = true
+				if len(out) == 0 {
+					inside[at] = true
+				}
+			}
+			touchingSum += each.sum
+			if len(out) == 0 {
+				insideSum += each.sum
+			}
+			outies = append(outies, out...)
+		}
+
+		var innies, uncovered []coord
+		for _, at := range cells {
+			if !inside[at] {
+				innies = append(innies, at)
+			}
+			if !touching[at] {
+				uncovered = append(uncovered, at)
+			}
+		}
+		groupTotal := total * len(group)
+		if insideSum > 0 {
+			add(innies, groupTotal-insideSum)
+		}
+		if len(uncovered) == 0 {
+			add(outies, touchingSum-groupTotal)
+		}
+	}
+	return result
+}
+
+// houseGroups returns every group of houses the 45 rule looks at - each row,
+// column and square alone, then runs of neighbouring rows and of neighbouring
+// columns, then runs of neighbouring boxes along a band or down a stack.
+// Boards spread over several grids only get the houses alone, and boards of
+// regions no runs of boxes.
+func houseGroups(s shape) [][]clusterRef {
+	var result [][]clusterRef
+	for orient := boardRow; orient <= boardSquare; orient++ {
+		for index := 0; index < s.clusterCount(orient); index++ {
+			result = append(result, []clusterRef{{orient: orient, index: index}})
+		}
+	}
+	if s.layout != nil {
+		return result
+	}
+	// run returns the refs of count houses of an orient, from first on, step
+	// apart
+	run := func(orient, first, count, step int) []clusterRef {
+		var refs []clusterRef
+		for i := 0; i < count; i++ {
+			refs = append(refs, clusterRef{orient: orient, index: first + i*step})
+		}
+		return refs
+	}
+	side := s.side()
+	for count := 2; count <= side; count++ {
+		for first := 0; first+count <= side; first++ {
+			result = append(result, run(boardRow, first, count, 1), run(boardCol, first, count, 1))
+		}
+	}
+	if s.regions != nil {
+		return result
+	}
+	// there are boxHeight boxes along a band, and boxWidth down a stack - a
+	// run of every box of one is a run of rows or columns
+	bands, stacks := side/s.boxHeight, s.boxHeight
+	for band := 0; band < bands; band++ {
+		for count := 2; count < stacks; count++ {
+			for first := 0; first+count <= stacks; first++ {
+				result = append(result, run(boardSquare, band*stacks+first, count, 1))
+			}
+		}
+	}
+	for stack := 0; stack < stacks; stack++ {
+		for count := 2; count < bands; count++ {
+			for first := 0; first+count <= bands; first++ {
+				result = append(result, run(boardSquare, first*stacks+stack, count, stacks))
+			}
+		}
+	}
+	return result
+}
+
+// oneHouse returns true if every cell sits in the same row, column or square
+func oneHouse(s shape, cells []coord) bool {
+	shared := make(map[clusterRef]int)
+	for _, at := range cells {
+		refs, err := s.clustersAt(at)
+		if err != nil {
+			return false
+		}
+		for _, ref := range refs {
+			if ref.orient <= boardSquare {
+				shared[ref]++
+			}
+		}
+	}
+	for _, count := range shared {
+		if count == len(cells) {
+			return true
+		}
+	}
+	return false
+}
+
+// moves returns the rules for cage index
+func (l *cageList) moves(index, side int, opts settings) moves {
+	c := l.cages[index]
+	return func(cells cluster) ([]change, error) {
+		support, ok := cageSupport(c.sum, side, cells)
+		if !ok {
+			return nil, fmt.Errorf("%w: the cage at %v can't add up to %d", ErrContradiction, c.cells[0], c.sum)
+		}
+		if opts.level < ruleLevel(c.rule, len(c.cells)) {
+			return nil, nil
+		}
+
+		var changes []change
+		for i, each := range cells {
+			if each.actual != 0 {
+				continue
+			}
+			if drop := each.possible.Difference(support[i]); !drop.Empty() {
+				changes = append(changes, change{
+					cell:  cell{location: each.location, excluded: drop},
+					rule:  c.rule,
+					cause: c.cells})
+			}
+		}
+		return changes, nil
+	}
+}
+
+// cageSupport finds every set of different values up to side that adds up to
+// sum, and returns the values each cell took in at least one way of placing
+// one of those sets in the cage. Returns false if there is no way at all.
+// Sets are built in ascending order, so the sum cuts them short, and each one
+// is only placed in the cells once it is whole - trying every order of the
+// values instead would take up to n! steps for a cage of n cells.
+func cageSupport(sum, side int, cells cluster) ([]CandidateSet, bool) {
+	var used, reach CandidateSet
+	var open []int
+	remaining := sum
+	for i, each := range cells {
+		if each.actual == 0 {
+			open = append(open, i)
+			reach = reach.Union(each.possible)
+			continue
+		}
+		if used.Has(each.actual) {
+			return nil, false
+		}
+		used = used.Add(each.actual)
+		remaining -= each.actual
+	}
+
+	support := make([]CandidateSet, len(cells))
+	for i, each := range cells {
+		if each.actual != 0 {
+			support[i] = NewCandidateSet(each.actual)
+		}
+	}
+	if len(open) == 0 {
+		return support, remaining == 0
+	}
+
+	// the values some open cell can take, with the sums of the first i of
+	// them in below[i]
+	values := reach.Difference(used).Intersect(valueSet(side)).Values()
+	below := make([]int, len(values)+1)
+	for i, value := range values {
+		below[i+1] = below[i] + value
+	}
+	found := false
+
+	var pick func(n, from, remaining int, set CandidateSet)
+	pick = func(n, from, remaining int, set CandidateSet) {
+		if n == 0 {
+			if remaining == 0 && placeSet(cells, open, set, support) {
+				found = true
+			}
+			return
+		}
+		last := len(values) - n
+		if last < from || remaining > below[len(values)]-below[last] {
+			// too few values left, or too small to make up the rest
+			return
+		}
+		for i := from; i <= last; i++ {
+			if below[i+n]-below[i] > remaining {
+				// the smallest n values from here already add up to too much
+				break
+			}
+			pick(n-1, i+1, remaining-values[i], set.Add(values[i]))
+		}
+	}
+	pick(len(open), 0, remaining, CandidateSet{})
+	return support, found
+}
+
+// placeSet adds to support every value of set that an open cell holds in some
+// way of placing the whole set in the open cells, one value a cell. Returns
+// false if there is no way to place it.
+func placeSet(cells cluster, open []int, set CandidateSet, support []CandidateSet) bool {
+	placed := assignSet(cells, open, set, -1, 0)
+	if placed == nil {
+		return false
+	}
+	for i, at := range open {
+		support[at] = support[at].Add(placed[i])
+	}
+	for i, at := range open {
+		cells[at].possible.Intersect(set).Difference(support[at]).Each(func(value int) {
+			if assignSet(cells, open, set, i, value) != nil {
+				support[at] = support[at].Add(value)
+			}
+		})
+	}
+	return true
+}
+
+// assignSet gives each open cell a different value of set that it can hold,
+// with open cell fixed held to value unless fixed is -1, and returns the value
+// of each - or nil if there is no way to
+func assignSet(cells cluster, open []int, set CandidateSet, fixed, value int) []int {
+	holder := make(map[int]int)
+	var tried CandidateSet
+	// give finds a value for open cell i, moving the others along if needed
+	var give func(i int) bool
+	give = func(i int) bool {
+		choices := cells[open[i]].possible.Intersect(set)
+		if i == fixed {
+			choices = choices.Intersect(NewCandidateSet(value))
+		}
+		for _, each := range choices.Values() {
+			if tried.Has(each) {
+				continue
+			}
+			tried = tried.Add(each)
+			if j, ok := holder[each]; !ok || give(j) {
+				holder[each] = i
+				return true
+			}
+		}
+		return false
+	}
+	for i := range open {
+		tried = CandidateSet{}
+		if !give(i) {
+			return nil
+		}
+	}
+	result := make([]int, len(open))
+	for each, i := range holder {
+		result[i] = each
+	}
+	return result
+}
+
+// sumBounds returns the smallest and largest sums n different values up to
+// top can make, leaving out the used values
+func sumBounds(n, top int, used CandidateSet) (int, int) {
+	low, high := 0, 0
+	for value, count := 1, 0; value <= top && count < n; value++ {
+		if !used.Has(value) {
+			low += value
+			count++
+		}
+	}
+	for value, count := top, 0; value >= 1 && count < n; value-- {
+		if !used.Has(value) {
+			high += value
+			count++
+		}
+	}
+	return low, high
+}
+
+// Cage is a killer cage - cells whose values add up to Sum, with no value
+// twice.
+type Cage struct {
+	Sum   int
+	Cells []Position
+}
+
+// AddCage adds a killer cage to the puzzle. The cells must be on the board,
+// different, and not already in a cage, and the sum must be one the cells can
+// make.
+func (b *Board) AddCage(sum int, cells []Position) error {
+	side := b.puzzle.side()
+	if len(cells) < 1 || len(cells) > side {
+		return fmt.Errorf("a cage can't have %d cells", len(cells))
+	}
+	if low, high := sumBounds(len(cells), side, CandidateSet{}); sum < low || sum > high {
+		return fmt.Errorf("%d cells can't add up to %d", len(cells), sum)
+	}
+
+	var given []cage
+	if b.puzzle.cages != nil {
+		given = b.puzzle.cages.cages[:b.puzzle.cages.given]
+	}
+	caged := make(map[coord]bool)
+	for _, each := range given {
+		for _, at := range each.cells {
+			caged[at] = true
+		}
+	}
+
+	added := cage{sum: sum, rule: RuleCageSum}
+	for _, each := range cells {
+		at := coord{x: each.Row, y: each.Col}
+		if !b.puzzle.onBoard(at) {
+			return fmt.Errorf("cell %v is off the board", each)
+		}
+		if caged[at] {
+			return fmt.Errorf("cell %v is already in a cage", each)
+		}
+		caged[at] = true
+		added.cells = append(added.cells, at)
+	}
+
+	given = append(given[:len(given):len(given)], added)
+	b.puzzle.cages = newCageList(b.puzzle.shape, given)
+	return nil
+}
+
+// Cages returns every cage added to the puzzle, in the order they were added.
+func (b *Board) Cages() []Cage {
+	if b.puzzle.cages == nil {
+		return nil
+	}
+	result := make([]Cage, b.puzzle.cages.given)
+	for i, each := range b.puzzle.cages.cages[:b.puzzle.cages.given] {
+		result[i] = Cage{Sum: each.sum, Cells: positions(each.cells)}
+	}
+	return result
+}
@@ -0,0 +1,220 @@
+package sudoku
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// killerCages are the cages of a 9x9 killer with no givens, sum first
+var killerCages = []string{
+	"20 r4c9 r4c8 r3c8", "18 r9c4 r9c3 r9c2 r9c5", "13 r8c1 r7c1 r8c2", "22 r5c2 r6c2 r4c2",
+	"14 r2c5 r1c5 r1c6", "19 r8c7 r8c6 r9c6 r9c7", "8 r4c6 r5c6", "17 r4c7 r3c7 r5c7",
+	"11 r2c6 r3c6", "17 r7c3 r8c3", "19 r2c7 r2c8 r1c8 r1c7", "1 r9c1",
+	"16 r1c4 r2c4 r2c3 r3c3", "6 r7c8 r7c7", "15 r6c3 r5c3 r6c4 r5c4", "10 r3c5 r4c5",
+	"18 r6c1 r5c1 r4c1 r3c1", "9 r1c2 r2c2", "10 r8c8 r8c9", "4 r1c3",
+	"23 r5c8 r6c8 r6c7 r6c6", "11 r6c9 r5c9 r7c9", "17 r2c1 r1c1", "10 r9c8 r9c9",
+	"12 r7c4 r7c5 r7c6", "14 r4c4 r3c4 r4c3", "14 r8c4 r8c5", "9 r3c9 r2c9",
+	"1 r3c2", "15 r6c5 r5c5", "7 r7c2", "5 r1c9",
+}
+
+const killerSolution = "964318725837452196512769843286145379451973682793286451379624518648591237125837964"
+
+// loadCages adds cages written as a sum followed by cells, as in "3 r1c1 r1c2"
+func loadCages(t *testing.T, b *Board, cages []string) {
+	for _, each := range cages {
+		fields := strings.Fields(each)
+		var sum int
+		fmt.Sscan(fields[0], &sum)
+		var cells []Position
+		for _, field := range fields[1:] {
+			var at Position
+			fmt.Sscanf(field, "r%dc%d", &at.Row, &at.Col)
+			cells = append(cells, Position{Row: at.Row - 1, Col: at.Col - 1})
+		}
+		if err := b.AddCage(sum, cells); err != nil {
+			t.Fatalf("could not add cage %q - %v", each, err)
+		}
+	}
+}
+
+func TestCageSupport(t *testing.T) {
+	// cells with every value left, then solved ones
+	open := func(n int) cell { return cell{possible: valueSet(n)} }
+	solved := func(value int) cell { return cell{actual: value} }
+
+	var tests = []struct {
+		sum     int
+		cells   cluster
+		ok      bool
+		support [][]int
+	}{
+		{3, cluster{open(9), open(9)}, true, [][]int{{1, 2}, {1, 2}}},
+		{17, cluster{open(9), open(9)}, true, [][]int{{8, 9}, {8, 9}}},
+		{6, cluster{open(9), open(9), open(9)}, true, [][]int{{1, 2, 3}, {1, 2, 3}, {1, 2, 3}}},
+		{10, cluster{solved(3), open(9), open(9)}, true, [][]int{{3}, {1, 2, 5, 6}, {1, 2, 5, 6}}},
+		{7, cluster{solved(7)}, true, [][]int{{7}}},
+		{8, cluster{solved(7)}, false, nil},
+		{4, cluster{open(9), open(9)}, true, [][]int{{1, 3}, {1, 3}}},
+		{4, cluster{open(9), cell{possible: NewCandidateSet(2)}}, false, nil},
+		{10, cluster{solved(5), solved(5)}, false, nil},
+		{25, cluster{open(9), open(9)}, false, nil},
+		// 1 2 3 is the only set, and can only be placed one way
+		{6, cluster{cell{possible: NewCandidateSet(1)}, cell{possible: NewCandidateSet(1, 2)},
+			cell{possible: NewCandidateSet(1, 3)}}, true, [][]int{{1}, {2}, {3}}},
+		// 1 2 6 and 1 3 5 can't be placed, which leaves 2 3 4
+		{9, cluster{cell{possible: NewCandidateSet(2, 3)}, cell{possible: NewCandidateSet(2, 3)},
+			open(9)}, true, [][]int{{2, 3}, {2, 3}, {4}}},
+	}
+
+	for id, testRun := range tests {
+		support, ok := cageSupport(testRun.sum, 9, testRun.cells)
+		assert.Equal(t, testRun.ok, ok, "test %d - wrong result", id)
+		if !ok {
+			continue
+		}
+		for i, each := range support {
+			assert.Equal(t, testRun.support[i], each.Values(), "test %d - wrong values for cell %d", id, i)
+		}
+	}
+
+	// a cage as large as a house on a 16x16 board has 16! orders to try, but
+	// only the one set of values
+	var house cluster
+	for i := 0; i < 16; i++ {
+		house = append(house, open(16))
+	}
+	support, ok := cageSupport(136, 16, house)
+	assert.True(t, ok)
+	for i, each := range support {
+		assert.Equal(t, valueSet(16), each, "cell %d - wrong values", i)
+	}
+}
+
+func TestHouseSumCages(t *testing.T) {
+	b := NewBoard(2)
+	assert.NoError(t, b.AddCage(3, []Position{{0, 0}, {0, 1}}))
+	assert.NoError(t, b.AddCage(4, []Position{{0, 2}, {1, 2}}))
+
+	// row 0, column 2 and both top squares each have one cage inside,
+	// leaving two cells to make up the rest of 10
+	assert.Equal(t, []cage{
+		{cells: []coord{{0, 2}, {0, 3}}, sum: 7, rule: RuleHouseSum},
+		{cells: []coord{{2, 2}, {3, 2}}, sum: 6, rule: RuleHouseSum},
+		{cells: []coord{{1, 0}, {1, 1}}, sum: 7, rule: RuleHouseSum},
+		{cells: []coord{{0, 3}, {1, 3}}, sum: 6, rule: RuleHouseSum},
+	}, b.puzzle.cages.cages[b.puzzle.cages.given:])
+	assert.Len(t, b.Cages(), 2)
+}
+
+func TestHouseSumUnions(t *testing.T) {
+	// dominoes down columns from the top two rows, leaving out some columns
+	dominoes := func(skip ...int) []string {
+		var result []string
+		for col := 1; col <= 9; col++ {
+			if !inArr(skip, col) {
+				result = append(result, fmt.Sprintf("10 r1c%d r2c%d", col, col))
+			}
+		}
+		return result
+	}
+
+	var tests = []struct {
+		cages   []string
+		want    []cage
+		without [][]coord
+	}{
+		// the innies of the top two rows are both in column 1
+		{dominoes(1), []cage{{cells: []coord{{0, 0}, {1, 0}}, sum: 10, rule: RuleHouseSum}}, nil},
+		// the top row is all in cages, and one sticks out below
+		{[]string{"10 r1c1 r1c2 r1c3 r1c4", "26 r1c5 r1c6 r1c7 r1c8", "15 r1c9 r2c9"}, []cage{
+			{cells: []coord{{0, 8}}, sum: 9, rule: RuleHouseSum},
+			{cells: []coord{{1, 8}}, sum: 6, rule: RuleHouseSum},
+		}, nil},
+		// innies sharing no house can't be a cage, so are left out
+		{append(dominoes(1, 6), "5 r1c6", "5 r2c1"), nil, [][]coord{{{0, 0}, {1, 5}}}},
+	}
+
+	for id, testRun := range tests {
+		b := NewBoard(3)
+		loadCages(t, b, testRun.cages)
+		derived := b.puzzle.cages.cages[b.puzzle.cages.given:]
+		for _, each := range testRun.want {
+			assert.Contains(t, derived, each, "test %d - missing a cage", id)
+		}
+		for _, each := range derived {
+			assert.True(t, oneHouse(b.puzzle.shape, each.cells), "test %d - %v not in one house", id, each.cells)
+			assert.NotContains(t, testRun.without, each.cells, "test %d - should be left out", id)
+		}
+	}
+}
+
+func TestAddCage(t *testing.T) {
+	b := NewBoard(3)
+	assert.Error(t, b.AddCage(1, nil), "no cells")
+	assert.Error(t, b.AddCage(2, []Position{{0, 0}, {0, 1}}), "too small a sum")
+	assert.Error(t, b.AddCage(18, []Position{{0, 0}, {0, 1}}), "too large a sum")
+	assert.Error(t, b.AddCage(3, []Position{{0, 0}, {0, 9}}), "off the board")
+	assert.NoError(t, b.AddCage(3, []Position{{0, 0}, {0, 1}}))
+	assert.Error(t, b.AddCage(5, []Position{{0, 1}, {0, 2}}), "already in a cage")
+	assert.Error(t, b.AddCage(5, []Position{{1, 1}, {1, 1}}), "the same cell twice")
+}
+
+func TestSolveKiller(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := NewBoard(3)
+	loadCages(t, b, killerCages)
+
+	result, err := NewFromBoard(b).Solve(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, Solved, result.Status, "puzzle should be solved")
+	assert.Equal(t, lineValues(killerSolution), result.Values, "wrong solution")
+
+	level, report := Grade(b)
+	assert.Equal(t, Hard, level, "should need the 45 rule")
+	assert.Equal(t, 0, report.Guesses)
+	assert.NotZero(t, report.Counts[RuleCageSum])
+	assert.NotZero(t, report.Counts[RuleHouseSum])
+}
+
+func TestKillerContradiction(t *testing.T) {
+	b := NewBoard(2)
+	assert.NoError(t, b.AddCage(3, []Position{{0, 0}, {0, 1}}))
+	assert.NoError(t, b.Set(0, 0, 4))
+
+	_, err := NewFromBoard(b).Solve(context.Background())
+	assert.ErrorIs(t, err, ErrContradiction)
+}
+
+func TestFormatCages(t *testing.T) {
+	in := `cage: 3 r1c1 r1c2
+cage: 7 r2c4 r1c4 r1c3
+cage: 4 r4c4
+...........4....
+`
+	b, err := Parse(strings.NewReader(in))
+	if !assert.NoError(t, err, "could not parse") {
+		return
+	}
+	assert.Equal(t, []Cage{
+		{3, []Position{{0, 0}, {0, 1}}},
+		{7, []Position{{1, 3}, {0, 3}, {0, 2}}},
+		{4, []Position{{3, 3}}},
+	}, b.Cages(), "cells in the order given")
+
+	var out bytes.Buffer
+	assert.NoError(t, Format(&out, b, LineStyle))
+	assert.Equal(t, in, out.String(), "did not round trip")
+
+	for id, each := range []string{"cage: r1c1 r1c2", "cage: 3", "cage: 3 r1c1 1,2", "cage: 9 r1c1 r1c2"} {
+		_, err := Parse(strings.NewReader(each + "\n" + strings.Repeat(".", 16)))
+		assert.IsType(t, &ParseError{}, err, "test %d - should not parse", id)
+	}
+}
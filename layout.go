@@ -0,0 +1,125 @@
+package sudoku
+
+// Samurai and the other gattai puzzles are several grids laid out on a larger
+// canvas, overlapping where they share boxes. Each grid brings its own rows,
+// columns and boxes, so a cell where grids overlap sits in a cluster of each
+// kind from every grid it is part of - an update there reaches all of them,
+// which is all it takes for the grids to work together. Cells of the canvas
+// outside every grid are void, and take no part in the puzzle.
+
+import (
+	"errors"
+	"fmt"
+)
+
+// gridLayout places grids on a canvas rows by cols cells. corners holds the
+// top left coord of each grid. houses[orient][i] lists the coords of row,
+// column or square i - grid by grid, so the rows of grid g are g*side thru
+// g*side+side-1 - and at[c] the clusters coord c sits in.
+type gridLayout struct {
+	corners    []coord
+	rows, cols int
+	houses     [boardSquare + 1][][]coord
+	at         map[coord][]clusterRef
+}
+
+// newGridLayout lays out grids of boxes width cells across and height cells
+// down, with their top left cells at corners
+func newGridLayout(width, height int, corners []coord) (*gridLayout, error) {
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("a box of %dx%d is too small", width, height)
+	}
+	if len(corners) < 1 {
+		return nil, errors.New("a layout needs at least one grid")
+	}
+	side := width * height
+	grid := shape{boxWidth: width, boxHeight: height}.empty()
+
+	result := &gridLayout{at: make(map[coord][]clusterRef)}
+	seen := make(map[coord]bool)
+	for g, corner := range corners {
+		if corner.x < 0 || corner.y < 0 {
+			return nil, fmt.Errorf("grid %d starts off the canvas at %v", g+1, position(corner))
+		}
+		if seen[corner] {
+			return nil, fmt.Errorf("grid %d is on top of another grid at %v", g+1, position(corner))
+		}
+		seen[corner] = true
+		result.corners = append(result.corners, corner)
+		if corner.x+side > result.rows {
+			result.rows = corner.x + side
+		}
+		if corner.y+side > result.cols {
+			result.cols = corner.y + side
+		}
+
+		// every cluster of a lone grid, moved over to where the grid sits
+		for orient := boardRow; orient <= boardSquare; orient++ {
+			for pos := 0; pos < side; pos++ {
+				house, err := clusterPicker(grid, orient, clusterStart(orient, pos, grid.shape))
+				if err != nil {
+					return nil, err
+				}
+				ref := clusterRef{orient: orient, index: len(result.houses[orient])}
+				cells := make([]coord, len(house))
+				for i, each := range house {
+					cells[i] = coord{x: corner.x + each.location.x, y: corner.y + each.location.y}
+					result.at[cells[i]] = append(result.at[cells[i]], ref)
+				}
+				result.houses[orient] = append(result.houses[orient], cells)
+			}
+		}
+	}
+	return result, nil
+}
+
+// NewLayoutBoard returns an empty puzzle made of several grids on a larger
+// canvas, each made of boxes width cells across and height cells down.
+// corners holds the top left cell of each grid - where grids overlap, they
+// share cells.
+func NewLayoutBoard(width, height int, corners []Position) (*Board, error) {
+	coords := make([]coord, len(corners))
+	for i, each := range corners {
+		coords[i] = coord{x: each.Row, y: each.Col}
+	}
+	layout, err := newGridLayout(width, height, coords)
+	if err != nil {
+		return nil, err
+	}
+	return &Board{puzzle: shape{boxWidth: width, boxHeight: height, layout: layout}.crossed().empty()}, nil
+}
+
+// SamuraiCorners returns the corners of a Samurai - four grids made of size x
+// size boxes, with a fifth in the middle sharing a corner box with each.
+// SamuraiCorners(3) lays out five 9x9 grids on a 21x21 canvas.
+func SamuraiCorners(size int) []Position {
+	offset := size*size - size
+	return []Position{
+		{Row: 0, Col: 0},
+		{Row: 0, Col: offset * 2},
+		{Row: offset, Col: offset},
+		{Row: offset * 2, Col: 0},
+		{Row: offset * 2, Col: offset * 2},
+	}
+}
+
+// Corners returns the top left cell of each grid of a puzzle made with
+// NewLayoutBoard, or nil for a puzzle of one grid.
+func (b *Board) Corners() []Position {
+	if b.puzzle.layout == nil {
+		return nil
+	}
+	return positions(b.puzzle.layout.corners)
+}
+
+// Canvas returns the number of rows and columns the puzzle covers - the same
+// as Side for a puzzle of one grid.
+func (b *Board) Canvas() (rows, cols int) {
+	return b.puzzle.rows(), b.puzzle.cols()
+}
+
+// OnBoard returns true if a cell is part of the puzzle - on the canvas, and
+// not in a gap between grids.
+func (b *Board) OnBoard(row, col int) bool {
+	return b.puzzle.onBoard(coord{x: row, y: col})
+}
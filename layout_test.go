@@ -0,0 +1,164 @@
+package sudoku
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const samuraiPuzzle = `layout: 3x3 r1c1 r1c13 r7c7 r13c1 r13c13
+.8.....97...8.1..4..6.87....9.27.6...1.4............8..4.3.6..9.8..1.75......5.....7......1958..4.....62.3.........8..........9..2....3.......2...............12.4....36............3..8..2....76.....5........1.2.6......3..71.....5.7.............84......7...6.1...9245.3.7.....8.126...91....4.....95...3.........74.....824.6....5...2........291...7....4...6.....2.1..5..6
+`
+
+const samuraiSolution = `layout: 3x3 r1c1 r1c13 r7c7 r13c1 r13c13
+384621597263891574926587314598274631517439862714365289842316759982413756763945128637958142195872436145627398651794283956471582963278163945718326749815439258671234859136427814325697396871245527649138541962738192564197823987135462587913852647236784159463782463159892456317657938412673891524348216795415327986129574368158249673435689271364578291896721534729613845271345986
+`
+
+func TestNewGridLayout(t *testing.T) {
+	var tests = []struct {
+		width, height int
+		corners       []coord
+		ok            bool
+	}{
+		{3, 3, []coord{{0, 0}}, true},
+		{3, 3, []coord{{0, 0}, {6, 6}}, true},
+		{3, 2, []coord{{0, 0}, {4, 3}}, true},
+		{0, 3, []coord{{0, 0}}, false},
+		{3, 3, nil, false},
+		{3, 3, []coord{{0, 0}, {-1, 6}}, false},
+		{3, 3, []coord{{0, 0}, {0, 0}}, false},
+	}
+
+	for id, testRun := range tests {
+		layout, err := newGridLayout(testRun.width, testRun.height, testRun.corners)
+		assert.Equal(t, testRun.ok, err == nil, "test %d - wrong result, got %v", id, err)
+		if err != nil {
+			continue
+		}
+		side := testRun.width * testRun.height
+		for orient := boardRow; orient <= boardSquare; orient++ {
+			assert.Len(t, layout.houses[orient], side*len(testRun.corners), "test %d - wrong house count", id)
+			for _, house := range layout.houses[orient] {
+				assert.Len(t, house, side, "test %d - wrong house size", id)
+			}
+		}
+	}
+}
+
+func TestSamuraiLayout(t *testing.T) {
+	b, err := NewLayoutBoard(3, 3, SamuraiCorners(3))
+	if !assert.NoError(t, err) {
+		return
+	}
+	rows, cols := b.Canvas()
+	assert.Equal(t, 21, rows)
+	assert.Equal(t, 21, cols)
+	assert.Equal(t, 9, b.Side())
+	assert.Len(t, b.Corners(), 5)
+
+	assert.True(t, b.OnBoard(0, 8))
+	assert.False(t, b.OnBoard(0, 9), "the gap between the top grids")
+	assert.True(t, b.OnBoard(9, 9), "the middle grid")
+	assert.False(t, b.OnBoard(21, 0))
+	assert.Error(t, b.Set(0, 10, 1), "a cell in a gap can't be set")
+	assert.Equal(t, 8, b.Region(6, 6), "the first grid's box wins")
+	assert.Equal(t, -1, b.Region(0, 10))
+
+	var tests = []struct {
+		at   coord
+		refs []clusterRef
+	}{
+		{coord{x: 0, y: 0}, []clusterRef{{boardRow, 0}, {boardCol, 0}, {boardSquare, 0}}},
+		{coord{x: 6, y: 6}, []clusterRef{{boardRow, 6}, {boardCol, 6}, {boardSquare, 8},
+			{boardRow, 18}, {boardCol, 18}, {boardSquare, 18}}},
+		{coord{x: 10, y: 10}, []clusterRef{{boardRow, 22}, {boardCol, 22}, {boardSquare, 22}}},
+		{coord{x: 20, y: 20}, []clusterRef{{boardRow, 44}, {boardCol, 44}, {boardSquare, 44}}},
+	}
+	for id, testRun := range tests {
+		refs, err := b.puzzle.clustersAt(testRun.at)
+		assert.NoError(t, err, "test %d - bad coord", id)
+		assert.Equal(t, testRun.refs, withoutDerived(refs), "test %d - wrong clusters", id)
+		for _, ref := range refs {
+			picked, err := pickCluster(b.puzzle, ref)
+			assert.NoError(t, err, "test %d - bad cluster", id)
+			assert.Contains(t, picked, b.puzzle.clusters[testRun.at.x][testRun.at.y], "test %d - %v not in %v", id, testRun.at, ref)
+		}
+	}
+	_, err = b.puzzle.clustersAt(coord{x: 0, y: 10})
+	assert.Error(t, err, "a cell in a gap is in no cluster")
+
+	assert.Error(t, b.AddDiagonals())
+	assert.Error(t, b.AddWindows())
+}
+
+func TestSolveSamurai(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	b, err := Parse(strings.NewReader(samuraiPuzzle))
+	if !assert.NoError(t, err, "could not parse") {
+		return
+	}
+	result, err := NewFromBoard(b).Solve(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, Solved, result.Status, "puzzle should be solved")
+
+	var out bytes.Buffer
+	assert.NoError(t, Format(&out, result.Board(), LineStyle))
+	assert.Equal(t, samuraiSolution, out.String(), "wrong solution")
+}
+
+func TestFormatLayout(t *testing.T) {
+	b, err := Parse(strings.NewReader(samuraiPuzzle))
+	if !assert.NoError(t, err, "could not parse") {
+		return
+	}
+
+	var line bytes.Buffer
+	assert.NoError(t, Format(&line, b, LineStyle))
+	assert.Equal(t, samuraiPuzzle, line.String(), "line did not round trip")
+
+	var grid bytes.Buffer
+	assert.NoError(t, Format(&grid, b, GridStyle))
+	lines := strings.Split(grid.String(), "\n")
+	assert.Equal(t, "layout: 3x3 r1c1 r1c13 r7c7 r13c1 r13c13", lines[0])
+	assert.Equal(t, ". 8 . . . . . 9 7       . . . 8 . 1 . . 4", lines[1])
+	assert.Equal(t, "            . . . . . . . . .", lines[10])
+
+	again, err := Parse(&grid)
+	if !assert.NoError(t, err, "could not parse the grid") {
+		return
+	}
+	line.Reset()
+	assert.NoError(t, Format(&line, again, LineStyle))
+	assert.Equal(t, samuraiPuzzle, line.String(), "grid did not round trip")
+}
+
+func TestParseLayoutErrors(t *testing.T) {
+	var tests = []struct {
+		in   string
+		line int
+	}{
+		{"size: 9\n" + strings.Repeat(".", 81), 1},
+		{"layout: 3x3\n" + strings.Repeat(".", 81), 1},
+		{"layout: 3 r1c1\n" + strings.Repeat(".", 81), 1},
+		{"layout: 3x3 1,1\n" + strings.Repeat(".", 81), 1},
+		{"layout: 3x3 r1c1 r1c1\n" + strings.Repeat(".", 81), 1},
+		{"layout: 3x3 r1c1\nlayout: 3x3 r1c1\n" + strings.Repeat(".", 81), 2},
+		{"layout: 3x3 r1c1 r7c7\n" + strings.Repeat(".", 81), 2},
+		{"layout: 2x2 r1c1\n" + strings.Repeat(".", 15), 2},
+	}
+
+	for id, testRun := range tests {
+		_, err := Parse(strings.NewReader(testRun.in))
+		parseErr, ok := err.(*ParseError)
+		if !assert.True(t, ok, "test %d - expected a parse error, got %v", id, err) {
+			continue
+		}
+		assert.Equal(t, testRun.line, parseErr.Line, "test %d - wrong line", id)
+	}
+}
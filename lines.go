@@ -0,0 +1,285 @@
+package sudoku
+
+// Line puzzles draw paths over the grid, each with a rule for the values
+// along it - rising along a thermometer, adding up along an arrow, reading
+// the same both ways along a palindrome, or jumping by at least 5 along a
+// German whisper. A line is a cluster like any other as far as the pipeline
+// goes, so a change to any cell on a line sends the line back to its worker.
+// The rules only ever narrow each cell down to the values that fit the
+// bounds the rest of the line puts on it.
+
+import (
+	"errors"
+	"fmt"
+)
+
+// LineKind is a kind of line drawn over the grid.
+type LineKind int
+
+const (
+	// Thermo values strictly increase from the bulb, the first cell.
+	Thermo LineKind = iota
+	// Arrow has the circle, the first cell, equal to the sum of the rest.
+	Arrow
+	// Palindrome values read the same from either end.
+	Palindrome
+	// Whisper values next to each other along the line differ by at least 5,
+	// as in German whispers.
+	Whisper
+)
+
+// the smallest gap between cells next to each other on a whisper
+const whisperGap = 5
+
+var lineNames = map[LineKind]string{
+	Thermo:     "thermo",
+	Arrow:      "arrow",
+	Palindrome: "palindrome",
+	Whisper:    "whisper",
+}
+
+func (k LineKind) String() string {
+	if name, ok := lineNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("LineKind(%d)", int(k))
+}
+
+// rule returns the rule that deductions from a kind of line are tagged with
+func (k LineKind) rule() Rule {
+	switch k {
+	case Thermo:
+		return RuleThermo
+	case Arrow:
+		return RuleArrow
+	case Palindrome:
+		return RulePalindrome
+	default:
+		return RuleWhisper
+	}
+}
+
+// line is a path of cells, in order from the start of the line
+type line struct {
+	kind  LineKind
+	cells []coord
+}
+
+// lineList lists the boardLine clusters of a shape. at[c] holds the lines
+// coord c sits on.
+type lineList struct {
+	lines []line
+	at    map[coord][]int
+}
+
+// with returns the lines with one more added on the end - the lines it is
+// called on are left alone, other boards may share them
+func (l *lineList) with(added line) *lineList {
+	result := &lineList{at: make(map[coord][]int)}
+	if l != nil {
+		result.lines = append(result.lines, l.lines...)
+		for at, indexes := range l.at {
+			result.at[at] = append([]int{}, indexes...)
+		}
+	}
+	index := len(result.lines)
+	result.lines = append(result.lines, added)
+	for _, each := range added.cells {
+		result.at[each] = append(result.at[each], index)
+	}
+	return result
+}
+
+// cellValues returns the values a cell can still hold
+func cellValues(c cell) CandidateSet {
+	if c.actual != 0 {
+		return NewCandidateSet(c.actual)
+	}
+	return c.possible
+}
+
+// moves returns the rules for line index
+func (l *lineList) moves(index, side int, opts settings) moves {
+	each := l.lines[index]
+	rule := each.kind.rule()
+	return func(cells cluster) ([]change, error) {
+		values := make([]CandidateSet, len(cells))
+		for i, c := range cells {
+			values[i] = cellValues(c)
+		}
+
+		var keep []CandidateSet
+		switch each.kind {
+		case Thermo:
+			keep = thermoBounds(values, side)
+		case Arrow:
+			keep = arrowBounds(values, side)
+		case Palindrome:
+			keep = palindromeBounds(values)
+		default:
+			keep = whisperBounds(values)
+		}
+
+		var changes []change
+		for i, c := range cells {
+			kept := values[i].Intersect(keep[i])
+			if kept.Empty() {
+				return nil, fmt.Errorf("%w: nothing fits %v on the %v at %v", ErrContradiction,
+					position(c.location), each.kind, position(each.cells[0]))
+			}
+			if c.actual != 0 || opts.level < ruleLevel(rule, len(cells)) {
+				continue
+			}
+			if drop := c.possible.Difference(kept); !drop.Empty() {
+				changes = append(changes, change{
+					cell:  cell{location: c.location, excluded: drop},
+					rule:  rule,
+					cause: each.cells})
+			}
+		}
+		return changes, nil
+	}
+}
+
+// thermoBounds keeps each cell of a thermometer above the smallest value the
+// cells before it can hold, and below the largest the cells after it can
+func thermoBounds(values []CandidateSet, side int) []CandidateSet {
+	low, high := make([]int, len(values)), make([]int, len(values))
+	for i, each := range values {
+		low[i] = each.Min()
+		if i > 0 && low[i-1]+1 > low[i] {
+			low[i] = low[i-1] + 1
+		}
+	}
+	for i := len(values) - 1; i >= 0; i-- {
+		high[i] = values[i].Max()
+		if i < len(values)-1 && high[i+1]-1 < high[i] {
+			high[i] = high[i+1] - 1
+		}
+	}
+	result := make([]CandidateSet, len(values))
+	for i := range values {
+		result[i] = boundSet(low[i], high[i], side)
+	}
+	return result
+}
+
+// arrowBounds keeps the circle of an arrow between the smallest and largest
+// sums the arrow can make, and each cell of the arrow to what is left of the
+// circle once the other cells take as little or as much as they can
+func arrowBounds(values []CandidateSet, side int) []CandidateSet {
+	sumLow, sumHigh := 0, 0
+	for _, each := range values[1:] {
+		sumLow += each.Min()
+		sumHigh += each.Max()
+	}
+	circle := values[0]
+	result := make([]CandidateSet, len(values))
+	result[0] = boundSet(sumLow, sumHigh, side)
+	for i, each := range values[1:] {
+		restLow, restHigh := sumLow-each.Min(), sumHigh-each.Max()
+		result[i+1] = boundSet(circle.Min()-restHigh, circle.Max()-restLow, side)
+	}
+	return result
+}
+
+// palindromeBounds keeps each cell of a palindrome to the values the cell at
+// the other end can hold too
+func palindromeBounds(values []CandidateSet) []CandidateSet {
+	result := make([]CandidateSet, len(values))
+	for i := range values {
+		result[i] = values[len(values)-1-i]
+	}
+	return result
+}
+
+// whisperBounds keeps each cell of a whisper to the values that some value of
+// each cell next to it on the line is far enough from
+func whisperBounds(values []CandidateSet) []CandidateSet {
+	result := make([]CandidateSet, len(values))
+	for i, each := range values {
+		result[i] = each
+		for _, j := range []int{i - 1, i + 1} {
+			if j < 0 || j >= len(values) {
+				continue
+			}
+			var near CandidateSet
+			each.Each(func(value int) {
+				values[j].Each(func(other int) {
+					if value-other >= whisperGap || other-value >= whisperGap {
+						near = near.Add(value)
+					}
+				})
+			})
+			result[i] = result[i].Intersect(near)
+		}
+	}
+	return result
+}
+
+// boundSet returns the values from low thru high, kept to 1 thru side
+func boundSet(low, high, side int) CandidateSet {
+	if low < 1 {
+		low = 1
+	}
+	if high > side {
+		high = side
+	}
+	return rangeSet(low, high)
+}
+
+// Line is a line drawn over the grid - the cells it passes thru, in order.
+type Line struct {
+	Kind  LineKind
+	Cells []Position
+}
+
+// AddLine draws a line over the grid. Each cell must be on the board and
+// touch the one before it, sides or corners, and no cell can be on the line
+// twice. For a thermometer the first cell is the bulb, and for an arrow the
+// circle.
+func (b *Board) AddLine(kind LineKind, cells []Position) error {
+	if _, ok := lineNames[kind]; !ok {
+		return fmt.Errorf("no such line %v", kind)
+	}
+	if len(cells) < 2 {
+		return errors.New("a line needs at least two cells")
+	}
+	if kind == Thermo && len(cells) > b.puzzle.side() {
+		return fmt.Errorf("a thermometer of %d cells can't rise thru %d values", len(cells), b.puzzle.side())
+	}
+
+	added := line{kind: kind}
+	seen := make(map[coord]bool)
+	for i, each := range cells {
+		at := coord{x: each.Row, y: each.Col}
+		if !b.puzzle.onBoard(at) {
+			return fmt.Errorf("cell %v is off the board", each)
+		}
+		if seen[at] {
+			return fmt.Errorf("cell %v is on the line twice", each)
+		}
+		if i > 0 {
+			last := added.cells[i-1]
+			if dx, dy := at.x-last.x, at.y-last.y; dx < -1 || dx > 1 || dy < -1 || dy > 1 {
+				return fmt.Errorf("cell %v doesn't touch %v", each, position(last))
+			}
+		}
+		seen[at] = true
+		added.cells = append(added.cells, at)
+	}
+	b.puzzle.lines = b.puzzle.lines.with(added)
+	return nil
+}
+
+// Lines returns every line drawn over the grid, in the order they were added.
+func (b *Board) Lines() []Line {
+	if b.puzzle.lines == nil {
+		return nil
+	}
+	result := make([]Line, len(b.puzzle.lines.lines))
+	for i, each := range b.puzzle.lines.lines {
+		result[i] = Line{Kind: each.kind, Cells: positions(each.cells)}
+	}
+	return result
+}
@@ -0,0 +1,183 @@
+package sudoku
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	thermoPuzzle = `thermo: r8c6 r7c7 r6c7 r7c6
+thermo: r4c9 r4c8 r5c8
+thermo: r9c6 r8c5 r8c4
+thermo: r8c2 r9c2 r9c1
+thermo: r3c3 r2c4 r1c4
+thermo: r9c9 r8c8 r8c7
+thermo: r5c2 r5c3 r4c3
+.2.9...1......84.7....3...6.....4....3.19.7...........54.....2...1...........6...
+`
+	thermoSolution = "826947315153628497794531286679284531438195762215763849547319628361872954982456173"
+	arrowPuzzle    = `arrow: r4c8 r4c7 r4c6
+arrow: r6c5 r5c6 r5c5
+arrow: r7c7 r8c6 r7c6
+arrow: r2c6 r2c5 r3c4
+arrow: r4c3 r5c2 r6c2 r5c3
+arrow: r1c4 r2c3 r3c2 r4c1
+arrow: r7c1 r8c2 r7c3 r7c4
+3....71.8..........2.......................4.6.8.....3.6...19..9........2........
+`
+	arrowSolution    = "396457128481623795527189436159342687732816549648975213865231974914768352273594861"
+	palindromePuzzle = `palindrome: r8c3 r7c2 r6c1
+palindrome: r8c4 r9c3 r9c2
+palindrome: r3c1 r4c1 r5c2
+palindrome: r1c3 r2c3 r3c4
+palindrome: r4c3 r3c3 r2c4 r2c5
+palindrome: r7c6 r8c6 r9c7
+palindrome: r1c5 r2c6 r3c7
+...2.......7..18.5.9....6........7...56.8....3........................1.7..5....4
+`
+	palindromeSolution = "138265947627491835594837621819354762456782193372916458985143276243678519761529384"
+	whisperPuzzle      = `whisper: r3c7 r4c8 r5c9
+whisper: r6c3 r7c2 r7c3
+whisper: r2c9 r1c8 r2c7
+whisper: r5c2 r6c2 r5c3
+whisper: r8c6 r8c7 r7c7
+whisper: r3c9 r2c8 r1c7 r1c6
+whisper: r5c6 r6c6 r6c7 r6c8
+........4..8.....3.4.8..........7...2.9..............8...25.......4...6...2......
+`
+	whisperSolution = "527631894968745123341892657856327419279184536413569278794256381185473962632918745"
+)
+
+func TestLineBounds(t *testing.T) {
+	set := NewCandidateSet
+	var tests = []struct {
+		kind   LineKind
+		values []CandidateSet
+		keep   []CandidateSet
+	}{
+		{Thermo,
+			[]CandidateSet{valueSet(9), valueSet(9), valueSet(9)},
+			[]CandidateSet{rangeSet(1, 7), rangeSet(2, 8), rangeSet(3, 9)}},
+		{Thermo,
+			[]CandidateSet{set(4, 6), valueSet(9), set(2, 7)},
+			[]CandidateSet{rangeSet(4, 5), rangeSet(5, 6), rangeSet(6, 7)}},
+		{Arrow,
+			[]CandidateSet{valueSet(9), set(1, 2), set(3, 4)},
+			[]CandidateSet{rangeSet(4, 6), rangeSet(1, 6), rangeSet(1, 8)}},
+		{Arrow,
+			[]CandidateSet{set(5), valueSet(9), valueSet(9)},
+			[]CandidateSet{rangeSet(2, 9), rangeSet(1, 4), rangeSet(1, 4)}},
+		{Palindrome,
+			[]CandidateSet{set(1, 2), valueSet(9), set(2, 3)},
+			[]CandidateSet{set(2, 3), valueSet(9), set(1, 2)}},
+		{Whisper,
+			[]CandidateSet{set(4, 9), valueSet(9), set(1)},
+			[]CandidateSet{set(4, 9), set(9), set(1)}},
+	}
+
+	for id, testRun := range tests {
+		var keep []CandidateSet
+		switch testRun.kind {
+		case Thermo:
+			keep = thermoBounds(testRun.values, 9)
+		case Arrow:
+			keep = arrowBounds(testRun.values, 9)
+		case Palindrome:
+			keep = palindromeBounds(testRun.values)
+		default:
+			keep = whisperBounds(testRun.values)
+		}
+		for i := range keep {
+			assert.True(t, testRun.keep[i].Equal(keep[i]), "test %d - cell %d kept %v, expected %v", id, i, keep[i], testRun.keep[i])
+		}
+	}
+}
+
+func TestAddLine(t *testing.T) {
+	b := NewBoard(2)
+	assert.Error(t, b.AddLine(Thermo, []Position{{0, 0}}), "too short")
+	assert.Error(t, b.AddLine(Thermo, []Position{{0, 0}, {0, 2}}), "cells don't touch")
+	assert.Error(t, b.AddLine(Thermo, []Position{{0, 0}, {1, 1}, {0, 0}}), "the same cell twice")
+	assert.Error(t, b.AddLine(Arrow, []Position{{3, 3}, {3, 4}}), "off the board")
+	assert.Error(t, b.AddLine(Thermo, []Position{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {2, 0}}), "too long to rise")
+	assert.Error(t, b.AddLine(LineKind(9), []Position{{0, 0}, {0, 1}}), "no such line")
+	assert.NoError(t, b.AddLine(Thermo, []Position{{0, 0}, {1, 1}, {2, 2}}))
+	assert.NoError(t, b.AddLine(Whisper, []Position{{2, 2}, {2, 3}}), "lines can cross")
+	assert.Equal(t, []Line{
+		{Thermo, []Position{{0, 0}, {1, 1}, {2, 2}}},
+		{Whisper, []Position{{2, 2}, {2, 3}}},
+	}, b.Lines())
+
+	refs, err := b.puzzle.clustersAt(coord{2, 2})
+	assert.NoError(t, err)
+	assert.Contains(t, refs, clusterRef{boardLine, 0})
+	assert.Contains(t, refs, clusterRef{boardLine, 1})
+	assert.Nil(t, NewBoard(2).Lines())
+}
+
+func TestSolveLines(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	var tests = []struct {
+		puzzle, solution string
+		rule             Rule
+	}{
+		{thermoPuzzle, thermoSolution, RuleThermo},
+		{arrowPuzzle, arrowSolution, RuleArrow},
+		{palindromePuzzle, palindromeSolution, RulePalindrome},
+		{whisperPuzzle, whisperSolution, RuleWhisper},
+	}
+
+	for id, testRun := range tests {
+		b, err := Parse(strings.NewReader(testRun.puzzle))
+		if !assert.NoError(t, err, "test %d - could not parse", id) {
+			continue
+		}
+		assert.Len(t, b.Lines(), 7, "test %d - wrong number of lines", id)
+		result, err := NewFromBoard(b).Solve(ctx)
+		assert.NoError(t, err, "test %d - could not solve", id)
+		assert.Equal(t, Solved, result.Status, "test %d - puzzle should be solved", id)
+		assert.Equal(t, lineValues(testRun.solution), result.Values, "test %d - wrong solution", id)
+		assert.True(t, IsUnique(b), "test %d - puzzle should be unique", id)
+
+		_, report := Grade(b)
+		assert.NotZero(t, report.Counts[testRun.rule], "test %d - the lines should be used", id)
+
+		plain := testRun.puzzle[strings.LastIndex(testRun.puzzle[:len(testRun.puzzle)-1], "\n")+1:]
+		unlined, err := Parse(strings.NewReader(plain))
+		assert.NoError(t, err)
+		assert.False(t, IsUnique(unlined), "test %d - the lines should matter", id)
+	}
+}
+
+func TestLineContradiction(t *testing.T) {
+	// fine as a plain puzzle, but the thermometer falls
+	b := NewBoard(2)
+	assert.NoError(t, b.Set(0, 0, 3))
+	assert.NoError(t, b.Set(1, 1, 2))
+	assert.NoError(t, b.AddLine(Thermo, []Position{{0, 0}, {1, 1}}))
+
+	_, err := NewFromBoard(b).Solve(context.Background())
+	assert.ErrorIs(t, err, ErrContradiction)
+}
+
+func TestFormatLines(t *testing.T) {
+	b, err := Parse(strings.NewReader(arrowPuzzle))
+	if !assert.NoError(t, err, "could not parse") {
+		return
+	}
+	var out bytes.Buffer
+	assert.NoError(t, Format(&out, b, LineStyle))
+	assert.Equal(t, arrowPuzzle, out.String(), "did not round trip")
+
+	_, err = Parse(strings.NewReader("thermo: r1c1 r1c3\n" + strings.Repeat(".", 16)))
+	assert.IsType(t, &ParseError{}, err, "cells that don't touch")
+	_, err = Parse(strings.NewReader("arrow: r1c1 1,2\n" + strings.Repeat(".", 16)))
+	assert.IsType(t, &ParseError{}, err, "a bad cell")
+}
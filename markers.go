@@ -0,0 +1,164 @@
+package sudoku
+
+// Kropki, XV and greater-than puzzles put markers between cells side by side,
+// each one a rule between the pair. With the negative constraint, a missing
+// marker says something too - two cells without a white dot between them
+// can't be consecutive.
+
+import (
+	"fmt"
+)
+
+// Marker is a kind of marker between two cells side by side.
+type Marker int
+
+const (
+	// WhiteDot marks cells holding values one apart.
+	WhiteDot Marker = iota
+	// BlackDot marks cells where one value is twice the other.
+	BlackDot
+	// MarkerX marks cells that add up to 10.
+	MarkerX
+	// MarkerV marks cells that add up to 5.
+	MarkerV
+	// GreaterThan marks the first cell of the pair as greater than the
+	// second.
+	GreaterThan
+)
+
+var markerNames = map[Marker]string{
+	WhiteDot:    "white",
+	BlackDot:    "black",
+	MarkerX:     "x",
+	MarkerV:     "v",
+	GreaterThan: "greater",
+}
+
+func (m Marker) String() string {
+	if name, ok := markerNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Marker(%d)", int(m))
+}
+
+// rule returns the rule a marker puts between its cells
+func (m Marker) rule() pairRule {
+	switch m {
+	case WhiteDot:
+		return pairConsecutive
+	case BlackDot:
+		return pairDouble
+	case MarkerX:
+		return pairSumTen
+	case MarkerV:
+		return pairSumFive
+	default:
+		return pairGreater
+	}
+}
+
+// negated returns the rule between cells without the marker, under the
+// negative constraint
+func (m Marker) negated() pairRule {
+	switch m {
+	case WhiteDot:
+		return pairNonConsecutive
+	case BlackDot:
+		return pairNotDouble
+	case MarkerX:
+		return pairNotSumTen
+	default:
+		return pairNotSumFive
+	}
+}
+
+// edgeMarker is a marker between cells a and b
+type edgeMarker struct {
+	kind Marker
+	a, b coord
+}
+
+// EdgeMarker is a marker between two cells side by side.
+type EdgeMarker struct {
+	Kind Marker
+	A, B Position
+}
+
+// AddMarker puts a marker between two cells side by side. For GreaterThan,
+// a is the greater cell. A pair can have more than one kind of marker, but
+// not the same kind twice.
+func (b *Board) AddMarker(kind Marker, a, c Position) error {
+	if _, ok := markerNames[kind]; !ok {
+		return fmt.Errorf("no such marker %v", kind)
+	}
+	from, to := coord{x: a.Row, y: a.Col}, coord{x: c.Row, y: c.Col}
+	for _, each := range []Position{a, c} {
+		if !b.puzzle.onBoard(coord{x: each.Row, y: each.Col}) {
+			return fmt.Errorf("cell %v is off the board", each)
+		}
+	}
+	if dx, dy := from.x-to.x, from.y-to.y; dx*dx+dy*dy != 1 {
+		return fmt.Errorf("cells %v and %v are not side by side", a, c)
+	}
+
+	var names []string
+	var markers []edgeMarker
+	var negative []Marker
+	if existing := b.puzzle.pairs; existing != nil {
+		names, markers, negative = existing.names, existing.markers, existing.negative
+	}
+	for _, each := range markers {
+		if each.kind == kind && (each.a == from && each.b == to || each.a == to && each.b == from) {
+			return fmt.Errorf("cells %v and %v already have a %v marker", a, c, kind)
+		}
+	}
+	markers = append(markers[:len(markers):len(markers)], edgeMarker{kind: kind, a: from, b: to})
+	b.puzzle.pairs = newPairList(b.puzzle.shape, names, markers, negative)
+	return nil
+}
+
+// AddNegative turns on the negative constraint for a kind of marker - every
+// pair of cells side by side without one breaks its rule, so cells without a
+// white dot between them are not consecutive. GreaterThan can't be negative.
+func (b *Board) AddNegative(kind Marker) error {
+	if _, ok := markerNames[kind]; !ok || kind == GreaterThan {
+		return fmt.Errorf("%v markers can't be negative", kind)
+	}
+
+	var names []string
+	var markers []edgeMarker
+	var negative []Marker
+	if existing := b.puzzle.pairs; existing != nil {
+		names, markers, negative = existing.names, existing.markers, existing.negative
+	}
+	for _, each := range negative {
+		if each == kind {
+			return fmt.Errorf("%v markers are already negative", kind)
+		}
+	}
+	negative = append(negative[:len(negative):len(negative)], kind)
+	b.puzzle.pairs = newPairList(b.puzzle.shape, names, markers, negative)
+	return nil
+}
+
+// Markers returns every marker added to the puzzle, in the order they were
+// added.
+func (b *Board) Markers() []EdgeMarker {
+	if b.puzzle.pairs == nil || len(b.puzzle.pairs.markers) == 0 {
+		return nil
+	}
+	result := make([]EdgeMarker, len(b.puzzle.pairs.markers))
+	for i, each := range b.puzzle.pairs.markers {
+		result[i] = EdgeMarker{Kind: each.kind, A: position(each.a), B: position(each.b)}
+	}
+	return result
+}
+
+// Negative returns the kinds of marker with the negative constraint on, in
+// the order they were added.
+func (b *Board) Negative() []Marker {
+	if b.puzzle.pairs == nil || len(b.puzzle.pairs.negative) == 0 {
+		return nil
+	}
+	return append([]Marker{}, b.puzzle.pairs.negative...)
+}
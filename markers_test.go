@@ -0,0 +1,185 @@
+package sudoku
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const kropkiPuzzle = `white: r1c2-r2c2 r1c6-r2c6 r1c7-r1c8 r2c4-r2c5 r2c6-r2c7 r2c6-r3c6 r2c9-r3c9 r3c3-r4c3 r3c4-r4c4 r3c7-r4c7 r3c9-r4c9 r4c2-r5c2 r4c6-r4c7 r5c1-r6c1 r5c5-r6c5 r5c6-r5c7 r5c7-r6c7 r5c9-r6c9 r6c3-r7c3 r7c5-r7c6 r7c7-r7c8 r7c9-r8c9 r8c4-r8c5 r8c6-r9c6 r8c7-r8c8 r9c2-r9c3 r9c4-r9c5 r9c7-r9c8
+black: r1c4-r1c5 r1c4-r2c4 r1c9-r2c9 r2c2-r2c3 r2c2-r3c2 r3c3-r3c4 r3c8-r3c9 r3c9-r4c9 r4c4-r4c5 r6c2-r6c3 r6c5-r7c5 r6c6-r7c6 r7c6-r8c6 r7c7-r7c8 r8c1-r9c1 r8c6-r9c6
+negative: white black
+.................................................................................
+`
+
+const (
+	kropkiSolution = "139425786742896513586317942357249861861573294924168375695734128413982657278651439"
+	xvMarkers      = `x: r1c1-r2c1 r1c4-r1c5 r2c5-r3c5 r2c6-r3c6 r3c1-r4c1 r3c7-r4c7 r4c5-r5c5 r5c1-r6c1 r5c6-r6c6 r5c9-r6c9 r6c7-r7c7 r8c1-r9c1 r8c4-r9c4 r8c6-r9c6 r8c7-r8c8 r9c2-r9c3
+v: r1c7-r1c8 r4c2-r5c2 r5c5-r6c5 r7c2-r7c3 r7c6-r7c7 r8c9-r9c9
+`
+	xvPuzzle        = "..................................................7........4....................."
+	xvSolution      = "487196235631572498295438761849265317712943856356817924523684179178359642964721583"
+	greaterPuzzle   = "......................4........................................................8."
+	greaterSolution = "964835721832719456175246839527684193619372548483591672246958317358127964791463285"
+)
+
+// boxMarkers puts a greater than marker between every pair of cells side by
+// side in the same box of a 9x9, going by the solution
+func boxMarkers(t *testing.T, b *Board, solution string) {
+	values := lineValues(solution)
+	for _, pair := range neighborPairs(b.puzzle.shape, sideBySide) {
+		a, c := pair[0], pair[1]
+		if a.x/3 != c.x/3 || a.y/3 != c.y/3 {
+			continue
+		}
+		if values[a.x][a.y] < values[c.x][c.y] {
+			a, c = c, a
+		}
+		if err := b.AddMarker(GreaterThan, position(a), position(c)); err != nil {
+			t.Fatalf("could not add marker - %v", err)
+		}
+	}
+}
+
+func TestMarkerRules(t *testing.T) {
+	var tests = []struct {
+		rule    pairRule
+		allowed [][2]int
+		denied  [][2]int
+	}{
+		{WhiteDot.rule(), [][2]int{{3, 4}, {4, 3}}, [][2]int{{3, 5}, {3, 3}}},
+		{BlackDot.rule(), [][2]int{{3, 6}, {6, 3}, {1, 2}}, [][2]int{{3, 4}, {2, 2}}},
+		{MarkerX.rule(), [][2]int{{1, 9}, {6, 4}}, [][2]int{{3, 3}, {2, 9}}},
+		{MarkerV.rule(), [][2]int{{1, 4}, {3, 2}}, [][2]int{{1, 5}}},
+		{GreaterThan.rule(), [][2]int{{5, 4}}, [][2]int{{4, 5}, {4, 4}}},
+		{GreaterThan.rule().reverse(), [][2]int{{4, 5}}, [][2]int{{5, 4}}},
+		{WhiteDot.negated(), [][2]int{{3, 5}}, [][2]int{{3, 4}, {4, 3}}},
+		{BlackDot.negated(), [][2]int{{3, 4}}, [][2]int{{3, 6}, {2, 1}}},
+		{MarkerX.negated(), [][2]int{{5, 6}}, [][2]int{{1, 9}}},
+		{MarkerV.negated(), [][2]int{{1, 5}}, [][2]int{{2, 3}}},
+	}
+
+	for id, testRun := range tests {
+		for _, each := range testRun.allowed {
+			assert.True(t, testRun.rule.allows(each[0], each[1]), "test %d - %v should be allowed", id, each)
+		}
+		for _, each := range testRun.denied {
+			assert.False(t, testRun.rule.allows(each[0], each[1]), "test %d - %v should be denied", id, each)
+		}
+	}
+}
+
+func TestAddMarker(t *testing.T) {
+	b := NewBoard(2)
+	assert.Error(t, b.AddMarker(WhiteDot, Position{0, 0}, Position{1, 1}), "not side by side")
+	assert.Error(t, b.AddMarker(WhiteDot, Position{0, 0}, Position{0, 2}), "not side by side")
+	assert.Error(t, b.AddMarker(WhiteDot, Position{3, 3}, Position{3, 4}), "off the board")
+	assert.Error(t, b.AddMarker(Marker(9), Position{0, 0}, Position{0, 1}), "no such marker")
+	assert.NoError(t, b.AddMarker(WhiteDot, Position{0, 0}, Position{0, 1}))
+	assert.Error(t, b.AddMarker(WhiteDot, Position{0, 1}, Position{0, 0}), "the same marker twice")
+	assert.NoError(t, b.AddMarker(BlackDot, Position{0, 1}, Position{0, 0}), "a 1 and 2 take both dots")
+	assert.NoError(t, b.AddMarker(GreaterThan, Position{1, 0}, Position{0, 0}))
+
+	assert.Error(t, b.AddNegative(GreaterThan))
+	assert.NoError(t, b.AddNegative(WhiteDot))
+	assert.Error(t, b.AddNegative(WhiteDot), "already negative")
+	assert.NoError(t, b.AddAntiKnight(), "constraints and markers go together")
+
+	assert.Equal(t, []EdgeMarker{
+		{WhiteDot, Position{0, 0}, Position{0, 1}},
+		{BlackDot, Position{0, 1}, Position{0, 0}},
+		{GreaterThan, Position{1, 0}, Position{0, 0}},
+	}, b.Markers())
+	assert.Equal(t, []Marker{WhiteDot}, b.Negative())
+	assert.Equal(t, []string{"anti-knight"}, b.Constraints())
+
+	pairs := b.puzzle.pairs
+	corner := pairs.links[pairs.index[coord{0, 0}]]
+	assert.Equal(t, []pairLink{
+		{to: coord{1, 2}, rules: []pairRule{pairDifferent}},
+		{to: coord{2, 1}, rules: []pairRule{pairDifferent}},
+		{to: coord{0, 1}, rules: []pairRule{pairConsecutive, pairDouble}},
+		{to: coord{1, 0}, rules: []pairRule{pairLess, pairNonConsecutive}},
+	}, corner, "the white dot should keep the negative rule off its pair")
+}
+
+func TestSolveMarkers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	kropki, err := Parse(strings.NewReader(kropkiPuzzle))
+	assert.NoError(t, err)
+	xv, err := Parse(strings.NewReader(xvMarkers + "negative: x v\n" + xvPuzzle))
+	assert.NoError(t, err)
+	greater, err := Parse(strings.NewReader(greaterPuzzle))
+	assert.NoError(t, err)
+	boxMarkers(t, greater, greaterSolution)
+
+	var tests = []struct {
+		b        *Board
+		solution string
+	}{
+		{kropki, kropkiSolution},
+		{xv, xvSolution},
+		{greater, greaterSolution},
+	}
+
+	for id, testRun := range tests {
+		result, err := NewFromBoard(testRun.b).Solve(ctx)
+		assert.NoError(t, err, "test %d - could not solve", id)
+		assert.Equal(t, Solved, result.Status, "test %d - puzzle should be solved", id)
+		assert.Equal(t, lineValues(testRun.solution), result.Values, "test %d - wrong solution", id)
+		assert.True(t, IsUnique(testRun.b), "test %d - puzzle should be unique", id)
+	}
+
+	// without the negative constraint, the markers alone aren't enough
+	positive, err := Parse(strings.NewReader(xvMarkers + xvPuzzle))
+	assert.NoError(t, err)
+	assert.False(t, IsUnique(positive), "the negative constraint should matter")
+}
+
+func TestMarkerContradiction(t *testing.T) {
+	b := NewBoard(2)
+	assert.NoError(t, b.Set(0, 0, 1))
+	assert.NoError(t, b.Set(0, 1, 3))
+	assert.NoError(t, b.AddMarker(WhiteDot, Position{0, 0}, Position{0, 1}))
+
+	_, err := NewFromBoard(b).Solve(context.Background())
+	assert.ErrorIs(t, err, ErrContradiction)
+}
+
+func TestFormatMarkers(t *testing.T) {
+	b, err := Parse(strings.NewReader(kropkiPuzzle))
+	if !assert.NoError(t, err, "could not parse") {
+		return
+	}
+	assert.NoError(t, b.AddConstraint("anti-king"))
+
+	var out bytes.Buffer
+	assert.NoError(t, Format(&out, b, LineStyle))
+	assert.Equal(t, "constraints: anti-king\n"+kropkiPuzzle, out.String(), "did not round trip")
+
+	var tests = []struct {
+		in   string
+		line int
+	}{
+		{"white: r1c1-r1c3\n" + strings.Repeat(".", 16), 1},
+		{"white: r1c1r1c2\n" + strings.Repeat(".", 16), 1},
+		{"\nnegative: greater\n" + strings.Repeat(".", 16), 2},
+		{"negative: dots\n" + strings.Repeat(".", 16), 1},
+		{"constraints: anti-bishop\n" + strings.Repeat(".", 16), 1},
+		{"black:\n" + strings.Repeat(".", 16), 1},
+	}
+	for id, testRun := range tests {
+		_, err := Parse(strings.NewReader(testRun.in))
+		parseErr, ok := err.(*ParseError)
+		if !assert.True(t, ok, "test %d - expected a parse error, got %v", id, err) {
+			continue
+		}
+		assert.Equal(t, testRun.line, parseErr.Line, "test %d - wrong line", id)
+	}
+}
@@ -1,5 +1,10 @@
 package sudoku
 
+import (
+	"fmt"
+	"sort"
+)
+
 // Sudoku has one rule - no value can be repeated horizontally, vertically,
 // or in the same section. This gives rise to a few simple rules - and those
 // rules are applied across each cluster - without even knowning the orientation
@@ -21,49 +26,97 @@ package sudoku
 // Additional Helper functions are included first.
 
 // indexedCLuster is a datatype for an index for the values of the cluster.
-// Each possible value is a key in the map. THe value of each key is an array of
-// possible locations for that value - the index and order are not defined,
-// instead the values of the array are the indexes of possible cells in for that
-// value.
-type intArray []int
-type indexedCluster map[int]intArray
-
-// maybe move fullArray into board, and move all of these moves into there?
-var fullArray intArray
-
-// need to initialize some other way?
-func init() {
-	for i, _ := range fullArray {
-		fullArray = append(fullArray, i)
-	}
+// Each possible value is a key in the map. THe value of each key is the set of
+// possible locations for that value - the members of the set are the indexes
+// of possible cells in the cluster for that value.
+type indexedCluster map[int]CandidateSet
+
+// a change is a cell update along with the reasoning behind it - the rule that
+// made it, the cluster the rule was looking at, and the cells that justify it
+type change struct {
+	cell
+	rule   Rule
+	orient int
+	index  int
+	cause  []coord
+	// chain holds the candidates of the chain behind a chain rule
+	chain []candidate
+	// sets holds the cells of each set behind a locked set rule
+	sets [][]coord
+	// trials holds the values tried out behind a forcing rule
+	trials []Trial
+}
+
+// locations returns the coords of some of the cells in a cluster
+func locations(ids CandidateSet, cluster []cell) []coord {
+	result := make([]coord, 0, ids.Count())
+	ids.Each(func(id int) {
+		result = append(result, cluster[id].location)
+	})
+	return result
 }
 
 // indexCluster takes a cluster of excluded values, and returns an index of
 //  the possible locations for each value.
-func indexCluster(in []cell) (out indexedCluster) {
+func indexCluster(in []cell) indexedCluster {
+	out := indexedCluster{}
 
-	// add the fullArray to every value location
-	for i, _ := range in {
-		copy(out[i], fullArray)
+	// add every unsolved cell to every value location
+	unsolved := indexSet(len(in))
+	for id, each := range in {
+		if each.actual != 0 {
+			unsolved = unsolved.Remove(id)
+		}
+	}
+	for value := 1; value <= len(in); value++ {
+		out[value] = unsolved
 	}
 
-	// I can simply delete known values from the array
+	// I can simply delete known values from the index
 	for _, each := range in {
-		if each.actual != 0{
+		if each.actual != 0 {
 			delete(out, each.actual)
 		}
 	}
 
 	// remove all exclusions
 	for id, each := range in {
-		for _, exclusion := range each.excluded {
-			out[exclusion] = subArr(out[exclusion], []int{exclusion})
+		if each.actual != 0 {
+			continue
 		}
+		each.excluded.Each(func(exclusion int) {
+			if locations, ok := out[exclusion]; ok {
+				out[exclusion] = locations.Remove(id)
+			}
+		})
 	}
 
 	return out
 }
 
+// clusterValid checks that the one rule still holds for a cluster - no value
+// is solved twice, and every unsolved value still has a cell it can go in.
+// The rules below assume this has passed.
+func clusterValid(cluster []cell) error {
+	var seen CandidateSet
+	for _, each := range cluster {
+		if each.actual == 0 {
+			continue
+		}
+		if seen.Has(each.actual) {
+			return fmt.Errorf("%w: %d is solved twice in one cluster", ErrContradiction, each.actual)
+		}
+		seen = seen.Add(each.actual)
+	}
+
+	for value, locations := range indexCluster(cluster) {
+		if locations.Empty() {
+			return fmt.Errorf("%w: no cell left for %d in one cluster", ErrContradiction, value)
+		}
+	}
+	return nil
+}
+
 // This covers rule 1 from above:
 // 1) If all cells are solved, that cluster is solved.
 func clusterSolved(cluster []cell) (solved bool) {
@@ -71,8 +124,6 @@ func clusterSolved(cluster []cell) (solved bool) {
 	for _, each := range cluster {
 		if each.actual == 0 {
 			solved = false
-		} else if len(each.possible) > 0 {
-			solved = false
 		}
 	}
 	return solved
@@ -80,11 +131,15 @@ func clusterSolved(cluster []cell) (solved bool) {
 
 // This covers rule 2 from above:
 // 2) If any cell is solved, it has all exclusions.
-func solvedNoPossible(cluster []cell) (changes []cell) {
-	for id, each := range cluster {
-		if each.actual != 0 && len(each.excluded) < len(fullArray) {
-			newExclusion := subArr(fullArray, each.excluded)
-			changes = append(changes, cell{location: each.location, excluded: newExclusion})
+func solvedNoPossible(cluster []cell) (changes []change) {
+	fullSet := valueSet(len(cluster))
+	for _, each := range cluster {
+		if each.actual != 0 && each.excluded.Count() < len(cluster)-1 {
+			newExclusion := fullSet.Remove(each.actual).Difference(each.excluded)
+			changes = append(changes, change{
+				cell:  cell{location: each.location, excluded: newExclusion},
+				rule:  RuleSolvedNoPossible,
+				cause: []coord{each.location}})
 		}
 	}
 	return
@@ -93,20 +148,32 @@ func solvedNoPossible(cluster []cell) (changes []cell) {
 // Removes known values from other possibles in the same cluster
 // Covers rule 3 from above:
 // 3) If any cell is solved, that value is excluded in other cells.
-func eliminateKnowns(cluster []cell) (changes []cell) {
-	var knownValues []int
+func eliminateKnowns(cluster []cell) (changes []change) {
+	var knownValues CandidateSet
+	knownCells := make(map[int]coord)
 
 	// Loop thru and find all solved values.
 	for _, each := range cluster {
 		if each.actual != 0 {
-			knownValues = append(knownValues, each.actual)
+			knownValues = knownValues.Add(each.actual)
+			knownCells[each.actual] = each.location
 		}
 	}
 
 	for _, each := range cluster {
-		if !allInArr(each.excluded, knownValues) {
-			newExclusion := subArr(knownValues, each.excluded)
-			changes = append(changes, cell{location: each.location, excluded: newExclusion})
+		// solved cells are handled by rule 2
+		if each.actual != 0 {
+			continue
+		}
+		if newExclusion := knownValues.Difference(each.excluded); !newExclusion.Empty() {
+			var cause []coord
+			newExclusion.Each(func(value int) {
+				cause = append(cause, knownCells[value])
+			})
+			changes = append(changes, change{
+				cell:  cell{location: each.location, excluded: newExclusion},
+				rule:  RuleEliminateKnowns,
+				cause: cause})
 		}
 	}
 	return
@@ -114,23 +181,25 @@ func eliminateKnowns(cluster []cell) (changes []cell) {
 
 // This covers the 4th rule from above:
 // 4) If any cell has all but one excluded value, that is that cell's value.
-func singleValueSolver(cluster []cell) (changes []cell) {
+func singleValueSolver(cluster []cell) (changes []change) {
 	for _, each := range cluster {
 		// skip this cell if it's already solved
 		if each.actual != 0 {
 			continue
 		}
 
-		// should never happen - probably #TODO# to catch this
-		if len(each.excluded) >= len(cluster) {
+		// should never happen - changeBoard refuses to exclude every value
+		if each.excluded.Count() >= len(cluster) {
 			panic("Found an unsolved cell with all values excluded")
 		}
 
-		if len(each.excluded) == len(cluster)-1 {
+		if each.excluded.Count() == len(cluster)-1 {
 			// send back an update for this cell
-			solvedValue := subArr(fullArray, each.excluded)
-			changes = append(changes, cell{location: each.location,
-				actual: solvedValue[0], possible: fullArray})
+			solvedValue := valueSet(len(cluster)).Difference(each.excluded)
+			changes = append(changes, change{
+				cell:  cell{location: each.location, actual: solvedValue.Min()},
+				rule:  RuleSingleValue,
+				cause: []coord{each.location}})
 		}
 	}
 	return
@@ -139,71 +208,106 @@ func singleValueSolver(cluster []cell) (changes []cell) {
 // ## Start rule 5 ##
 // 5) If any x cells have the same x values, the missing values are
 //  elsewhere excluded - those values are constrained to those cells.
-// 
-// A helper function to determine the values certain cells hit
-// ##TODO## review
-func exclusionsPainted(markedCells []int, cluster []cell) []int {
-	switch {
-	case len(markedCells) < 1:
-		// this will only happen when passed one cell
-		return []int{}
-	case len(markedCells) == 1:
-		return cluster[markedCells[0]].excluded
-	default:
-		first := cluster[markedCells[0]].excluded
-		second := exclusionsPainted(markedCells[1:], cluster)
-		return addArr(first, second)
-	}
-}
-
-func valuesPainted(markedCells []int, cluster []cell) []int {
-	return subArr(fullArray, exclusionsPainted(markedCells, cluster))
+//
+// A helper function to determine the values possible in any marked cell
+func valuesPainted(markedCells CandidateSet, cluster []cell) CandidateSet {
+	var values CandidateSet
+	markedCells.Each(func(id int) {
+		values = values.Union(cluster[id].possible)
+	})
+	return values
 }
 
 // A helper function to determine the number of valus hit given a specific set
 // of cells.
-func valuesCost(markedCells []int, cluster []cell) int {
-	return len(valuesPainted(markedCells, cluster))
+func valuesCost(markedCells CandidateSet, cluster []cell) int {
+	return valuesPainted(markedCells, cluster).Count()
 }
 
-func cellLimiterChild(markedCells, availableCells []int,
-	cluster []cell) (changes []cell) {
+// cellSubsets calls found with every set of up to limit cells, made of
+// markedCells and some of availableCells - until done is closed
+func cellSubsets(done <-chan struct{}, limit int, markedCells CandidateSet, availableCells []int,
+	found func(markedCells CandidateSet)) {
 	switch {
+	case stopped(done):
+		return
+	case markedCells.Count() > limit:
+		// too many cells marked to be worth looking at
+		return
 	case len(availableCells) < 1:
-		valuesSeen := valuesPainted(markedCells, cluster)
-		if len(valuesSeen) <= len(markedCells) {
+		found(markedCells)
+	default:
+		// try a child run without the current cell
+		cellSubsets(done, limit, markedCells, availableCells[1:], found)
+
+		// try a child run with the current cell
+		cellSubsets(done, limit, markedCells.Add(availableCells[0]), availableCells[1:], found)
+	}
+}
+
+// nakedSubsets calls found with every set of up to limit cells, made of
+// markedCells and some of availableCells, that holds no more than limit values
+// between them - values being the values of markedCells. Adding a cell never
+// takes a value away, so a set already holding too many is cut short. Stops
+// once done is closed.
+func nakedSubsets(done <-chan struct{}, limit int, markedCells, values CandidateSet, availableCells []int,
+	cluster []cell, found func(markedCells, values CandidateSet)) {
+	switch {
+	case stopped(done):
+		return
+	case markedCells.Count() > limit || values.Count() > limit:
+		return
+	case len(availableCells) < 1:
+		found(markedCells, values)
+	default:
+		// try a child run without the current cell
+		nakedSubsets(done, limit, markedCells, values, availableCells[1:], cluster, found)
+
+		// try a child run with the current cell
+		next := availableCells[0]
+		nakedSubsets(done, limit, markedCells.Add(next), values.Union(cluster[next].possible),
+			availableCells[1:], cluster, found)
+	}
+}
+
+func cellLimiterChild(done <-chan struct{}, limit int, markedCells CandidateSet, availableCells []int,
+	cluster []cell) (changes []change) {
+	painted := valuesPainted(markedCells, cluster)
+	nakedSubsets(done, limit, markedCells, painted, availableCells, cluster, func(markedCells, valuesSeen CandidateSet) {
+		if markedCells.Count() < 2 {
+			// one cell with one value is covered by rules 3 and 4
+			return
+		}
+		if valuesSeen.Count() <= markedCells.Count() {
 			// check the current marks, if valid, check removal
 			// check other cells for things to remove
-			cellsToClean := subArr(fullArray, markedCells)
-			for _, each := range cellsToClean {
-				valuesToRemove := subArr(valuesSeen, cluster[each].excluded)
-				if len(valuesToRemove) > 0 {
+			cellsToClean := indexSet(len(cluster)).Difference(markedCells)
+			cellsToClean.Each(func(each int) {
+				if cluster[each].actual != 0 {
+					// solved cells are handled by rule 3
+					return
+				}
+				valuesToRemove := valuesSeen.Difference(cluster[each].excluded)
+				if !valuesToRemove.Empty() {
 					// if you found something to remove from another cell
 					// create that update
-					changes = append(changes, cell{
-						location: cluster[each].location,
-						actual:   cluster[each].actual,
-						excluded: valuesToRemove})
+					changes = append(changes, change{
+						cell: cell{location: cluster[each].location,
+							excluded: valuesToRemove},
+						rule:  RuleCellLimiter,
+						cause: locations(markedCells, cluster)})
 				}
-			}
+			})
 		}
-	default:
-		// try a child run without the current cell
-		newChanges := cellLimiterChild(markedCells, availableCells[1:], cluster)
-		changes = append(changes, newChanges...)
-
-		// try a child run with the current cell
-		newCells := append(markedCells, availableCells[0])
-		newChanges = cellLimiterChild(newCells, availableCells[1:], cluster)
-		changes = append(changes, newChanges...)
-	}
+	})
 	return
 }
 
 // Actual function for rule 5:
 // 5) If any x cells have the same x values, the missing values are
 //  elsewhere excluded - those values are constrained to those cells.
-func cellLimiter(cluster []cell) []cell {
+// Only looks at up to limit cells at a time, and stops once done is closed.
+func cellLimiter(done <-chan struct{}, limit int, cluster []cell) []change {
 	var availableCells []int
 
 	for id, each := range cluster {
@@ -212,23 +316,25 @@ func cellLimiter(cluster []cell) []cell {
 		}
 	}
 
-	return cellLimiterChild([]int{}, availableCells, cluster)
+	return cellLimiterChild(done, limit, CandidateSet{}, availableCells, cluster)
 }
 
 // ## END Rule 5 ##
 
 // This covers rule 6 from above:
 // 6) If any value is possible in only one cell, that is that cell's value.
-func singleCellSolver(index indexedCluster, cluster []cell) (changes []cell) {
+func singleCellSolver(index indexedCluster, cluster []cell) (changes []change) {
 	for val, section := range index {
-		if len(section) < 1 {
-			// something went terribly wrong here - #TODO# add panic catch?
+		if section.Empty() {
+			// should never happen - clusterValid checks this first
 			panic("Found a value with no possible cells")
-		} else if len(section) == 1 {
-			changes = append(changes, cell{
-				location: cluster[section[0]].location,
-				actual:   val,
-				excluded: fullArray,
+		} else if section.Count() == 1 {
+			// every other cell in the cluster rules the value out
+			changes = append(changes, change{
+				cell: cell{location: cluster[section.Min()].location,
+					actual: val},
+				rule:  RuleSingleCell,
+				cause: locations(indexSet(len(cluster)).Difference(section), cluster),
 			})
 		}
 	}
@@ -236,11 +342,11 @@ func singleCellSolver(index indexedCluster, cluster []cell) (changes []cell) {
 }
 
 // ## Start Rule 7 ##
-// 
+//
 // A helper function to determine what cells are painted by given values
-func cellsPainted(markedVals []int, index indexedCluster) (neededCells []int) {
+func cellsPainted(markedVals []int, index indexedCluster) (neededCells CandidateSet) {
 	for _, value := range markedVals {
-		neededCells = addArr(neededCells, index[value])
+		neededCells = neededCells.Union(index[value])
 	}
 	return
 }
@@ -248,37 +354,42 @@ func cellsPainted(markedVals []int, index indexedCluster) (neededCells []int) {
 // A helper function to determine the number of cells hit by working across a map
 // of values.
 func cellsCost(markedVals []int, index indexedCluster) int {
-	return len(cellsPainted(markedVals, index))
+	return cellsPainted(markedVals, index).Count()
 }
 
-func valueLimiterChild(limit int, markedValues []int, index indexedCluster,
-	cluster []cell) (changes []cell) {
+func valueLimiterChild(done <-chan struct{}, limit int, markedValues, availableValues []int,
+	index indexedCluster, cluster []cell) (changes []change) {
 	cellCount := cellsCost(markedValues, index)
 	switch {
-	case cellCount < len(markedValues):
-		panic("less cells available than the values that need to go in them")
-	case len(markedValues) > limit:
-		// we have marked more values
-		return []cell{}
-	case cellCount == len(markedValues):
+	case stopped(done):
+		return []change{}
+	case cellCount > limit:
+		// marking more values will never cover fewer cells
+		return []change{}
+	case len(markedValues) == limit:
+		if cellCount < limit {
+			// less cells than values - the one rule is already broken, and
+			// clusterValid will catch it once the other rules catch up
+			return []change{}
+		}
 		// you have exactly as many values as cells
+		otherValues := valueSet(len(cluster)).Difference(NewCandidateSet(markedValues...))
 		cellsCovered := cellsPainted(markedValues, index)
-		for _, id := range cellsCovered {
-			if toRemove := subArr(markedValues, cluster[id].excluded); len(toRemove) > 0 {
-				changes = append(changes, cell{
-					location: cluster[id].location,
-					excluded: toRemove})
+		cellsCovered.Each(func(id int) {
+			if toRemove := otherValues.Difference(cluster[id].excluded); !toRemove.Empty() {
+				changes = append(changes, change{
+					cell: cell{location: cluster[id].location,
+						excluded: toRemove},
+					rule:  RuleValueLimiter,
+					cause: locations(cellsCovered, cluster)})
 			}
-		}
-	case len(markedValues) < limit:
+		})
+	default:
 		// you can mark another value and see where that gets you
-		for value, _ := range index {
-			if inArr(markedValues, value) {
-				// this value is already marked
-				continue
-			}
+		for i, value := range availableValues {
 			// decend down into looking at that value
-			changes = append(changes, valueLimiterChild(limit, append(markedValues, value),
+			changes = append(changes, valueLimiterChild(done, limit,
+				append(markedValues, value), availableValues[i+1:],
 				index, cluster)...)
 		}
 	}
@@ -288,10 +399,17 @@ func valueLimiterChild(limit int, markedValues []int, index indexedCluster,
 // This covers rule 7 from above:
 // 7) If any x values are possible in x cells, all other values are excluded in
 //  those cells.
-func valueLimiter(index indexedCluster, cluster []cell) (changes []cell) {
-	upperBound := len(index)
-	for i := 2; i <= upperBound; i++ {
-		changes = append(changes, valueLimiterChild(i, []int{}, index, cluster)...)
+// Only looks at up to limit values at a time, and stops once done is closed.
+func valueLimiter(done <-chan struct{}, limit int, index indexedCluster, cluster []cell) (changes []change) {
+	var values []int
+	for value := range index {
+		values = append(values, value)
+	}
+	sort.Ints(values)
+
+	// one value in one cell is rule 6, every value in every cell says nothing
+	for i := 2; i < len(values) && i <= limit; i++ {
+		changes = append(changes, valueLimiterChild(done, i, []int{}, values, index, cluster)...)
 	}
 	return changes
 }
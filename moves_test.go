@@ -1,164 +1,74 @@
 package sudoku
 
 import (
-	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
-func loadCluster(inputFile string) ([][]cell, error) {
-	b, err := ioutil.ReadFile(inputFile)
-	if err != nil {
-		return err
-	}
-
-	var v [][]cell
-	err = Unmarshal(b, v)
-	if err != nil {
-		return err
-	}
-	return v
-}
-
-func loadBools(inputFile string) ([]bool, error) {
-	b, err := ioutil.ReadFile(inputFile)
-	if err != nil {
-		return err
-	}
-
-	var v []bool
-	err = Unmarshal(b, v)
-	if err != nil {
-		return err
-	}
-	return v
-}
-
-func loadIndex(inputFile string) ([]bool, error) {
-	b, err := ioutil.ReadFile(inputFile)
-	if err != nil {
-		return err
-	}
-
-	var v []indexedCluster
-	err = Unmarshal(b, v)
-	if err != nil {
-		return err
-	}
-	return v
-}
-
-func updateCatcher(in, out chan cell) {
-	defer close(out)
-	more := true
-	var updates Cluster
-	var val cell
-	for more {
-		val, more = <-in
-		if more {
-			updates = append(updates, val)
-		}
-	}
-	sort.Sort(updates)
-	for _, each := range updates {
-		out <- each
-	}
-	return
-}
-
 func TestIndexCluster(t *testing.T) {
-	input, err := loadCluster("testdata/moves_input.json")
-	if err != nil {
-		t.FatalF("expected input could not be loaded - %v", err)
-	}
-
-	expected, err := loadIndex("testdata/moves_index.json")
-	if err != nil {
-		t.FatalF("expected cound not be loaded - %v", err)
-	}
-
-	if len(input) != len(expected) {
-		t.FatalF("input and expected do not have the same number of tests")
-	}
-
-	for i := 0; i < len(input); i++ {
-		output := indexCluster(input[i])
-		assert.Equal(t, expected, output, "test %d - expected index and returned index differ", i)
+	all := NewCandidateSet(0, 1, 2, 3)
+	var tests = []struct {
+		in  []cell
+		out indexedCluster
+	}{
+		{
+			[]cell{{}, {}, {}, {}},
+			indexedCluster{1: all, 2: all, 3: all, 4: all},
+		}, {
+			// solved values and cells drop out, as do exclusions
+			[]cell{{excluded: NewCandidateSet(3)}, {actual: 2}, {}, {}},
+			indexedCluster{1: NewCandidateSet(0, 2, 3), 3: NewCandidateSet(2, 3), 4: NewCandidateSet(0, 2, 3)},
+		}, {
+			[]cell{{actual: 1}, {actual: 2}, {actual: 3}, {excluded: NewCandidateSet(1, 2, 3)}},
+			indexedCluster{4: NewCandidateSet(3)},
+		},
+	}
+
+	for id, testRun := range tests {
+		assert.Equal(t, testRun.out, indexCluster(testRun.in), "test %d - wrong index", id)
 	}
 }
 
 func TestClusterSolved(t *testing.T) {
-	input, err := loadCluster("testdata/moves_input.json")
-	if err != nil {
-		t.FatalF("expected input could not be loaded - %v", err)
-	}
-
-	expected, err := loadBools("testdata/moves_one.json")
-	if err != nil {
-		t.FatalF("expected cound not be loaded - %v", err)
+	var tests = []struct {
+		in     []cell
+		solved bool
+	}{
+		{[]cell{{actual: 1}, {actual: 2}, {actual: 3}, {actual: 4}}, true},
+		{[]cell{{actual: 1}, {actual: 2}, {}, {actual: 4}}, false},
+		{[]cell{{}, {}, {}, {}}, false},
+		{nil, true},
 	}
 
-	if len(input) != len(expected) {
-		t.FatalF("input and expected do not have the same number of tests")
-	}
-
-	for i := 0; i < len(input); i++ {
-		var update chan cell
-		var more bool
-		result := clusterSolved(input[i], update)
-		select {
-		case _, more = <-update:
-		default:
-			more = true
-		}
-
-		assert.Equal(t, expected[i], result, "test %d - return value differs", i)
-		assert.Equal(t, expected[i], more, "test %d - channel status differs", i)
+	for id, testRun := range tests {
+		assert.Equal(t, testRun.solved, clusterSolved(testRun.in), "test %d - wrong result", id)
 	}
 }
 
 func TestSolvedNoPossible(t *testing.T) {
-	input, err := loadCluster("testdata/moves_input.json")
-	if err != nil {
-		t.FatalF("input could not be loaded - %v", err)
-	}
-
-	expectedResponse, err := loadCluster("testdata/moves_two_response.json")
-	if err != nil {
-		t.FatalF("expected cound not be loaded - %v", err)
-	}
-
-	expected, err := loadCluster("testdata/moves_two_updates.json")
-	if err != nil {
-		t.FatalF("expected cound not be loaded - %v", err)
-	}
-
-	if len(input) != len(expected) {
-		t.FatalF("input and expected count differs")
-	}
-
-	for i := 0; i < len(input); i++ {
-		var updates, back chan cell
-		var more bool
-		go updateCatcher(updates, back)
-		result := clusterSolved(input[i], update)
-
-		assert.Equal(t, expectedResponse[i], result, "expected and actual return differs")
-		close(updates)
-		sort.Sort(expected[i])
-
-		for _, update := range expected[i] {
-			assert.Equal(t, expected[i], <-back, "test %d - return value differs", i)
-		}
-
-		junk, more := <-update
-		if more {
-			t.Fail()
-			t.Logf("test %d - channel status differs", i)
-			for more {
-				t.Log(junk)
-				junk, more = <-update
-			}
-		}
+	var tests = []struct {
+		in      []cell
+		changes []change
+	}{
+		{
+			[]cell{{location: coord{0, 0}, actual: 2}, {location: coord{0, 1}}, {location: coord{0, 2}},
+				{location: coord{0, 3}}},
+			[]change{{cell: cell{location: coord{0, 0}, excluded: NewCandidateSet(1, 3, 4)}, rule: RuleSolvedNoPossible,
+				cause: []coord{{0, 0}}}},
+		}, {
+			// only the exclusions still missing are changed
+			[]cell{{location: coord{0, 0}, actual: 2, excluded: NewCandidateSet(1)}, {location: coord{0, 1}, actual: 3,
+				excluded: NewCandidateSet(1, 2, 4)}, {location: coord{0, 2}}, {location: coord{0, 3}}},
+			[]change{{cell: cell{location: coord{0, 0}, excluded: NewCandidateSet(3, 4)}, rule: RuleSolvedNoPossible,
+				cause: []coord{{0, 0}}}},
+		}, {
+			[]cell{{location: coord{0, 0}}, {location: coord{0, 1}}, {location: coord{0, 2}},
+				{location: coord{0, 3}}},
+			nil,
+		},
+	}
+
+	for id, testRun := range tests {
+		assert.Equal(t, testRun.changes, solvedNoPossible(testRun.in), "test %d - wrong changes", id)
 	}
 }
@@ -0,0 +1,348 @@
+package sudoku
+
+// Anti-knight, anti-king and non-consecutive puzzles add rules between pairs
+// of cells - a knight's move apart, a king's move apart, or side by side - as
+// opposed to rules over a whole cluster, and so do the markers of Kropki, XV
+// and greater-than puzzles. Every cell with a neighbor gets a pair cluster of
+// its own: the cell first, then each cell it has a rule with. A pair cluster
+// is picked up by clusterFilter whenever any of its cells changes, so a cell
+// being solved sends exclusions to its neighbors thru the same update channel
+// as every other rule.
+
+import (
+	"errors"
+	"fmt"
+)
+
+// pairRule says which values two cells with a rule between them may hold -
+// the first cell of the pair, then the second
+type pairRule int
+
+const (
+	// pairDifferent cells can't hold the same value
+	pairDifferent pairRule = iota
+	// pairNonConsecutive cells can't hold values one apart
+	pairNonConsecutive
+	// pairConsecutive cells hold values one apart - a white dot
+	pairConsecutive
+	// pairDouble cells hold values where one is twice the other - a black dot
+	pairDouble
+	// pairNotDouble cells can't hold values where one is twice the other
+	pairNotDouble
+	// pairSumTen cells add up to 10 - an X
+	pairSumTen
+	// pairNotSumTen cells can't add up to 10
+	pairNotSumTen
+	// pairSumFive cells add up to 5 - a V
+	pairSumFive
+	// pairNotSumFive cells can't add up to 5
+	pairNotSumFive
+	// pairGreater has the first cell greater than the second
+	pairGreater
+	// pairLess has the first cell less than the second
+	pairLess
+)
+
+// allows returns true if the rule lets one cell hold a while the other holds b
+func (r pairRule) allows(a, b int) bool {
+	switch r {
+	case pairDifferent:
+		return a != b
+	case pairNonConsecutive:
+		return a-b != 1 && b-a != 1
+	case pairConsecutive:
+		return a-b == 1 || b-a == 1
+	case pairDouble:
+		return a == b*2 || b == a*2
+	case pairNotDouble:
+		return a != b*2 && b != a*2
+	case pairSumTen:
+		return a+b == 10
+	case pairNotSumTen:
+		return a+b != 10
+	case pairSumFive:
+		return a+b == 5
+	case pairNotSumFive:
+		return a+b != 5
+	case pairGreater:
+		return a > b
+	case pairLess:
+		return a < b
+	default:
+		return true
+	}
+}
+
+// reverse returns the rule as seen from the other cell of the pair
+func (r pairRule) reverse() pairRule {
+	switch r {
+	case pairGreater:
+		return pairLess
+	case pairLess:
+		return pairGreater
+	default:
+		return r
+	}
+}
+
+// pairLink is every rule between a cell and one of its neighbors
+type pairLink struct {
+	to    coord
+	rules []pairRule
+}
+
+// allows returns true if every rule of the link lets the cell hold a while
+// the neighbor holds b
+func (l pairLink) allows(a, b int) bool {
+	for _, rule := range l.rules {
+		if !rule.allows(a, b) {
+			return false
+		}
+	}
+	return true
+}
+
+// pairConstraint is a rule between every cell and the cells offsets away
+type pairConstraint struct {
+	rule    pairRule
+	offsets []coord
+}
+
+var (
+	knightMoves = []coord{{-2, -1}, {-2, 1}, {-1, -2}, {-1, 2}, {1, -2}, {1, 2}, {2, -1}, {2, 1}}
+	kingMoves   = []coord{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
+	sideBySide  = []coord{{-1, 0}, {0, -1}, {0, 1}, {1, 0}}
+)
+
+// pairConstraints holds every constraint that can be added by name
+var pairConstraints = map[string]pairConstraint{
+	"anti-knight":     {pairDifferent, knightMoves},
+	"anti-king":       {pairDifferent, kingMoves},
+	"non-consecutive": {pairNonConsecutive, sideBySide},
+}
+
+// pairList lists the boardPair clusters of a shape. cells[i] is the cell at
+// the middle of cluster i, and links[i] its neighbors, in the order they sit
+// in the cluster after it. index[c] is the cluster coord c is the middle of.
+//
+// The clusters are built from the constraints named in names, the markers,
+// and the markers that are negative - every pair of cells side by side
+// without one of those has the opposite rule.
+type pairList struct {
+	cells    []coord
+	links    [][]pairLink
+	index    map[coord]int
+	names    []string
+	markers  []edgeMarker
+	negative []Marker
+}
+
+// newPairList builds the pair clusters of a puzzle out of its constraints and
+// markers
+func newPairList(s shape, names []string, markers []edgeMarker, negative []Marker) *pairList {
+	result := &pairList{index: make(map[coord]int), names: names, markers: markers, negative: negative}
+
+	link := func(from, to coord, rule pairRule) {
+		index, ok := result.index[from]
+		if !ok {
+			index = len(result.cells)
+			result.index[from] = index
+			result.cells = append(result.cells, from)
+			result.links = append(result.links, nil)
+		}
+		for i, each := range result.links[index] {
+			if each.to == to {
+				result.links[index][i].rules = append(each.rules, rule)
+				return
+			}
+		}
+		result.links[index] = append(result.links[index], pairLink{to: to, rules: []pairRule{rule}})
+	}
+	both := func(a, b coord, rule pairRule) {
+		link(a, b, rule)
+		link(b, a, rule.reverse())
+	}
+
+	for _, name := range names {
+		for _, pair := range neighborPairs(s, pairConstraints[name].offsets) {
+			both(pair[0], pair[1], pairConstraints[name].rule)
+		}
+	}
+	marked := make(map[[2]coord]map[Marker]bool)
+	for _, each := range markers {
+		both(each.a, each.b, each.kind.rule())
+		for _, key := range [][2]coord{{each.a, each.b}, {each.b, each.a}} {
+			if marked[key] == nil {
+				marked[key] = make(map[Marker]bool)
+			}
+			marked[key][each.kind] = true
+		}
+	}
+	if len(negative) > 0 {
+		for _, pair := range neighborPairs(s, sideBySide) {
+			for _, kind := range negative {
+				if !marked[pair][kind] {
+					both(pair[0], pair[1], kind.negated())
+				}
+			}
+		}
+	}
+	return result
+}
+
+// neighborPairs returns every pair of cells on the board offsets apart, each
+// pair once
+func neighborPairs(s shape, offsets []coord) [][2]coord {
+	var pairs [][2]coord
+	for x := 0; x < s.rows(); x++ {
+		for y := 0; y < s.cols(); y++ {
+			from := coord{x: x, y: y}
+			if !s.onBoard(from) {
+				continue
+			}
+			for _, offset := range offsets {
+				to := coord{x: x + offset.x, y: y + offset.y}
+				// each pair once - the rules go both ways
+				if to.x < x || to.x == x && to.y < y || !s.onBoard(to) {
+					continue
+				}
+				pairs = append(pairs, [2]coord{from, to})
+			}
+		}
+	}
+	return pairs
+}
+
+// at returns the pair clusters coord c sits in - its own, and the one of
+// every neighbor
+func (p *pairList) at(c coord) []int {
+	index, ok := p.index[c]
+	if !ok {
+		return nil
+	}
+	result := []int{index}
+	for _, each := range p.links[index] {
+		result = append(result, p.index[each.to])
+	}
+	return result
+}
+
+// cluster returns the coords of pair cluster index - the middle cell, then
+// its neighbors
+func (p *pairList) cluster(index int) []coord {
+	result := []coord{p.cells[index]}
+	for _, each := range p.links[index] {
+		result = append(result, each.to)
+	}
+	return result
+}
+
+// moves returns the rules for pair cluster index. Once the middle cell is
+// solved, its neighbors lose every value the rules don't allow next to it. At
+// Medium, the middle cell also loses every value some neighbor has nothing
+// to go with.
+func (p *pairList) moves(index int, opts settings) moves {
+	links := p.links[index]
+	return func(cells cluster) ([]change, error) {
+		middle := cells[0]
+		var changes []change
+		for i, link := range links {
+			other := cells[i+1]
+			switch {
+			case middle.actual != 0 && other.actual != 0:
+				if !link.allows(middle.actual, other.actual) {
+					return nil, fmt.Errorf("%w: %v and %v can't hold %d and %d", ErrContradiction,
+						position(middle.location), position(other.location), middle.actual, other.actual)
+				}
+			case middle.actual != 0:
+				var drop CandidateSet
+				other.possible.Each(func(value int) {
+					if !link.allows(middle.actual, value) {
+						drop = drop.Add(value)
+					}
+				})
+				if !drop.Empty() {
+					changes = append(changes, change{
+						cell:  cell{location: other.location, excluded: drop},
+						rule:  RulePairExclusion,
+						cause: []coord{middle.location}})
+				}
+			case other.actual == 0 && opts.level >= ruleLevel(RulePairSupport, 1):
+				var drop CandidateSet
+				middle.possible.Each(func(value int) {
+					supported := false
+					other.possible.Each(func(partner int) {
+						supported = supported || link.allows(value, partner)
+					})
+					if !supported {
+						drop = drop.Add(value)
+					}
+				})
+				if !drop.Empty() {
+					changes = append(changes, change{
+						cell:  cell{location: middle.location, excluded: drop},
+						rule:  RulePairSupport,
+						cause: []coord{other.location}})
+				}
+			}
+		}
+		return changes, nil
+	}
+}
+
+// addPairs adds a constraint from pairConstraints, by name
+func (b *Board) addPairs(name string) error {
+	var names []string
+	var markers []edgeMarker
+	var negative []Marker
+	if existing := b.puzzle.pairs; existing != nil {
+		for _, each := range existing.names {
+			if each == name {
+				return fmt.Errorf("the puzzle is already %s", name)
+			}
+		}
+		names, markers, negative = existing.names, existing.markers, existing.negative
+	}
+	if _, ok := pairConstraints[name]; !ok {
+		return fmt.Errorf("no such constraint %q", name)
+	}
+	if len(neighborPairs(b.puzzle.shape, pairConstraints[name].offsets)) == 0 {
+		return errors.New("the board is too small for any pairs")
+	}
+	names = append(names[:len(names):len(names)], name)
+	b.puzzle.pairs = newPairList(b.puzzle.shape, names, markers, negative)
+	return nil
+}
+
+// AddAntiKnight makes the puzzle anti-knight - no two cells a knight's move
+// apart hold the same value.
+func (b *Board) AddAntiKnight() error {
+	return b.addPairs("anti-knight")
+}
+
+// AddAntiKing makes the puzzle anti-king - no two cells a king's move apart
+// hold the same value.
+func (b *Board) AddAntiKing() error {
+	return b.addPairs("anti-king")
+}
+
+// AddNonConsecutive makes the puzzle non-consecutive - no two cells side by
+// side hold values one apart.
+func (b *Board) AddNonConsecutive() error {
+	return b.addPairs("non-consecutive")
+}
+
+// AddConstraint adds a constraint between pairs of cells by name - one of
+// "anti-knight", "anti-king" or "non-consecutive".
+func (b *Board) AddConstraint(name string) error {
+	return b.addPairs(name)
+}
+
+// Constraints returns the name of every constraint between pairs of cells
+// added to the puzzle, in the order they were added.
+func (b *Board) Constraints() []string {
+	if b.puzzle.pairs == nil || len(b.puzzle.pairs.names) == 0 {
+		return nil
+	}
+	return append([]string{}, b.puzzle.pairs.names...)
+}
@@ -0,0 +1,169 @@
+package sudoku
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	antiKnightPuzzle       = ".9..36.24.......9....9..537..1..............9....27.................5.7...4.7.2.."
+	antiKnightSolution     = "598736124327541698416982537741698352283154769659327481175269843832415976964873215"
+	antiKingPuzzle         = ".....2.........6.97..169.53..8...2....4.....6.........9.1.....7.....7.1537....9.."
+	antiKingSolution       = "869532741513784629742169853638951274194278536257643198981325467426897315375416982"
+	nonConsecutivePuzzle   = "9.............4...6.......32.......8..1.5........7..6....9......................."
+	nonConsecutiveSolution = "972538641358164927614792583297416358461853792835279164583927416146385279729641835"
+)
+
+func TestPairRule(t *testing.T) {
+	var tests = []struct {
+		rule  pairRule
+		a, b  int
+		allow bool
+	}{
+		{pairDifferent, 1, 2, true},
+		{pairDifferent, 3, 3, false},
+		{pairNonConsecutive, 3, 3, true},
+		{pairNonConsecutive, 3, 4, false},
+		{pairNonConsecutive, 4, 3, false},
+		{pairNonConsecutive, 3, 5, true},
+	}
+
+	for id, testRun := range tests {
+		assert.Equal(t, testRun.allow, testRun.rule.allows(testRun.a, testRun.b), "test %d - wrong answer", id)
+	}
+}
+
+func TestPairClusters(t *testing.T) {
+	b := NewBoard(2)
+	assert.NoError(t, b.AddAntiKing())
+	assert.NoError(t, b.AddNonConsecutive())
+	assert.Equal(t, []string{"anti-king", "non-consecutive"}, b.Constraints())
+
+	pairs := b.puzzle.pairs
+	assert.Len(t, pairs.cells, 16, "every cell has a neighbor")
+	corner := pairs.links[pairs.index[coord{0, 0}]]
+	assert.Equal(t, []pairLink{
+		{to: coord{0, 1}, rules: []pairRule{pairDifferent, pairNonConsecutive}},
+		{to: coord{1, 0}, rules: []pairRule{pairDifferent, pairNonConsecutive}},
+		{to: coord{1, 1}, rules: []pairRule{pairDifferent}},
+	}, corner, "the rules of both constraints should be on one link")
+
+	refs, err := b.puzzle.clustersAt(coord{0, 0})
+	assert.NoError(t, err)
+	var pairRefs []clusterRef
+	for _, ref := range refs {
+		if ref.orient == boardPair {
+			pairRefs = append(pairRefs, ref)
+			picked, err := pickCluster(b.puzzle, ref)
+			assert.NoError(t, err)
+			assert.Contains(t, picked, b.puzzle.clusters[0][0], "%v not in %v", coord{0, 0}, ref)
+		}
+	}
+	assert.Len(t, pairRefs, 4, "its own cluster, and one for each neighbor")
+
+	// the board a constraint was added to is the only one to see it
+	other := NewBoard(2)
+	assert.Nil(t, other.Constraints())
+	copied := *b
+	assert.NoError(t, copied.AddAntiKnight())
+	assert.Len(t, b.Constraints(), 2)
+}
+
+func TestPairMoves(t *testing.T) {
+	b := NewBoard(2)
+	assert.NoError(t, b.AddNonConsecutive())
+	pairs := b.puzzle.pairs
+	index := pairs.index[coord{0, 0}]
+
+	var tests = []struct {
+		level   Difficulty
+		middle  cell
+		right   cell
+		down    cell
+		changes []change
+	}{
+		// a solved 2 pushes 1 and 3 out of both neighbors
+		{Easy,
+			cell{location: coord{0, 0}, actual: 2, possible: NewCandidateSet(2)},
+			cell{location: coord{0, 1}, possible: valueSet(4)},
+			cell{location: coord{1, 0}, possible: NewCandidateSet(1, 4)},
+			[]change{
+				{cell: cell{location: coord{0, 1}, excluded: NewCandidateSet(1, 3)}, rule: RulePairExclusion, cause: []coord{{0, 0}}},
+				{cell: cell{location: coord{1, 0}, excluded: NewCandidateSet(1)}, rule: RulePairExclusion, cause: []coord{{0, 0}}},
+			}},
+		// nothing goes with a 2 next to a cell of 1 or 3
+		{Medium,
+			cell{location: coord{0, 0}, possible: NewCandidateSet(2, 4)},
+			cell{location: coord{0, 1}, possible: NewCandidateSet(1, 3)},
+			cell{location: coord{1, 0}, possible: valueSet(4)},
+			[]change{
+				{cell: cell{location: coord{0, 0}, excluded: NewCandidateSet(2)}, rule: RulePairSupport, cause: []coord{{0, 1}}},
+			}},
+		// the same, but Easy doesn't look
+		{Easy,
+			cell{location: coord{0, 0}, possible: NewCandidateSet(2, 4)},
+			cell{location: coord{0, 1}, possible: NewCandidateSet(1, 3)},
+			cell{location: coord{1, 0}, possible: valueSet(4)},
+			nil},
+	}
+
+	for id, testRun := range tests {
+		rules := pairs.moves(index, settings{level: testRun.level})
+		changes, err := rules(cluster{testRun.middle, testRun.right, testRun.down})
+		assert.NoError(t, err, "test %d - unexpected error", id)
+		assert.Equal(t, testRun.changes, changes, "test %d - wrong changes", id)
+	}
+
+	_, err := pairs.moves(index, settings{})(cluster{
+		{location: coord{0, 0}, actual: 2}, {location: coord{0, 1}, actual: 3}, {location: coord{1, 0}, actual: 4}})
+	assert.ErrorIs(t, err, ErrContradiction, "2 and 3 side by side")
+}
+
+func TestSolvePairs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var tests = []struct {
+		puzzle, solution string
+		add              func(*Board) error
+	}{
+		{antiKnightPuzzle, antiKnightSolution, (*Board).AddAntiKnight},
+		{antiKingPuzzle, antiKingSolution, (*Board).AddAntiKing},
+		{nonConsecutivePuzzle, nonConsecutiveSolution, (*Board).AddNonConsecutive},
+	}
+
+	for id, testRun := range tests {
+		b, err := Parse(strings.NewReader(testRun.puzzle))
+		if !assert.NoError(t, err, "test %d - could not parse", id) {
+			continue
+		}
+		assert.False(t, IsUnique(b), "test %d - the constraint should matter", id)
+
+		assert.NoError(t, testRun.add(b), "test %d - could not add the constraint", id)
+		assert.Error(t, testRun.add(b), "test %d - the constraint is already there", id)
+		result, err := NewFromBoard(b).Solve(ctx)
+		assert.NoError(t, err, "test %d - could not solve", id)
+		assert.Equal(t, Solved, result.Status, "test %d - puzzle should be solved", id)
+		assert.Equal(t, lineValues(testRun.solution), result.Values, "test %d - wrong solution", id)
+		assert.True(t, IsUnique(b), "test %d - puzzle should be unique", id)
+
+		_, report := Grade(b)
+		assert.Equal(t, 0, report.Guesses, "test %d - should solve without guessing", id)
+		assert.NotZero(t, report.Counts[RulePairExclusion], "test %d - the pairs should be used", id)
+	}
+}
+
+func TestPairContradiction(t *testing.T) {
+	// fine as a plain puzzle, but the 1s are a knight's move apart
+	b := NewBoard(3)
+	assert.NoError(t, b.Set(0, 0, 1))
+	assert.NoError(t, b.Set(2, 1, 1))
+	assert.NoError(t, b.AddAntiKnight())
+
+	_, err := NewFromBoard(b).Solve(context.Background())
+	assert.ErrorIs(t, err, ErrContradiction)
+}
@@ -0,0 +1,133 @@
+package sudoku
+
+// Jigsaw puzzles swap the boxes for regions of any shape. The rules never
+// look at the shape of a cluster, so a region is just another cluster - the
+// region map only changes which cells getPos and clusterPicker put together.
+
+import (
+	"fmt"
+)
+
+// regionMap assigns every coord to a region. ids[x][y] is the region of
+// coord{x, y}, and cells[id] lists the coords in region id, row by row.
+type regionMap struct {
+	ids   [][]int
+	cells [][]coord
+}
+
+// regionError is returned for a region map that doesn't make a puzzle, along
+// with the coord that gave it away
+type regionError struct {
+	at  coord
+	msg string
+}
+
+func (e *regionError) Error() string {
+	return fmt.Sprintf("%v: %s", position(e.at), e.msg)
+}
+
+// newRegionMap checks a region map - every row as long as there are rows,
+// every id from 0 thru side-1, and every region side cells that all touch -
+// and indexes it by region
+func newRegionMap(ids [][]int) (*regionMap, error) {
+	side := len(ids)
+	if side < 1 {
+		return nil, &regionError{msg: "no regions given"}
+	}
+	result := &regionMap{ids: make([][]int, side), cells: make([][]coord, side)}
+	for x, row := range ids {
+		if len(row) != side {
+			return nil, &regionError{at: coord{x: x}, msg: fmt.Sprintf("row has %d cells, expected %d", len(row), side)}
+		}
+		result.ids[x] = append([]int{}, row...)
+		for y, id := range row {
+			if id < 0 || id >= side {
+				return nil, &regionError{at: coord{x: x, y: y}, msg: fmt.Sprintf("region %d is out of range", id)}
+			}
+			result.cells[id] = append(result.cells[id], coord{x: x, y: y})
+		}
+	}
+
+	for id, cells := range result.cells {
+		if len(cells) != side {
+			at := coord{}
+			if len(cells) > 0 {
+				at = cells[0]
+			}
+			return nil, &regionError{at: at, msg: fmt.Sprintf("region %d has %d cells, expected %d", id, len(cells), side)}
+		}
+		if apart, ok := result.contiguous(id); !ok {
+			return nil, &regionError{at: apart, msg: fmt.Sprintf("region %d is split in two", id)}
+		}
+	}
+	return result, nil
+}
+
+// contiguous walks region id from its first cell, stepping between cells
+// that share an edge. Returns false, along with a cell the walk never got to,
+// if the region is in pieces.
+func (r *regionMap) contiguous(id int) (coord, bool) {
+	side := len(r.ids)
+	start := r.cells[id][0]
+	seen := map[coord]bool{start: true}
+	queue := []coord{start}
+	for len(queue) > 0 {
+		at := queue[0]
+		queue = queue[1:]
+		for _, next := range []coord{{at.x - 1, at.y}, {at.x + 1, at.y}, {at.x, at.y - 1}, {at.x, at.y + 1}} {
+			if next.x < 0 || next.x >= side || next.y < 0 || next.y >= side {
+				continue
+			}
+			if r.ids[next.x][next.y] == id && !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	for _, each := range r.cells[id] {
+		if !seen[each] {
+			return each, false
+		}
+	}
+	return coord{}, true
+}
+
+// NewJigsawBoard returns an empty puzzle where the boxes are replaced by
+// regions. regions[row][col] is the region of each cell, counting from 0 -
+// there must be as many regions as rows, and each region must be as many
+// cells as there are rows, all joined up edge to edge.
+func NewJigsawBoard(regions [][]int) (*Board, error) {
+	regionMap, err := newRegionMap(regions)
+	if err != nil {
+		return nil, err
+	}
+	return &Board{puzzle: shape{regions: regionMap}.crossed().empty()}, nil
+}
+
+// Jigsaw returns true if the puzzle has regions in place of boxes.
+func (b *Board) Jigsaw() bool {
+	return b.puzzle.regions != nil
+}
+
+// Region returns the box or region a cell sits in, counting from 0, or -1 for
+// a cell off the board. For a puzzle of several grids the boxes are counted
+// grid by grid.
+func (b *Board) Region(row, col int) int {
+	if row < 0 || col < 0 {
+		return -1
+	}
+	if b.puzzle.layout != nil {
+		// a cell where grids overlap is in a box of each - the first grid wins
+		for _, ref := range b.puzzle.layout.at[coord{x: row, y: col}] {
+			if ref.orient == boardSquare {
+				return ref.index
+			}
+		}
+		return -1
+	}
+	pos, err := getPos(coord{x: row, y: col}, boardSquare, b.puzzle.shape)
+	if err != nil {
+		return -1
+	}
+	return pos
+}
@@ -0,0 +1,88 @@
+package sudoku
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	jigsawPuzzle   = "4.....36...84.5.........5.........7...397.1......1....5.....7392...8........6...1"
+	jigsawRegions  = "AAAABBCCCAAABBBCCCAABBBBCCDEEEFFFFCDEEEFFFDDDGEEFFDDDDGEGGHHIIIGGGHHHIIIGGHHHHIII"
+	jigsawSolution = "412597368768435912936821547194258673623974185857316294581642739279183456345769821"
+)
+
+// regionIds turns rows of region letters into a region map
+func regionIds(rows ...string) [][]int {
+	ids := make([][]int, len(rows))
+	for x, row := range rows {
+		for _, each := range row {
+			ids[x] = append(ids[x], int(each-'A'))
+		}
+	}
+	return ids
+}
+
+func TestNewRegionMap(t *testing.T) {
+	var tests = []struct {
+		ids [][]int
+		ok  bool
+		at  coord
+	}{
+		{regionIds("AABBBB", "AAACCB", "DACCCB", "DDDCEE", "DFFFEE", "DFFFEE"), true, coord{}},
+		{regionIds("AB", "BA"), false, coord{x: 1, y: 1}},
+		{regionIds("AAB", "ABB", "CCC"), true, coord{}},
+		{regionIds("AAB", "ABB"), false, coord{x: 0}},
+		{regionIds("AAB", "ABB", "CCCC"), false, coord{x: 2}},
+		{regionIds("AAB", "ABB", "CCD"), false, coord{x: 2, y: 2}},
+		{regionIds("AAB", "ABB", "CCA"), false, coord{x: 0}},
+		{nil, false, coord{}},
+	}
+
+	for id, testRun := range tests {
+		result, err := newRegionMap(testRun.ids)
+		if testRun.ok {
+			assert.NoError(t, err, "test %d - good regions refused", id)
+			for region, cells := range result.cells {
+				assert.Len(t, cells, len(testRun.ids), "test %d - region %d is the wrong size", id, region)
+			}
+			continue
+		}
+		regionErr, ok := err.(*regionError)
+		if assert.True(t, ok, "test %d - expected a regionError, got %v", id, err) {
+			assert.Equal(t, testRun.at, regionErr.at, "test %d - wrong coord", id)
+		}
+	}
+}
+
+func TestSolveJigsaw(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b, err := Parse(strings.NewReader(jigsawPuzzle + "\n" + jigsawRegions + "\n"))
+	if !assert.NoError(t, err, "could not parse") {
+		return
+	}
+	assert.True(t, b.Jigsaw())
+	assert.Equal(t, 9, b.Side())
+	assert.Equal(t, 0, b.Region(0, 3))
+	assert.Equal(t, 1, b.Region(2, 2))
+	assert.Equal(t, -1, b.Region(9, 0))
+
+	result, err := NewFromBoard(b).Solve(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, Solved, result.Status, "puzzle should be solved")
+
+	var out bytes.Buffer
+	assert.NoError(t, Format(&out, result.Board(), LineStyle))
+	assert.Equal(t, jigsawSolution+"\n"+jigsawRegions+"\n", out.String(), "wrong solution")
+
+	// the same givens with the usual boxes have other solutions
+	boxed, err := Parse(strings.NewReader(jigsawPuzzle))
+	assert.NoError(t, err)
+	assert.False(t, IsUnique(boxed), "the regions should matter")
+}
@@ -0,0 +1,149 @@
+package sudoku
+
+// When the rules stall, the only way forward is to guess. Guessing is done
+// depth first - take the unsolved cell with the fewest candidates, and try
+// each candidate in turn on a fork of the board. A guess that runs into a
+// contradiction is backed out, and the next candidate is tried.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+var (
+	// ErrDepthLimit is returned when a solve needed guesses stacked deeper
+	// than the Solver's MaxDepth.
+	ErrDepthLimit = errors.New("guesses went deeper than the depth limit")
+	// ErrGuessLimit is returned when a solve needed more guesses than the
+	// Solver's MaxGuesses.
+	ErrGuessLimit = errors.New("ran out of guesses")
+
+	// errStopped is passed back up the search once found asks it to stop
+	errStopped = errors.New("search stopped")
+)
+
+// searcher holds the limits for a search, and how far the search has got
+type searcher struct {
+	level      Difficulty
+	maxDepth   int
+	maxGuesses int
+	maxChain   int
+	unique     bool
+	// forcingDepth and forcingTrials bound the forcing rules, which are off
+	// at a depth of 0
+	forcingDepth  int
+	forcingTrials int
+	guesses       int
+	solutions     int
+	// found is called with every solution, and returns false to stop the
+	// search there
+	found func(board) bool
+	// trace, if set, is called with every step of the search in order
+	trace func(Event)
+	// random, if set, shuffles the order guesses are tried in
+	random *rand.Rand
+}
+
+// settings returns the pipeline settings for a search at a given depth
+func (s *searcher) settings(depth int) settings {
+	opts := settings{level: s.level, chainLength: s.maxChain, unique: s.unique,
+		forcingDepth: s.forcingDepth, forcingTrials: s.forcingTrials}
+	if s.trace != nil {
+		opts.trace = func(u change, before, after cell) {
+			s.trace(deductionEvent(u, before, after, depth))
+		}
+	}
+	return opts
+}
+
+// guessEvent builds the event for a guess, or for backing a guess out
+func guessEvent(kind EventKind, at coord, value, depth int) Event {
+	return Event{Kind: kind, Depth: depth, Cell: position(at), Value: value}
+}
+
+// fewestCandidates returns the unsolved cell with the fewest possible values
+// returns false if every cell is solved
+func fewestCandidates(in board) (cell, bool) {
+	var best cell
+	found := false
+	for _, row := range in.clusters {
+		for _, each := range row {
+			if each.actual != 0 || !in.onBoard(each.location) {
+				continue
+			}
+			if !found || each.possible.Count() < best.possible.Count() {
+				best, found = each, true
+			}
+		}
+	}
+	return best, found
+}
+
+// search runs the rules over a board, and guesses when they stall - handing
+// every solution it comes across to found. It returns the board as the rules
+// left it. The error is errStopped if found stopped the search, one of the
+// limits if a guess ran into it, or a contradiction if there is no solution.
+func (s *searcher) search(ctx context.Context, in board, depth int) (board, error) {
+	out, err := propagate(ctx, in, s.settings(depth))
+	if err != nil {
+		return board{}, err
+	}
+	target, found := fewestCandidates(out)
+	if !found {
+		s.solutions++
+		if !s.found(out) {
+			return out, errStopped
+		}
+		return out, nil
+	}
+	if s.maxDepth > 0 && depth >= s.maxDepth {
+		return out, ErrDepthLimit
+	}
+
+	guesses := target.possible.Values()
+	if s.random != nil {
+		s.random.Shuffle(len(guesses), func(i, j int) {
+			guesses[i], guesses[j] = guesses[j], guesses[i]
+		})
+	}
+
+	limited := false
+	before := s.solutions
+	for _, value := range guesses {
+		if s.maxGuesses < 0 || (s.maxGuesses > 0 && s.guesses >= s.maxGuesses) {
+			return out, ErrGuessLimit
+		}
+		s.guesses++
+
+		if s.trace != nil {
+			s.trace(guessEvent(Guess, target.location, value, depth+1))
+		}
+		branch, _, err := changeBoard(out, cell{location: target.location, actual: value})
+		if err == nil {
+			_, err = s.search(ctx, branch, depth+1)
+		}
+		if s.trace != nil && err != nil && err != errStopped {
+			s.trace(guessEvent(Backtrack, target.location, value, depth+1))
+		}
+		switch {
+		case err == nil:
+			// every solution down this guess has been found
+		case errors.Is(err, ErrContradiction):
+			// back out of this guess, and try the next one
+		case errors.Is(err, ErrDepthLimit):
+			// this guess went too deep, but another one might not
+			limited = true
+		default:
+			return out, err
+		}
+	}
+	switch {
+	case limited:
+		return out, ErrDepthLimit
+	case s.solutions == before:
+		return board{}, fmt.Errorf("%w: every guess for %v failed", ErrContradiction, target.location)
+	}
+	return out, nil
+}
@@ -0,0 +1,86 @@
+package sudoku
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSolutions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var tests = []struct {
+		puzzle string
+		n      int
+		found  int
+	}{
+		// an empty 4x4 has 288 solutions
+		{"................", 0, 288},
+		{"................", 5, 5},
+		{easyPuzzle, 0, 1},
+		{easyPuzzle, 2, 1},
+		// two 1s in the first row
+		{"11..............", 0, 0},
+	}
+
+	for id, testRun := range tests {
+		b, err := Parse(strings.NewReader(testRun.puzzle))
+		assert.NoError(t, err)
+		solutions, err := NewFromBoard(b).Solutions(ctx, testRun.n)
+		assert.NoError(t, err, "test %d - search failed", id)
+		assert.Len(t, solutions, testRun.found, "test %d - wrong number of solutions", id)
+
+		seen := map[string]bool{}
+		for _, each := range solutions {
+			assert.Equal(t, Solved, each.Status, "test %d - unsolved solution", id)
+			var out strings.Builder
+			assert.NoError(t, Format(&out, each.Board(), LineStyle))
+			seen[out.String()] = true
+		}
+		assert.Len(t, seen, testRun.found, "test %d - repeated solutions", id)
+	}
+}
+
+func TestSolutionStream(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	out := make(chan Grid)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- New(2).SolutionStream(ctx, 3, out)
+	}()
+
+	count := 0
+	for range out {
+		count++
+	}
+	assert.NoError(t, <-errs)
+	assert.Equal(t, 3, count, "wrong number of solutions sent")
+}
+
+func TestSolutionsLimited(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	s := New(2)
+	s.MaxGuesses = 3
+	solutions, err := s.Solutions(ctx, 0)
+	assert.Equal(t, ErrGuessLimit, err)
+	assert.True(t, len(solutions) < 288, "the limit should cut the search short")
+}
+
+func TestIsUnique(t *testing.T) {
+	easy, err := Parse(strings.NewReader(easyPuzzle))
+	assert.NoError(t, err)
+	assert.True(t, IsUnique(easy), "easy puzzle has one solution")
+	assert.False(t, IsUnique(NewBoard(2)), "empty puzzle has many solutions")
+
+	broken, err := Parse(strings.NewReader("11.............."))
+	assert.NoError(t, err)
+	assert.False(t, IsUnique(broken), "broken puzzle has no solution")
+}
@@ -0,0 +1,315 @@
+package sudoku
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Status says how far a solve got.
+type Status int
+
+const (
+	// Stalled means the rules ran out of deductions before the puzzle was
+	// solved.
+	Stalled Status = iota
+	// Solved means every cell has a value.
+	Solved
+	// Contradiction means the puzzle breaks the one rule - it has no solution.
+	Contradiction
+)
+
+func (s Status) String() string {
+	switch s {
+	case Stalled:
+		return "stalled"
+	case Solved:
+		return "solved"
+	case Contradiction:
+		return "contradiction"
+	default:
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+}
+
+// Grid is the result of a solve. Values holds one slice per row, with 0 for
+// every cell that is still unknown.
+type Grid struct {
+	Status Status
+	Values [][]int
+
+	// the shape of the puzzle solved, if known
+	shape shape
+}
+
+// Board is a puzzle - the givens placed on an otherwise empty grid.
+type Board struct {
+	puzzle board
+}
+
+// NewBoard returns an empty puzzle made of size x size squares - NewBoard(3)
+// is the common 9x9 puzzle.
+func NewBoard(size int) *Board {
+	return &Board{puzzle: createBoard(size, size)}
+}
+
+// NewBoxBoard returns an empty puzzle made of boxes width cells across and
+// height cells down - NewBoxBoard(3, 2) is a 6x6 puzzle, and
+// NewBoxBoard(4, 3) a 12x12 one.
+func NewBoxBoard(width, height int) *Board {
+	return &Board{puzzle: createBoard(width, height)}
+}
+
+// Size returns the width of a box, the same size the Board was made with.
+func (b *Board) Size() int {
+	return b.puzzle.boxWidth
+}
+
+// Box returns the width and height of a box, or 0 and 0 for a jigsaw puzzle.
+func (b *Board) Box() (width, height int) {
+	return b.puzzle.boxWidth, b.puzzle.boxHeight
+}
+
+// Side returns the number of cells along one side of the puzzle, or of each
+// grid for a puzzle made with NewLayoutBoard - which is also the number of
+// values.
+func (b *Board) Side() int {
+	return b.puzzle.side()
+}
+
+// Get returns the value given at a cell, or 0 if there is none. Rows and
+// columns count from 0.
+func (b *Board) Get(row, col int) int {
+	if !b.puzzle.onBoard(coord{x: row, y: col}) {
+		return 0
+	}
+	return b.puzzle.clusters[row][col].actual
+}
+
+// Set places a given value on the puzzle. Rows and columns count from 0, and
+// values from 1.
+func (b *Board) Set(row, col, value int) error {
+	if !b.puzzle.onBoard(coord{x: row, y: col}) {
+		return fmt.Errorf("cell %d,%d is off the board", row, col)
+	}
+	if value < 1 || value > b.puzzle.side() {
+		return fmt.Errorf("value %d is out of range for the board", value)
+	}
+	newBoard, _, err := changeBoard(b.puzzle, cell{location: coord{x: row, y: col}, actual: value})
+	if err != nil {
+		return err
+	}
+	b.puzzle = newBoard
+	return nil
+}
+
+// Board returns the values of the grid as a Board, so a solution can be
+// written out with Format. A Grid that didn't come from a solve gets the box
+// shape Parse would pick for its side.
+func (g Grid) Board() *Board {
+	s := g.shape
+	if s.rows() != len(g.Values) || s.rows() == 0 {
+		s = shape{}
+		s.boxWidth, s.boxHeight, _ = boxForSide(len(g.Values))
+	}
+	return &Board{puzzle: fillBoard(s, g.Values)}
+}
+
+// fillBoard makes a board of the given shape with the given values, 0 for
+// unknown
+func fillBoard(s shape, values [][]int) board {
+	result := s.empty()
+	for x, row := range values {
+		for y, value := range row {
+			if value != 0 {
+				result.clusters[x][y].actual = value
+			}
+		}
+	}
+	return result
+}
+
+// Solver holds a puzzle and solves it. Use New or NewFromBoard to get one.
+type Solver struct {
+	// MaxDepth limits how many guesses can be stacked on top of each other
+	// once the rules stall, 0 for no limit.
+	MaxDepth int
+	// MaxGuesses limits how many guesses a solve can make in total, 0 for no
+	// limit. Below 0 the solve never guesses.
+	MaxGuesses int
+	// MaxChain limits how many links the chains the rules look for can
+	// have, 0 for the default.
+	MaxChain int
+	// AssumeUnique lets the rules that only hold for puzzles with one
+	// solution run - with more than one, the solve may miss some, or find
+	// none at all.
+	AssumeUnique bool
+	// ForcingDepth lets the forcing rules try values out once every other
+	// rule has stalled, before guessing - nesting trials inside trials up to
+	// ForcingDepth deep. 0 leaves them off.
+	ForcingDepth int
+	// ForcingTrials limits how many values the forcing rules can try out
+	// each time they run, 0 for 100. Trials nested inside a trial have a
+	// budget of their own.
+	ForcingTrials int
+	// Backend picks how the puzzle is solved, Propagation by default. The
+	// DancingLinks backend makes no use of the options above, or of Trace.
+	Backend Backend
+	// Trace, if set, is sent every step of a solve in the order the steps
+	// were applied. It is never closed.
+	Trace chan<- Event
+
+	puzzle Board
+}
+
+// New returns a Solver for an empty puzzle made of size x size squares -
+// New(3) is the common 9x9 puzzle.
+func New(size int) *Solver {
+	return &Solver{puzzle: *NewBoard(size)}
+}
+
+// NewFromBoard returns a Solver for a copy of a puzzle - later changes to b
+// do not change the Solver.
+func NewFromBoard(b *Board) *Solver {
+	return &Solver{puzzle: *b}
+}
+
+// Set places a given value on the puzzle. Rows and columns count from 0, and
+// values from 1.
+func (s *Solver) Set(row, col, value int) error {
+	return s.puzzle.Set(row, col, value)
+}
+
+// Solve runs the puzzle through the rules, guessing whenever they stop making
+// progress, until it is solved. The puzzle held by the Solver is not changed.
+// The error is non-nil if the puzzle has no solution (wrapping
+// ErrContradiction), if the guesses hit MaxDepth or MaxGuesses (the grid is
+// then Stalled, as far as the rules got without guessing), or if ctx is done
+// before the solve finishes.
+func (s *Solver) Solve(ctx context.Context) (Grid, error) {
+	if s.Backend == DancingLinks {
+		return s.solveLinks(ctx)
+	}
+	var solution board
+	search := s.searcher(ctx, func(found board) bool {
+		solution = found
+		return false
+	})
+	final, err := search.search(ctx, s.puzzle.puzzle, 0)
+	if err == errStopped {
+		return makeGrid(solution), nil
+	}
+	if final.clusters == nil {
+		final = s.puzzle.puzzle
+	}
+	result := makeGrid(final)
+	switch {
+	case errors.Is(err, ErrContradiction):
+		result.Status = Contradiction
+	case err != nil:
+		result.Status = Stalled
+	}
+	return result, err
+}
+
+// Solutions finds up to n solutions to the puzzle, or every solution if n is
+// 0. No solutions at all is not an error. The error is non-nil if the
+// guesses hit MaxDepth or MaxGuesses - there may be more solutions than the
+// ones returned - or if ctx is done before the search finishes.
+func (s *Solver) Solutions(ctx context.Context, n int) ([]Grid, error) {
+	var solutions []Grid
+	err := s.enumerate(ctx, n, func(found Grid) {
+		solutions = append(solutions, found)
+	})
+	return solutions, err
+}
+
+// SolutionStream sends up to n solutions to the puzzle on out as they are
+// found, or every solution if n is 0. It closes out on exit, and returns the
+// same errors as Solutions.
+func (s *Solver) SolutionStream(ctx context.Context, n int, out chan<- Grid) error {
+	defer close(out)
+	return s.enumerate(ctx, n, func(found Grid) {
+		select {
+		case out <- found:
+		case <-ctx.Done():
+		}
+	})
+}
+
+// Count counts the solutions to the puzzle, stopping once it has found n, or
+// counting every one if n is 0. The error is the same as for Solutions - the
+// count is then only of the solutions found so far.
+func (s *Solver) Count(ctx context.Context, n int) (int, error) {
+	if s.Backend == DancingLinks {
+		return s.links(ctx, n, nil)
+	}
+	count := 0
+	err := s.enumerate(ctx, n, func(Grid) {
+		count++
+	})
+	return count, err
+}
+
+// enumerate runs the search, handing each solution to send until n are found
+func (s *Solver) enumerate(ctx context.Context, n int, send func(Grid)) error {
+	if s.Backend == DancingLinks {
+		_, err := s.links(ctx, n, send)
+		return err
+	}
+	search := s.searcher(ctx, nil)
+	search.found = func(found board) bool {
+		send(makeGrid(found))
+		return n < 1 || search.solutions < n
+	}
+	_, err := search.search(ctx, s.puzzle.puzzle, 0)
+	if err == errStopped || errors.Is(err, ErrContradiction) {
+		return nil
+	}
+	return err
+}
+
+// searcher sets up a search with the limits and trace of the Solver
+func (s *Solver) searcher(ctx context.Context, found func(board) bool) *searcher {
+	result := &searcher{level: Expert, maxDepth: s.MaxDepth, maxGuesses: s.MaxGuesses,
+		maxChain: s.MaxChain, unique: s.AssumeUnique, forcingDepth: s.ForcingDepth,
+		forcingTrials: s.ForcingTrials, found: found}
+	if s.Trace != nil {
+		result.trace = func(step Event) {
+			select {
+			case s.Trace <- step:
+			case <-ctx.Done():
+			}
+		}
+	}
+	return result
+}
+
+// IsUnique returns true if a puzzle has exactly one solution. It stops
+// searching as soon as it finds a second one - counting with dancing links
+// when the puzzle is made of houses alone, and with the rules otherwise.
+func IsUnique(b *Board) bool {
+	s := NewFromBoard(b)
+	s.Backend = DancingLinks
+	count, err := s.Count(context.Background(), 2)
+	if errors.Is(err, ErrUnsupported) {
+		s.Backend = Propagation
+		count, err = s.Count(context.Background(), 2)
+	}
+	return err == nil && count == 1
+}
+
+// makeGrid copies the values out of a board
+func makeGrid(in board) Grid {
+	result := Grid{Status: Solved, Values: make([][]int, len(in.clusters)), shape: in.shape}
+	for x, row := range in.clusters {
+		result.Values[x] = make([]int, len(row))
+		for y, each := range row {
+			result.Values[x][y] = each.actual
+			if each.actual == 0 && in.onBoard(each.location) {
+				result.Status = Stalled
+			}
+		}
+	}
+	return result
+}
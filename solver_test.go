@@ -0,0 +1,220 @@
+package sudoku
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	easyPuzzle   = "530070000600195000098000060800060003400803001700020006060000280000419005000080079"
+	easySolution = "534678912672195348198342567859761423426853791713924856961537284287419635345286179"
+	hardPuzzle   = "100007090030020008009600500005300900010080002600004000300000010040000007007000300"
+
+	// boxes 3 wide and 2 tall
+	sixPuzzle   = "...........61...25.2.1....4....62.3."
+	sixSolution = "256314431256143625625143314562562431"
+	// boxes 4 wide and 3 tall
+	twelvePuzzle   = ".A..4...7.3......6.....B5.4.........2....5..A.8..83.2.16.C..6C..8..94..37.8..1..3.....A.32..5.6.9..2..5..1.7..C...6.BA.2B9.3...1C...A46.......5."
+	twelveSolution = "8A164B25793C379C168A254B524B9C37681A2B79C543A681483A27169CB56C518AB947237685A19B32C4C1A432785B6993B2645C81A715C87364BA92B92358A1C476A467B9C21358"
+)
+
+// loadLine fills a 9x9 solver from an 81 character line, 0 for unknown
+func loadLine(t *testing.T, line string) *Solver {
+	s := New(3)
+	for i, each := range line {
+		if each == '0' {
+			continue
+		}
+		if err := s.Set(i/9, i%9, int(each-'0')); err != nil {
+			t.Fatalf("could not load puzzle - %v", err)
+		}
+	}
+	return s
+}
+
+// lineValues turns an 81 character line into rows of values
+func lineValues(line string) [][]int {
+	values := make([][]int, 9)
+	for i, each := range line {
+		values[i/9] = append(values[i/9], int(each-'0'))
+	}
+	return values
+}
+
+func TestSolve(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := loadLine(t, easyPuzzle).Solve(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, Solved, result.Status, "puzzle should be solved")
+	assert.Equal(t, lineValues(easySolution), result.Values, "wrong solution")
+}
+
+func TestSolveBoxes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var tests = []struct {
+		puzzle, solution string
+	}{
+		{sixPuzzle, sixSolution},
+		{twelvePuzzle, twelveSolution},
+	}
+
+	for id, testRun := range tests {
+		b, err := Parse(strings.NewReader(testRun.puzzle))
+		if !assert.NoError(t, err, "test %d - could not parse", id) {
+			continue
+		}
+		result, err := NewFromBoard(b).Solve(ctx)
+		assert.NoError(t, err, "test %d - could not solve", id)
+		assert.Equal(t, Solved, result.Status, "test %d - puzzle should be solved", id)
+
+		var out bytes.Buffer
+		assert.NoError(t, Format(&out, result.Board(), LineStyle))
+		assert.Equal(t, testRun.solution+"\n", out.String(), "test %d - wrong solution", id)
+	}
+}
+
+// checkSolution fails the test if values isn't a solution that keeps the
+// givens of line
+func checkSolution(t *testing.T, line string, values [][]int) {
+	for i, each := range line {
+		if each != '0' && values[i/9][i%9] != int(each-'0') {
+			t.Errorf("given at %d,%d changed", i/9, i%9)
+		}
+	}
+	for i := 0; i < 9; i++ {
+		row, col, square := map[int]bool{}, map[int]bool{}, map[int]bool{}
+		for j := 0; j < 9; j++ {
+			row[values[i][j]] = true
+			col[values[j][i]] = true
+			square[values[(i/3)*3+j/3][(i%3)*3+j%3]] = true
+		}
+		if len(row) != 9 || len(col) != 9 || len(square) != 9 || row[0] {
+			t.Errorf("cluster %d breaks the one rule", i)
+		}
+	}
+}
+
+func TestSolveGuessing(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	s := loadLine(t, hardPuzzle)
+	s.MaxGuesses = -1
+	result, err := s.Solve(ctx)
+	assert.Equal(t, ErrGuessLimit, err)
+	assert.Equal(t, Stalled, result.Status, "the rules alone can't solve this")
+
+	s.MaxGuesses = 0
+	result, err = s.Solve(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, Solved, result.Status, "guessing should solve this")
+	checkSolution(t, hardPuzzle, result.Values)
+}
+
+func TestSolveLimits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := New(3)
+	s.MaxDepth = 1
+	_, err := s.Solve(ctx)
+	assert.Equal(t, ErrDepthLimit, err)
+
+	s = New(3)
+	s.MaxGuesses = 2
+	_, err = s.Solve(ctx)
+	assert.Equal(t, ErrGuessLimit, err)
+}
+
+func TestSolveStalled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := New(3)
+	s.MaxGuesses = -1
+	assert.NoError(t, s.Set(0, 0, 1))
+	result, err := s.Solve(ctx)
+	assert.Equal(t, ErrGuessLimit, err)
+	assert.Equal(t, Stalled, result.Status, "an almost empty board can't be solved")
+	assert.Equal(t, 1, result.Values[0][0], "given value lost")
+	assert.Equal(t, 0, result.Values[8][8], "value made up")
+}
+
+func TestSolveContradiction(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := New(3)
+	assert.NoError(t, s.Set(0, 0, 5))
+	assert.NoError(t, s.Set(0, 8, 5))
+	result, err := s.Solve(ctx)
+	assert.True(t, errors.Is(err, ErrContradiction), "expected a contradiction, got %v", err)
+	assert.Equal(t, Contradiction, result.Status)
+}
+
+func TestSolveCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := loadLine(t, easyPuzzle).Solve(ctx)
+	assert.Equal(t, context.Canceled, err)
+}
+
+// a deadline is met even while a worker is deep inside a rule - the rules on
+// an empty 25x25 board take far longer than the deadline
+func TestSolveDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := New(5).Solve(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Less(t, int64(time.Since(start)), int64(10*time.Second), "the solve should stop soon after the deadline")
+}
+
+func TestSet(t *testing.T) {
+	var tests = []struct {
+		row, col, value int
+		ok              bool
+	}{
+		{0, 0, 1, true},
+		{8, 8, 9, true},
+		{9, 0, 1, false},
+		{0, -1, 1, false},
+		{0, 0, 0, false},
+		{0, 0, 10, false},
+	}
+
+	for id, testRun := range tests {
+		err := New(3).Set(testRun.row, testRun.col, testRun.value)
+		assert.Equal(t, testRun.ok, err == nil, "test %d - wrong error %v", id, err)
+	}
+
+	s := New(3)
+	assert.NoError(t, s.Set(4, 4, 7))
+	assert.Error(t, s.Set(4, 4, 6), "a given can't be changed")
+}
+
+// empty boards, solved by the rules and guessing together
+func BenchmarkSolve(b *testing.B) {
+	for _, size := range []int{3, 4, 5} {
+		b.Run(fmt.Sprintf("side-%d", size*size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := New(size).Solve(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
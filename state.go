@@ -6,16 +6,56 @@ package sudoku
 // * A channel to distribute the current board state
 // * A channel to notify threads of updates
 // * A channel to update known & possible values
+// * A channel to count outstanding work, so the pipeline knows when it is idle
 
-import "math"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
 
 const (
 	boardRow    = 0
 	boardCol    = 1
 	boardSquare = 2
+	// boardExtra is every cluster the puzzle adds on top of the rows, columns
+	// and squares - diagonals, windows, anything listed in the shape's extras
+	boardExtra = 3
+	// boardCage is every killer cage - cells that add up to a sum, without
+	// holding every value
+	boardCage = 4
+	// boardPair is a cell along with every cell it has a pairwise rule with,
+	// such as a knight's move away in an anti-knight puzzle
+	boardPair = 5
+	// boardLine is every line drawn over the grid, such as a thermometer
+	boardLine = 6
+	// boardClue is every clue outside the grid - the cells it looks along,
+	// in order from the clue
+	boardClue = 7
+	// boardIntersection is every square along with a row or column crossing
+	// it, for the rules that need to see both
+	boardIntersection = 8
+	// boardFish is every cell of a grid, for the rules that look at a value
+	// over several rows or columns at once
+	boardFish = 9
+	// boardWhole is every cell of the board, once for each rule that looks at
+	// the whole board at once
+	boardWhole = 10
+	// boardForcing is every cell of the board, for the rules that try out
+	// values - the last resort before guessing
+	boardForcing = 11
 )
 
+// orientations is the number of kinds of cluster
+const orientations = 12
+
+// ErrContradiction is wrapped by every error caused by the puzzle breaking the
+// one rule - as opposed to the solve being cancelled.
+var ErrContradiction = errors.New("contradiction")
+
 // coord contains x and y elements for a given position
+// x is the row, y is the column
 type coord struct {
 	x int
 	y int
@@ -27,14 +67,78 @@ type coord struct {
 type cell struct {
 	location coord
 	actual   int
-	possible []int
-	excluded []int
+	possible CandidateSet
+	excluded CandidateSet
 }
 
 type cluster []cell
 
+// settings changes how the pipeline runs
+type settings struct {
+	// level is the hardest rules the workers may use
+	level Difficulty
+	// forcingDepth is how deep the forcing rules can nest their trials, 0
+	// to not run them, and forcingTrials how many values they can try out
+	// over a board
+	forcingDepth  int
+	forcingTrials int
+	// chainLength is the most links a chain can have, 0 for the default
+	chainLength int
+	// unique is set if the puzzle is assumed to have one solution, letting
+	// the uniqueness rules run
+	unique bool
+	// trace is called with every change as it is applied to the board, along
+	// with the cell before and after - in order, from a single goroutine
+	trace func(u change, before, after cell)
+	// done is closed once the pipeline is shutting down - rules that can run
+	// long give up when it is
+	done <-chan struct{}
+}
+
+// shape holds everything about a board but the cells - where its clusters
+// are. Every board with the same shape shares it, so it is never changed.
+type shape struct {
+	// each box is boxWidth cells across and boxHeight cells down, and holds
+	// every value once, so the board is boxWidth*boxHeight cells on a side
+	boxWidth  int
+	boxHeight int
+	// regions, if set, takes the place of the boxes
+	regions *regionMap
+	// extras, if set, lists the boardExtra clusters
+	extras *extraClusters
+	// cages, if set, lists the boardCage clusters
+	cages *cageList
+	// pairs, if set, lists the boardPair clusters
+	pairs *pairList
+	// lines, if set, lists the boardLine clusters
+	lines *lineList
+	// clues, if set, lists the boardClue clusters
+	clues *clueList
+	// intersections, if set, lists the boardIntersection clusters - every
+	// board made by a constructor has them
+	intersections *intersectionList
+	// fish, if set, lists the boardFish clusters - every board made by a
+	// constructor has them
+	fish *fishList
+	// whole, if set, lists every cell for the boardWhole clusters - every
+	// board made by a constructor has it
+	whole *wholeBoard
+	// layout, if set, spreads several grids over a larger canvas - the boxes
+	// are the boxes of every grid
+	layout *gridLayout
+}
+
+// clusterRef names a single cluster - the kind of cluster, and which one of
+// that kind it is
+type clusterRef struct {
+	orient int
+	index  int
+}
+
+// board holds every cell - clusters[x][y] is the cell at coord{x, y}, so each
+// entry in clusters is one row.
 type board struct {
-	size     int
+	shape
 	clusters []cluster
 }
 
@@ -46,80 +150,149 @@ type Cluster interface {
 }
 */
 
-func (c Cluster) Len() int {
+func (c cluster) Len() int {
 	return len(c)
 }
-func (c Cluster) Swap(i, j int) {
+func (c cluster) Swap(i, j int) {
 	c[i], c[j] = c[j], c[i]
 }
-func (c Cluster) Less(i, j int) bool {
-	if c[i].x < c[j].x {
+func (c cluster) Less(i, j int) bool {
+	if c[i].location.x < c[j].location.x {
 		return true
 	}
-	if c[i].x == c[j].x && c[i].y < c[j].y {
+	if c[i].location.x == c[j].location.x && c[i].location.y < c[j].location.y {
 		return true
 	}
 	return false
 }
 
-func createBoard(size int) board {
-	var newBoard board
-	newBoard.size = size
-	for i := 0; i < size*size; i++ {
-		for j := 0; j < size*size; j++ {
-			board[coord{x: i, y: j}].location = coord{x: i, y: j}
-			for k := 1; k <= size*size; k++ {
-				board[coord{x: i, y: j}].possible = append(board[coord{x: i, y: j}].possible, k)
-			}
+// createBoard returns an empty board made of boxes width cells across and
+// height cells down
+func createBoard(width, height int) board {
+	return shape{boxWidth: width, boxHeight: height}.crossed().empty()
+}
+
+// crossed returns the shape with its intersections, fish and whole board
+// found
+func (s shape) crossed() shape {
+	s.intersections = newIntersectionList(s)
+	s.fish = newFishList(s)
+	s.whole = newWholeBoard(s)
+	return s
+}
+
+// empty returns a board of the shape with every cell unknown
+func (s shape) empty() board {
+	newBoard := board{shape: s, clusters: make([]cluster, s.rows())}
+	for x := range newBoard.clusters {
+		newBoard.clusters[x] = make(cluster, s.cols())
+		for y := range newBoard.clusters[x] {
+			newBoard.clusters[x][y].location = coord{x: x, y: y}
+			newBoard.clusters[x][y].possible = valueSet(s.side())
 		}
 	}
 	return newBoard
 }
 
-func getPos(position coord, orientation, size int) (int, error) {
-	if position.x >= size*size {
+// side returns the number of cells along one side of a grid - which is also
+// the number of values, and the number of cells in every row, column and box
+func (s shape) side() int {
+	if s.regions != nil {
+		return len(s.regions.ids)
+	}
+	return s.boxWidth * s.boxHeight
+}
+
+// rows returns the number of rows of cells on the board
+func (s shape) rows() int {
+	if s.layout != nil {
+		return s.layout.rows
+	}
+	return s.side()
+}
+
+// cols returns the number of columns of cells on the board
+func (s shape) cols() int {
+	if s.layout != nil {
+		return s.layout.cols
+	}
+	return s.side()
+}
+
+// onBoard returns true if a coord is a cell of the puzzle - on the board, and
+// not in a gap between the grids of a layout
+func (s shape) onBoard(at coord) bool {
+	if at.x < 0 || at.x >= s.rows() || at.y < 0 || at.y >= s.cols() {
+		return false
+	}
+	return s.layout == nil || len(s.layout.at[at]) > 0
+}
+
+// solved returns true if every cell on the board has a value
+func (b board) solved() bool {
+	for _, row := range b.clusters {
+		for _, each := range row {
+			if each.actual == 0 && b.onBoard(each.location) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// getPos returns the index of the cluster position sits in for orientation.
+// Boxes are counted across then down - there are boxHeight boxes to a band.
+// Regions, where the board has them, are the squares instead.
+func getPos(position coord, orientation int, in shape) (int, error) {
+	if position.x >= in.side() {
 		return -1, errors.New("x position is larger than the board")
 	}
-	if position.y >= size*size {
+	if position.y >= in.side() {
 		return -1, errors.New("y position is larger than the board")
 	}
 	switch orientation {
 	case boardRow:
-		return position.x
+		return position.x, nil
 	case boardCol:
-		return position.y
+		return position.y, nil
 	case boardSquare:
-		return ((position.y / size) * size) + (position.x / size)
+		if in.regions != nil {
+			return in.regions.ids[position.x][position.y], nil
+		}
+		return ((position.x / in.boxHeight) * in.boxHeight) + (position.y / in.boxWidth), nil
 	default:
 		return -1, errors.New("bad position")
 	}
 }
 
 func clusterPicker(in board, orient int, position coord) (cluster, error) {
-	if position.x >= len(in) {
+	if position.x >= in.side() {
 		return cluster{}, errors.New("x coord out of range")
-	} else if position.y >= len(in) {
+	} else if position.y >= in.side() {
 		return cluster{}, errors.New("y coord out of range")
 	}
 	switch orient {
 	case boardRow:
-		return in[position.y], nil
+		return in.clusters[position.x], nil
 	case boardCol:
 		var result cluster
-		for _, each := range in {
-			result = append(result, each[position.x])
+		for _, each := range in.clusters {
+			result = append(result, each[position.y])
 		}
-		return outCluster, nil
+		return result, nil
 	case boardSquare:
 		var result cluster
-		boardSize := int(math.Sqrt(len(board)))
-		startX := (position.x / boardSize) * boardSize
-		endX := ((position.x / boardSize) + 1) * boardSize
-		startY := (position.y / boardSize) * boardSize
-		endY := ((position.y / boardSize) + 1) * boardSize
-		for x := startX; x < endX; x++ {
-			for y := startY; y < endY; y++ {
-				result = append(result, in[x][y])
+		if in.regions != nil {
+			for _, each := range in.regions.cells[in.regions.ids[position.x][position.y]] {
+				result = append(result, in.clusters[each.x][each.y])
+			}
+			return result, nil
+		}
+		startX := (position.x / in.boxHeight) * in.boxHeight
+		startY := (position.y / in.boxWidth) * in.boxWidth
+		for x := startX; x < startX+in.boxHeight; x++ {
+			for y := startY; y < startY+in.boxWidth; y++ {
+				result = append(result, in.clusters[x][y])
 			}
 		}
 		return result, nil
@@ -128,238 +301,752 @@ func clusterPicker(in board, orient int, position coord) (cluster, error) {
 	}
 }
 
-func clusterFilter(update <-chan coord, in <-chan board, out [][]chan<- cluster, status [][]<-chan struct{}) {
+// clusterCount returns the number of clusters of an orientation
+func (s shape) clusterCount(orient int) int {
+	switch orient {
+	case boardExtra:
+		if s.extras == nil {
+			return 0
+		}
+		return len(s.extras.cells)
+	case boardCage:
+		if s.cages == nil {
+			return 0
+		}
+		return len(s.cages.cages)
+	case boardPair:
+		if s.pairs == nil {
+			return 0
+		}
+		return len(s.pairs.cells)
+	case boardLine:
+		if s.lines == nil {
+			return 0
+		}
+		return len(s.lines.lines)
+	case boardClue:
+		if s.clues == nil {
+			return 0
+		}
+		return len(s.clues.clues)
+	case boardIntersection:
+		if s.intersections == nil {
+			return 0
+		}
+		return len(s.intersections.crossings)
+	case boardFish:
+		if s.fish == nil {
+			return 0
+		}
+		return len(s.fish.fish)
+	case boardWhole:
+		if s.whole == nil {
+			return 0
+		}
+		return s.whole.rules
+	case boardForcing:
+		if s.whole == nil {
+			return 0
+		}
+		return 1
+	default:
+		if s.layout != nil {
+			return len(s.layout.houses[orient])
+		}
+		return s.side()
+	}
+}
 
-	var toWork cluster
-	defer closeArrArrChan(out)
-	// don't actually do anything until you have a board state to work with
-	<-in
+// clustersAt returns every cluster a coord sits in
+func (s shape) clustersAt(position coord) ([]clusterRef, error) {
+	result := make([]clusterRef, 0, orientations)
+	if s.layout != nil {
+		if !s.onBoard(position) {
+			return nil, errors.New("coord is not on the board")
+		}
+		result = append(result, s.layout.at[position]...)
+	} else {
+		for orient := boardRow; orient <= boardSquare; orient++ {
+			pos, err := getPos(position, orient, s)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, clusterRef{orient: orient, index: pos})
+		}
+	}
+	if s.extras != nil {
+		for _, index := range s.extras.at[position] {
+			result = append(result, clusterRef{orient: boardExtra, index: index})
+		}
+	}
+	if s.cages != nil {
+		for _, index := range s.cages.at[position] {
+			result = append(result, clusterRef{orient: boardCage, index: index})
+		}
+	}
+	if s.pairs != nil {
+		for _, index := range s.pairs.at(position) {
+			result = append(result, clusterRef{orient: boardPair, index: index})
+		}
+	}
+	if s.lines != nil {
+		for _, index := range s.lines.at[position] {
+			result = append(result, clusterRef{orient: boardLine, index: index})
+		}
+	}
+	if s.clues != nil {
+		for _, index := range s.clues.at[position] {
+			result = append(result, clusterRef{orient: boardClue, index: index})
+		}
+	}
+	if s.intersections != nil {
+		for _, index := range s.intersections.at[position] {
+			result = append(result, clusterRef{orient: boardIntersection, index: index})
+		}
+	}
+	if s.fish != nil {
+		for _, index := range s.fish.at[position] {
+			result = append(result, clusterRef{orient: boardFish, index: index})
+		}
+	}
+	if s.whole != nil {
+		for index := 0; index < s.whole.rules; index++ {
+			result = append(result, clusterRef{orient: boardWhole, index: index})
+		}
+		result = append(result, clusterRef{orient: boardForcing, index: 0})
+	}
+	return result, nil
+}
+
+// pickCluster returns a cluster as it is on a board
+func pickCluster(in board, ref clusterRef) (cluster, error) {
+	var cells []coord
+	if ref.orient < boardRow || ref.orient >= orientations || ref.index < 0 || ref.index >= in.clusterCount(ref.orient) {
+		return cluster{}, errors.New("no such cluster")
+	}
+	switch {
+	case ref.orient == boardExtra:
+		cells = in.extras.cells[ref.index]
+	case ref.orient == boardCage:
+		cells = in.cages.cages[ref.index].cells
+	case ref.orient == boardPair:
+		cells = in.pairs.cluster(ref.index)
+	case ref.orient == boardLine:
+		cells = in.lines.lines[ref.index].cells
+	case ref.orient == boardClue:
+		cells = in.clues.clues[ref.index].cells
+	case ref.orient == boardIntersection:
+		cells = in.intersections.crossings[ref.index].cells
+	case ref.orient == boardFish:
+		cells = in.fish.fish[ref.index].cells
+	case ref.orient == boardWhole, ref.orient == boardForcing:
+		cells = in.whole.cells
+	case in.layout != nil:
+		cells = in.layout.houses[ref.orient][ref.index]
+	default:
+		return clusterPicker(in, ref.orient, clusterStart(ref.orient, ref.index, in.shape))
+	}
+	result := make(cluster, 0, len(cells))
+	for _, each := range cells {
+		result = append(result, in.clusters[each.x][each.y])
+	}
+	return result, nil
+}
+
+// clusterStart returns a coord that sits in the cluster at pos for orient -
+// the reverse of getPos.
+func clusterStart(orient, pos int, in shape) coord {
+	switch orient {
+	case boardRow:
+		return coord{x: pos}
+	case boardCol:
+		return coord{y: pos}
+	default:
+		if in.regions != nil {
+			return in.regions.cells[pos][0]
+		}
+		return coord{x: (pos / in.boxHeight) * in.boxHeight, y: (pos % in.boxHeight) * in.boxWidth}
+	}
+}
+
+// report adds delta to the count of outstanding work kept by idleCheck
+// returns false if the pipeline is shutting down
+func report(status chan<- int, delta int, done <-chan struct{}) bool {
+	select {
+	case status <- delta:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// stopped returns true once done is closed
+func stopped(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// takes every changed coord, and sends every cluster that coord sits in - as
+// it is on the latest board - to the worker for that cluster, but for the
+// late ones and those left without a worker
+// exits when update is closed, when a coord can't be found on the board, or
+// when done is closed
+func clusterFilter(update <-chan coord, in <-chan board, out [][]chan<- cluster, status chan<- int, problems chan<- error, done <-chan struct{}) {
 	for {
 		select {
 		case changed, more := <-update:
 			if !more {
 				return
 			}
-			for i = boardRow; i <= boardSquare; i++ {
-				curBoard := <-in
-				position := getPos(changed, i, curBoard.size)
-				if _, open := <-status[i][position]; !open {
-					// skip this update if the cluster is already solved
+			var curBoard board
+			select {
+			case curBoard = <-in:
+			case <-done:
+				return
+			}
+			refs, err := curBoard.clustersAt(changed)
+			if err != nil {
+				select {
+				case problems <- err:
+				case <-done:
+				}
+				return
+			}
+			for _, ref := range refs {
+				if lateOrient(ref.orient) || out[ref.orient] == nil {
+					// these wait until the pipeline goes idle, or have no
+					// workers at all
 					continue
 				}
-				curCluster, err := clusterPicker(curBoard, i, changed)
+				curCluster, err := pickCluster(curBoard, ref)
 				if err != nil {
-					panic(err) // #TODO# replace this panic
+					select {
+					case problems <- err:
+					case <-done:
+					}
+					return
+				}
+				if !report(status, 1, done) {
+					return
 				}
-				out[i][position] <- curCluster
+				select {
+				case out[ref.orient][ref.index] <- curCluster:
+				case <-done:
+					return
+				}
+			}
+			// the coord itself is handled now
+			if !report(status, -1, done) {
+				return
 			}
+		case <-done:
+			return
 		}
 	}
 }
 
-// takes an input and sends it out as it can
-// exits when in is closed
+// takes an input and sends it out as it can - only ever holding the newest
 // closes out on exit
-func clusterSticky(in <-chan cluster, out chan<- cluster) {
-	var more bool
+// exits when in is closed or done is closed
+func clusterSticky(in <-chan cluster, out chan<- cluster, status chan<- int, done <-chan struct{}) {
+	var locCluster cluster
+	var holding bool
 	defer close(out)
 
-	// preload the locCluster - don't do anything until you have that
-	locCluster := <-in
 	for {
+		// only offer the cluster downstream if there is one to offer
+		var send chan<- cluster
+		if holding {
+			send = out
+		}
 		select {
-		case locCluster, more = <-in:
+		case newCluster, more := <-in:
 			// if you get an update from upstream, do that first
 			if !more {
 				return
 			}
-		// pass the update downstream then block till you get another update from upstream
-		case out <- locCluster:
-			locCluster = <-in
+			if holding {
+				// the older cluster never went out, so that work is dropped
+				if !report(status, -1, done) {
+					return
+				}
+			}
+			locCluster, holding = newCluster, true
+		case send <- locCluster:
+			holding = false
+		case <-done:
+			return
 		}
 	}
 }
 
 // like a buffered channel, but no limit to the buffer size
 // closes out on exit
-// exits when in is closed
-func updateBuffer(in <-chan cell, out chan<- cell) {
-	var updates []interface{}
-	var singleUpdate interface{}
-	var open bool
+// exits when in is closed or done is closed
+func updateBuffer(in <-chan change, out chan<- change, done <-chan struct{}) {
+	var updates []change
 	defer close(out)
 
 	for {
 		if len(updates) < 1 {
 			// if you currently don't have anything to pass, WAIT FOR SOMETHING
-			singleUpdate, open = <-in
-			if !open {
+			select {
+			case singleUpdate, open := <-in:
+				if !open {
+					return
+				}
+				updates = append(updates, singleUpdate)
+			case <-done:
 				return
 			}
-			updates = append(updates, singleUpdate)
 			continue
 		}
 		select {
-		case singleUpdate, open = <-in:
+		case singleUpdate, open := <-in:
 			if !open {
 				return
 			}
 			updates = append(updates, singleUpdate)
 		case out <- updates[0]:
 			updates = updates[1:]
+		case <-done:
+			return
 		}
 	}
 }
 
 // boardCache serves a given (or newer) update out as many times as requested
 // closes `out` on exit
-// exits when in `is` closed
-func boardCache(in chan board, out chan<- board) {
-	currentBoard := <-in
-	var done bool
+// exits when `in` is closed or done is closed
+func boardCache(in <-chan board, out chan<- board, done <-chan struct{}) {
+	var currentBoard board
+	var more bool
 	defer close(out)
 
+	// don't serve anything until there is a board to serve
+	select {
+	case currentBoard, more = <-in:
+		if !more {
+			return
+		}
+	case <-done:
+		return
+	}
+
 	for {
 		select {
-		case currentBoard, done = <-in:
-			if done {
+		case currentBoard, more = <-in:
+			if !more {
 				return
 			}
-		case in <- currentBoard:
 		case out <- currentBoard:
+		case <-done:
+			return
 		}
 	}
 }
 
-// looks for a send from all status channels
-// if recieved or closed on all status chan, send on `idle`
-// if all status is closed, close idle and exits
-func idleCheck(status [][]<-chan interface{}, idle chan<- interface{}) {
-	var more, isIdle, isSolved bool
-	defer close(idle)
+// keeps a count of the outstanding work in the pipeline - everything that
+// hands work on adds to the count before it subtracts its own
+// sends on `idle` every time the count falls to zero
+// exits when done is closed
+func idleCheck(status <-chan int, idle chan<- struct{}, done <-chan struct{}) {
+	var pending int
 
 	for {
-	start:
-		isSolved, isIdle = true, true
-		for _, middle := range status {
-			for _, inner := range status {
-				select {
-				case _, more = <-inner:
-					if more {
-						isSolved = false
-					}
-				default:
-					isSolved = false
-					isIdle = false
-					continue start
-				}
+		select {
+		case delta := <-status:
+			pending += delta
+			if pending > 0 {
+				continue
 			}
-		}
-		if isSolved {
+			select {
+			case idle <- struct{}{}:
+			case <-done:
+				return
+			}
+		case <-done:
 			return
-		} else if isIdle {
-			idle <- nil
 		}
 	}
 }
 
-// takes a given cluster, and runs it through all of the moves
-// exits when one of the conditions is met, or when the update channel is closed
-// closes the status channel on exit
-func clusterWorker(in <-chan cluster, status chan<- struct{}, updates chan<- cell, problems chan<- error) {
-	defer close(status)
+// moves runs the rules for one cluster - returning the changes they make, or
+// an error if the cluster already breaks the puzzle
+type moves func(cluster) ([]change, error)
 
-	var more bool
-	var newCluster cluster
-	var index indexedCluster
-	var changes, newChanges []cell
+// clusterMoves returns the moves for a cluster that holds every value once
+func clusterMoves(opts settings) moves {
+	return func(newCluster cluster) ([]change, error) {
+		if err := clusterValid(newCluster); err != nil {
+			return nil, err
+		}
+
+		var changes []change
+		if !clusterSolved(newCluster) {
+			changes = append(changes, solvedNoPossible(newCluster)...)
+			changes = append(changes, eliminateKnowns(newCluster)...)
+			changes = append(changes, singleValueSolver(newCluster)...)
+
+			if opts.level >= Medium {
+				index := indexCluster(newCluster)
+				unsolved := 0
+				for _, each := range newCluster {
+					if each.actual == 0 {
+						unsolved++
+					}
+				}
+				limit := subsetLimit(opts.level, unsolved)
 
+				changes = append(changes, singleCellSolver(index, newCluster)...)
+				changes = append(changes, cellLimiter(opts.done, limit, newCluster)...)
+				changes = append(changes, valueLimiter(opts.done, limit, index, newCluster)...)
+			}
+		} else {
+			// a solved cluster can still be missing exclusions
+			changes = append(changes, solvedNoPossible(newCluster)...)
+		}
+		return changes, nil
+	}
+}
+
+// takes a given cluster, and runs it through all of the moves
+// every change is tagged with the orientation and position of the cluster
+// exits when the in channel is closed or done is closed
+func clusterWorker(orient, pos int, rules moves, in <-chan cluster, status chan<- int, updates chan<- change, problems chan<- error, done <-chan struct{}) {
 	for {
 		select {
-		case newCluster, more = <-in:
+		case newCluster, more := <-in:
 			if !more {
 				// if the channel is closed, exit
 				return
 			}
-			if clusterSolved(cluster) {
-				// if the cell is solved, exit
+			changes, err := rules(newCluster)
+			if err != nil {
+				select {
+				case problems <- err:
+				case <-done:
+				}
 				return
 			}
 
-			newChanges = solvedNoPossible(cluster.cells)
-			changes = append(changes, newChanges)
+			// feed all those changes into the update queue
+			for _, each := range changes {
+				each.orient, each.index = orient, pos
+				if !report(status, 1, done) {
+					return
+				}
+				select {
+				case updates <- each:
+				case <-done:
+					return
+				}
+			}
+			if !report(status, -1, done) {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
 
-			newChanges = eliminateKnowns(cluster.cells)
-			changes = append(changes, newChanges)
+// processes updates - applies each to the board, then sends out the new board
+// and the coord that changed
+// exits when updates is closed, when an update breaks the board, or when done
+// is closed
+func updateProcessor(curBoard board, opts settings, out chan<- board, updates <-chan change, posChange chan<- coord, status chan<- int, problems chan<- error, done <-chan struct{}) {
+	// everyone downstream starts from the board we were given
+	select {
+	case out <- curBoard:
+	case <-done:
+		return
+	}
 
-			newChanges = singleValueSolver(cluster.cells)
-			changes = append(changes, newChanges)
+	for {
+		select {
+		case cellChange, more := <-updates:
+			if !more {
+				return
+			}
+			newBoard, changed, err := changeBoard(curBoard, cellChange.cell)
+			if err != nil {
+				select {
+				case problems <- err:
+				case <-done:
+				}
+				return
+			}
+			if changed {
+				if opts.trace != nil {
+					at := cellChange.location
+					opts.trace(cellChange, curBoard.clusters[at.x][at.y], newBoard.clusters[at.x][at.y])
+				}
+				curBoard = newBoard
+				select {
+				case out <- curBoard:
+				case <-done:
+					return
+				}
+				if !report(status, 1, done) {
+					return
+				}
+				select {
+				case posChange <- cellChange.location:
+				case <-done:
+					return
+				}
+			}
+			if !report(status, -1, done) {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
 
-			newChanges = cellLimiter(cluster.cells)
-			changes = append(changes, newChanges)
+// propagate starts the whole pipeline on a board, and runs it until there is
+// nothing left for it to do. It returns the board as it stood when the
+// pipeline went idle, or the first problem the pipeline ran into. Every
+// goroutine started here has exited by the time it returns.
+func propagate(ctx context.Context, start board, opts settings) (board, error) {
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(ctx)
+	defer wg.Wait()
+	defer cancel()
+	done := ctx.Done()
+	opts.done = done
 
-			index = indexCluster(cluster.cells)
+	spawn := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
 
-			newChanges = singleCellSolver(index, cluster.cells)
-			changes = append(changes, newChanges)
+	status := make(chan int)
+	idle := make(chan struct{})
+	problems := make(chan error)
+	updates := make(chan change)
+	buffered := make(chan change)
+	boards := make(chan board)
+	cached := make(chan board)
+	changed := make(chan coord)
 
-			newChanges = valueLimiter(index, cluster.cells)
-			changes = append(changes, newChanges)
+	spawn(func() { idleCheck(status, idle, done) })
+	spawn(func() { boardCache(boards, cached, done) })
+	spawn(func() { updateBuffer(updates, buffered, done) })
+	spawn(func() { updateProcessor(start, opts, boards, buffered, changed, status, problems, done) })
 
-			// feed all those changes into the update queue
-			for len(changes) > 1 {
-				updates <- changes[0]
-				changes = changes[1:]
+	stickies := make([][]chan cluster, orientations)
+	filterOut := make([][]chan<- cluster, orientations)
+	for i := range stickies {
+		if opts.level < orientLevel(i) {
+			// none of their rules would run, so they get no workers
+			continue
+		}
+		stickies[i] = make([]chan cluster, start.clusterCount(i))
+		filterOut[i] = make([]chan<- cluster, start.clusterCount(i))
+		for j := range stickies[i] {
+			orient, pos := i, j
+			sticky, work := make(chan cluster), make(chan cluster)
+			stickies[i][j], filterOut[i][j] = sticky, sticky
+			spawn(func() { clusterSticky(sticky, work, status, done) })
+			rules := clusterMoves(opts)
+			switch orient {
+			case boardCage:
+				rules = start.cages.moves(pos, start.side(), opts)
+			case boardPair:
+				rules = start.pairs.moves(pos, opts)
+			case boardLine:
+				rules = start.lines.moves(pos, start.side(), opts)
+			case boardClue:
+				rules = start.clues.moves(pos, start.side(), opts)
+			case boardIntersection:
+				rules = start.intersections.moves(pos, start.side(), opts)
+			case boardFish:
+				rules = start.fish.moves(pos, start.side(), opts)
+			case boardWhole:
+				rules = start.whole.moves(pos, start, opts)
+			case boardForcing:
+				rules = start.whole.forcing(ctx, start, opts)
 			}
-		case status <- nil:
-			// report idle only if there is nothing to do - order matters
+			spawn(func() { clusterWorker(orient, pos, rules, work, status, updates, problems, done) })
 		}
 	}
-}
+	spawn(func() { clusterFilter(changed, cached, filterOut, status, problems, done) })
 
-// processes updates
-// priority is problems, updates, then status checks
-func updateProcessor(curBoard chan board, status <-chan struct{}, updates <-chan cell, posChange chan<- coord, problems <-chan error) {
-	defer close(curBoard)
-	defer close(posChange)
+	// handOut hands every cluster of the kinds picked to its worker as it is
+	// on a board - all of that work is counted up front, or the first worker
+	// to finish would find the pipeline idle before the rest had been handed
+	// out
+	handOut := func(on board, pick func(orient int) bool) error {
+		count := 0
+		for i := range stickies {
+			if pick(i) {
+				count += len(stickies[i])
+			}
+		}
+		if !report(status, count, done) {
+			return ctx.Err()
+		}
+		for i := range stickies {
+			if !pick(i) {
+				continue
+			}
+			for j := range stickies[i] {
+				next, err := pickCluster(on, clusterRef{orient: i, index: j})
+				if err != nil {
+					return err
+				}
+				select {
+				case stickies[i][j] <- next:
+				case <-done:
+					return ctx.Err()
+				}
+			}
+		}
+		return nil
+	}
 
-	var newBoard board
-	var err error
+	// kick things off with every cluster but the late ones
+	early := func(orient int) bool { return !lateOrient(orient) }
+	late := func(orient int) bool { return lateOrient(orient) && !lastOrient(orient) }
+	if err := handOut(start, early); err != nil {
+		return board{}, err
+	}
 
+	// each time the pipeline goes idle, the late clusters get a look at the
+	// board - until they have looked at it without changing anything. Then
+	// the last resort gets a look, if the settings allow it.
+	var looked, forced board
 	for {
 		select {
-		case err = <-problems:
-			// any errors get top priority
-			// do error handling
-		case cellChange = <-updates:
-			// any udpates are handled before idle checks
-			newBoard, err = changeBoard(<-curBoard, cellChange)
+		case <-idle:
+			var current board
+			select {
+			case current = <-cached:
+			case <-done:
+				return board{}, ctx.Err()
+			}
+			var err error
+			switch {
+			case looked.clusters == nil || !sameBoard(current, looked):
+				looked = current
+				err = handOut(current, late)
+			case opts.forcingDepth > 0 && (forced.clusters == nil || !sameBoard(current, forced)):
+				forced = current
+				err = handOut(current, lastOrient)
+			default:
+				return current, nil
+			}
 			if err != nil {
-				// do error handling
+				return board{}, err
 			}
-		case _, solved = <-status:
-			// status check if neither of the above happens - or solved check
+		case err := <-problems:
+			return board{}, err
+		case <-done:
+			return board{}, ctx.Err()
 		}
 	}
 }
 
-func changeBoard(in board, u cell) (board, error) {
+// orientLevel returns the easiest level any of the rules for a kind of
+// cluster run at
+func orientLevel(orient int) Difficulty {
+	switch orient {
+	case boardIntersection:
+		return ruleLevel(RulePointing, 1)
+	case boardFish:
+		return ruleLevel(RuleXWing, 2)
+	case boardWhole:
+		// the wings, chains, uniqueness and almost locked sets all start at
+		// the same level
+		return ruleLevel(RuleXYWing, 3)
+	case boardForcing:
+		return ruleLevel(RuleContradiction, 1)
+	default:
+		return Easy
+	}
+}
+
+// lateOrient returns true for the kinds of cluster that only get a look at
+// the board once the pipeline has gone idle - the ones that look at a whole
+// grid, and would be slow to run on every change
+func lateOrient(orient int) bool {
+	return orient == boardFish || orient == boardWhole || orient == boardForcing
+}
+
+// lastOrient returns true for the kinds of cluster that only get a look at
+// the board once the late ones have stalled too
+func lastOrient(orient int) bool {
+	return orient == boardForcing
+}
+
+// sameBoard returns true if two boards are the same board - changeBoard
+// copies the rows of every board it changes, so boards that share their rows
+// have had no change made between them
+func sameBoard(a, b board) bool {
+	return len(a.clusters) == len(b.clusters) && (len(a.clusters) == 0 || &a.clusters[0] == &b.clusters[0])
+}
+
+// changeBoard applies a single cell update to a board. The board passed in is
+// left alone, other goroutines may still be reading it - the board returned
+// shares everything but the changed row. Also says if anything changed.
+func changeBoard(in board, u cell) (board, bool, error) {
+	side := in.side()
+	if !in.onBoard(u.location) {
+		return board{}, false, errors.New("got an update for a cell off the board")
+	}
 	t := in.clusters[u.location.x][u.location.y]
+	changed := false
+
 	if u.actual != 0 {
-		// this is trying to update the
+		if u.actual < 1 || u.actual > side {
+			return board{}, false, errors.New("got an update with an out of bound value")
+		}
+		// this is trying to update the value
 		if t.actual != u.actual && t.actual != 0 {
-			return board{}, errors.New("got an update for a solved cell")
+			return board{}, false, fmt.Errorf("%w: got an update for a solved cell at %v", ErrContradiction, u.location)
+		}
+		if t.excluded.Has(u.actual) {
+			return board{}, false, fmt.Errorf("%w: got an update solving %v to an excluded value", ErrContradiction, u.location)
+		}
+		if t.actual == 0 {
+			t.actual = u.actual
+			changed = true
 		}
-		t.actual = u.actual
 	}
-	if len(u.excluded) > 0 {
-		t.excluded = addArr(t.excluded, u.excluded)
-		if len(t.excluded) >= in.level*in.level {
-			return board{}, errors.New("got an update that excludes every possibility")
+	if !u.excluded.Empty() {
+		excluded := t.excluded.Union(u.excluded)
+		if excluded.Min() < 1 || excluded.Max() > side {
+			return board{}, false, errors.New("got an update that excludes an out of bound value")
+		}
+		if t.actual != 0 && excluded.Has(t.actual) {
+			return board{}, false, fmt.Errorf("%w: got an update that excludes the value of %v", ErrContradiction, u.location)
 		}
-		if t.excluded[0] < 1 || t.excluded[len(t.excluded)-1] > level*level {
-			return board{}, errors.New("got an update that excludes an out of bound value")
+		if excluded.Count() >= side {
+			return board{}, false, fmt.Errorf("%w: got an update that excludes every possibility at %v", ErrContradiction, u.location)
 		}
+		if !excluded.Equal(t.excluded) {
+			t.excluded = excluded
+			t.possible = valueSet(side).Difference(excluded)
+			changed = true
+		}
+	}
+	if !changed {
+		return in, false, nil
 	}
-	return in, nil
+
+	out := board{shape: in.shape, clusters: make([]cluster, len(in.clusters))}
+	copy(out.clusters, in.clusters)
+	out.clusters[u.location.x] = append(cluster{}, in.clusters[u.location.x]...)
+	out.clusters[u.location.x][u.location.y] = t
+	return out, true, nil
 }
@@ -0,0 +1,70 @@
+package sudoku
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPosBoxes(t *testing.T) {
+	var tests = []struct {
+		width, height int
+		at            coord
+		square        int
+	}{
+		{3, 3, coord{x: 4, y: 7}, 5},
+		{3, 2, coord{x: 0, y: 3}, 1},
+		{3, 2, coord{x: 2, y: 2}, 2},
+		{3, 2, coord{x: 5, y: 5}, 5},
+		{4, 3, coord{x: 3, y: 0}, 3},
+		{4, 3, coord{x: 11, y: 11}, 11},
+		{2, 4, coord{x: 4, y: 5}, 6},
+	}
+
+	for id, testRun := range tests {
+		in := createBoard(testRun.width, testRun.height)
+		pos, err := getPos(testRun.at, boardSquare, in.shape)
+		assert.NoError(t, err, "test %d - bad coord", id)
+		assert.Equal(t, testRun.square, pos, "test %d - wrong square", id)
+	}
+}
+
+// every cluster holds side cells, all of which report the same position
+// through getPos, and clusterStart finds the cluster again
+func TestClusterPickerBoxes(t *testing.T) {
+	var shapes = [][2]int{{2, 2}, {3, 3}, {3, 2}, {2, 3}, {4, 3}, {4, 2}}
+
+	for id, shape := range shapes {
+		in := createBoard(shape[0], shape[1])
+		side := in.side()
+		for orient := boardRow; orient <= boardSquare; orient++ {
+			seen := map[coord]bool{}
+			for pos := 0; pos < side; pos++ {
+				picked, err := clusterPicker(in, orient, clusterStart(orient, pos, in.shape))
+				assert.NoError(t, err, "test %d - bad cluster", id)
+				assert.Len(t, picked, side, "test %d - wrong cluster size", id)
+				for _, each := range picked {
+					got, err := getPos(each.location, orient, in.shape)
+					assert.NoError(t, err, "test %d - bad coord", id)
+					assert.Equal(t, pos, got, "test %d - %v in the wrong cluster", id, each.location)
+					assert.False(t, seen[each.location], "test %d - %v in two clusters", id, each.location)
+					seen[each.location] = true
+				}
+			}
+			assert.Len(t, seen, side*side, "test %d - cells missed", id)
+		}
+	}
+}
+
+// a coord that isn't on the board is reported as a problem, not a panic
+func TestClusterFilterProblems(t *testing.T) {
+	in := createBoard(2, 2)
+	update, boards := make(chan coord), make(chan board, 1)
+	status, problems, done := make(chan int, 10), make(chan error), make(chan struct{})
+	defer close(done)
+	go clusterFilter(update, boards, nil, status, problems, done)
+
+	boards <- in
+	update <- coord{x: 9, y: 0}
+	assert.Error(t, <-problems)
+}
@@ -0,0 +1,414 @@
+package sudoku
+
+// Every change the solver makes can be traced back to where it came from -
+// the rule that made it, the cluster the rule was looking at, and the cells
+// that justify it. The trace is handed out in the order the changes were
+// applied to the board, so replaying it in order rebuilds the solve.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rule names a rule that makes deductions. The rules that work on a single
+// cluster are numbered as they are in moves.go.
+type Rule int
+
+const (
+	// RuleSolvedNoPossible is rule 2 - a solved cell has all exclusions.
+	RuleSolvedNoPossible Rule = 2
+	// RuleEliminateKnowns is rule 3 - a solved value is excluded in the rest
+	// of the cluster.
+	RuleEliminateKnowns Rule = 3
+	// RuleSingleValue is rule 4 - a cell with one value left has that value.
+	RuleSingleValue Rule = 4
+	// RuleCellLimiter is rule 5 - x cells with the same x values keep those
+	// values to themselves.
+	RuleCellLimiter Rule = 5
+	// RuleSingleCell is rule 6 - a value with one cell left goes in that cell.
+	RuleSingleCell Rule = 6
+	// RuleValueLimiter is rule 7 - x values that only fit in x cells push
+	// every other value out of those cells.
+	RuleValueLimiter Rule = 7
+	// RuleCageSum keeps only the values in a killer cage that can make up its
+	// sum.
+	RuleCageSum Rule = 8
+	// RuleHouseSum is the 45 rule - the cells of a house outside the cages
+	// inside it make up the rest of the house's total.
+	RuleHouseSum Rule = 9
+	// RulePairExclusion takes the values a pairwise rule forbids next to a
+	// solved cell out of its neighbor, such as the same value a knight's move
+	// away in an anti-knight puzzle.
+	RulePairExclusion Rule = 10
+	// RulePairSupport takes a value out of a cell when a neighbor has no value
+	// left that a pairwise rule allows next to it.
+	RulePairSupport Rule = 11
+	// RuleThermo keeps each cell of a thermometer between the values the
+	// cells before and after it leave room for.
+	RuleThermo Rule = 12
+	// RuleArrow keeps the circle of an arrow to the sums the arrow can make,
+	// and the arrow to what the circle leaves room for.
+	RuleArrow Rule = 13
+	// RulePalindrome keeps each cell of a palindrome to the values of the
+	// cell at the other end.
+	RulePalindrome Rule = 14
+	// RuleWhisper keeps each cell of a German whisper to the values far
+	// enough from those of the cells next to it.
+	RuleWhisper Rule = 15
+	// RuleSandwich keeps each cell of a row or column to the values that fit
+	// some sandwich adding up to its clue.
+	RuleSandwich Rule = 16
+	// RuleLittleKiller keeps each cell of a diagonal to the values that leave
+	// the rest of the diagonal able to make up its clue.
+	RuleLittleKiller Rule = 17
+	// RuleSkyscraper keeps each cell of a row or column to the values that
+	// fit some way of seeing as many cells as its clue.
+	RuleSkyscraper Rule = 18
+	// RuleXSum keeps each cell of a row or column to the values that fit some
+	// way of making up its clue.
+	RuleXSum Rule = 19
+	// RulePointing takes a value out of the rest of a row or column when the
+	// only places left for it in a square are on that line.
+	RulePointing Rule = 20
+	// RuleBoxLine takes a value out of the rest of a square when the only
+	// places left for it on a row or column are in that square.
+	RuleBoxLine Rule = 21
+	// RuleXWing takes a value out of two columns when the only places left
+	// for it in two rows are in those columns, or the other way round.
+	RuleXWing Rule = 22
+	// RuleSwordfish is RuleXWing for three rows or columns.
+	RuleSwordfish Rule = 23
+	// RuleJellyfish is RuleXWing for four rows or columns.
+	RuleJellyfish Rule = 24
+	// RuleFinnedFish is a fish with fins - places left over, all in one
+	// square - taking the value out of the cells of that square the fish
+	// would have. Sashimi fish are finned fish too.
+	RuleFinnedFish Rule = 25
+	// RuleXYWing takes a value out of every cell seeing both pincers of an
+	// XY-Wing. The cause is the pivot, then the pincers.
+	RuleXYWing Rule = 26
+	// RuleXYZWing takes a value out of every cell seeing the pivot and both
+	// pincers of an XYZ-Wing. The cause is the pivot, then the pincers.
+	RuleXYZWing Rule = 27
+	// RuleWWing takes a value out of every cell seeing both pincers of a
+	// W-Wing. The cause is the pincers, then the two places of the value
+	// that tie them together - the one seen by the first pincer first.
+	RuleWWing Rule = 28
+	// RuleColoring takes a value out of every cell seeing both colors of a
+	// group of cells tied together by the only two places for the value in
+	// a house, or out of every cell of a color that sees itself. The cause
+	// is the group, and the chain the candidates of it.
+	RuleColoring Rule = 29
+	// RuleXChain takes a value out of every cell seeing both ends of a chain
+	// of places for the value, alternating strong and weak links. The cause
+	// is the cells of the chain in order.
+	RuleXChain Rule = 30
+	// RuleXCycle is RuleXChain for a chain closed into a loop, taking the
+	// value out of every cell seeing both ends of each weak link.
+	RuleXCycle Rule = 31
+	// RuleAIC is RuleXChain or RuleXCycle for a chain over more than one
+	// value - a cell seeing both ends loses the value they share, and a
+	// cell at one end loses the value of the other end if they see each
+	// other.
+	RuleAIC Rule = 32
+	// RuleUniqueRectangle1 takes a pair of values out of the one corner of
+	// a unique rectangle that holds more. The cause is the corners. Like
+	// every uniqueness rule, it only runs when the puzzle is assumed to have
+	// one solution.
+	RuleUniqueRectangle1 Rule = 33
+	// RuleUniqueRectangle2 takes the one extra value two corners of a unique
+	// rectangle hold out of every cell seeing both.
+	RuleUniqueRectangle2 Rule = 34
+	// RuleUniqueRectangle3 takes the values of a naked subset, made of the
+	// extra values of two corners of a unique rectangle and other cells of a
+	// house, out of the rest of the house. The cause is the corners, then
+	// the other cells of the subset.
+	RuleUniqueRectangle3 Rule = 35
+	// RuleUniqueRectangle4 takes a value out of two corners of a unique
+	// rectangle that hold the only places in a house for the other value of
+	// the pair.
+	RuleUniqueRectangle4 Rule = 36
+	// RuleBUG places the value in the one cell holding three values on a
+	// board where every other cell holds two. The cause is the other places
+	// for the value in the houses of the cell.
+	RuleBUG Rule = 37
+	// RuleALSXZ takes a value out of every cell seeing all its places in two
+	// almost locked sets tied by a restricted common value. The cause is the
+	// cells of both sets, and the sets each set.
+	RuleALSXZ Rule = 38
+	// RuleALSXYWing is RuleALSXZ for two almost locked sets each tied to a
+	// third, the pivot, by a different restricted common value. The pivot is
+	// the first set.
+	RuleALSXYWing Rule = 39
+	// RuleSueDeCoq takes values out of a box and a line that cross, locked
+	// into the cells they share along with some of the rest of each. The
+	// sets are the shared cells, then those of the line, then of the box.
+	RuleSueDeCoq Rule = 40
+	// RuleContradiction takes a value out of a cell when trying it out with
+	// the other rules breaks the board. The cause is the cell, and the trial
+	// the one that broke.
+	RuleContradiction Rule = 41
+	// RuleCellForcing makes a change that every trial of the values left in
+	// a cell agrees on, but for those that broke the board. The cause is the
+	// cell, and the trials one for each value.
+	RuleCellForcing Rule = 42
+	// RuleUnitForcing is RuleCellForcing for the places left for a value in
+	// a house. The cause is the places.
+	RuleUnitForcing Rule = 43
+)
+
+var ruleNames = map[Rule]string{
+	RuleSolvedNoPossible: "solvedNoPossible",
+	RuleEliminateKnowns:  "eliminateKnowns",
+	RuleSingleValue:      "singleValueSolver",
+	RuleCellLimiter:      "cellLimiter",
+	RuleSingleCell:       "singleCellSolver",
+	RuleValueLimiter:     "valueLimiter",
+	RuleCageSum:          "cageSum",
+	RuleHouseSum:         "houseSum",
+	RulePairExclusion:    "pairExclusion",
+	RulePairSupport:      "pairSupport",
+	RuleThermo:           "thermo",
+	RuleArrow:            "arrow",
+	RulePalindrome:       "palindrome",
+	RuleWhisper:          "whisper",
+	RuleSandwich:         "sandwich",
+	RuleLittleKiller:     "littleKiller",
+	RuleSkyscraper:       "skyscraper",
+	RuleXSum:             "xSum",
+	RulePointing:         "pointing",
+	RuleBoxLine:          "boxLine",
+	RuleXWing:            "xWing",
+	RuleSwordfish:        "swordfish",
+	RuleJellyfish:        "jellyfish",
+	RuleFinnedFish:       "finnedFish",
+	RuleXYWing:           "xyWing",
+	RuleXYZWing:          "xyzWing",
+	RuleWWing:            "wWing",
+	RuleColoring:         "coloring",
+	RuleXChain:           "xChain",
+	RuleXCycle:           "xCycle",
+	RuleAIC:              "aic",
+	RuleUniqueRectangle1: "uniqueRectangle1",
+	RuleUniqueRectangle2: "uniqueRectangle2",
+	RuleUniqueRectangle3: "uniqueRectangle3",
+	RuleUniqueRectangle4: "uniqueRectangle4",
+	RuleBUG:              "bug",
+	RuleALSXZ:            "alsXZ",
+	RuleALSXYWing:        "alsXYWing",
+	RuleSueDeCoq:         "sueDeCoq",
+	RuleContradiction:    "contradiction",
+	RuleCellForcing:      "cellForcing",
+	RuleUnitForcing:      "unitForcing",
+}
+
+// assumesUnique returns true for the rules that only hold if the puzzle has
+// one solution
+func (r Rule) assumesUnique() bool {
+	switch r {
+	case RuleUniqueRectangle1, RuleUniqueRectangle2, RuleUniqueRectangle3, RuleUniqueRectangle4, RuleBUG:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r Rule) String() string {
+	if name, ok := ruleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("Rule(%d)", int(r))
+}
+
+// Orientation says which kind of cluster a rule was looking at.
+type Orientation int
+
+const (
+	OrientRow    Orientation = boardRow
+	OrientColumn Orientation = boardCol
+	OrientSquare Orientation = boardSquare
+	// OrientExtra is a cluster the puzzle adds, such as a diagonal.
+	OrientExtra Orientation = boardExtra
+	// OrientCage is a killer cage.
+	OrientCage Orientation = boardCage
+	// OrientPair is a cell along with its neighbors under a pairwise rule.
+	OrientPair Orientation = boardPair
+	// OrientLine is a line drawn over the grid, such as a thermometer.
+	OrientLine Orientation = boardLine
+	// OrientClue is the cells a clue outside the grid looks along.
+	OrientClue Orientation = boardClue
+	// OrientIntersection is a square along with a line crossing it.
+	OrientIntersection Orientation = boardIntersection
+	// OrientFish is every cell of a grid, row by row or column by column.
+	OrientFish Orientation = boardFish
+	// OrientWhole is every cell of the board, for a rule that looks at all of
+	// it at once.
+	OrientWhole Orientation = boardWhole
+	// OrientForcing is every cell of the board, for a rule that tries values
+	// out.
+	OrientForcing Orientation = boardForcing
+)
+
+func (o Orientation) String() string {
+	switch o {
+	case OrientRow:
+		return "row"
+	case OrientColumn:
+		return "column"
+	case OrientSquare:
+		return "square"
+	case OrientExtra:
+		return "extra"
+	case OrientCage:
+		return "cage"
+	case OrientPair:
+		return "pair"
+	case OrientLine:
+		return "line"
+	case OrientClue:
+		return "clue"
+	case OrientIntersection:
+		return "intersection"
+	case OrientFish:
+		return "fish"
+	case OrientWhole:
+		return "whole"
+	case OrientForcing:
+		return "forcing"
+	default:
+		return fmt.Sprintf("Orientation(%d)", int(o))
+	}
+}
+
+// Position is the row and column of a cell, counting from 0.
+type Position struct {
+	Row int
+	Col int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("r%dc%d", p.Row+1, p.Col+1)
+}
+
+func position(c coord) Position {
+	return Position{Row: c.x, Col: c.y}
+}
+
+func positions(in []coord) []Position {
+	if in == nil {
+		return nil
+	}
+	result := make([]Position, len(in))
+	for i, each := range in {
+		result[i] = position(each)
+	}
+	return result
+}
+
+// EventKind says what sort of step an Event is.
+type EventKind int
+
+const (
+	// Deduction is a change made by a Rule.
+	Deduction EventKind = iota
+	// Guess is a value placed because the rules stalled.
+	Guess
+	// Backtrack is a guess that led to a contradiction being taken back -
+	// every event since that Guess is undone.
+	Backtrack
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case Deduction:
+		return "deduction"
+	case Guess:
+		return "guess"
+	case Backtrack:
+		return "backtrack"
+	default:
+		return fmt.Sprintf("EventKind(%d)", int(k))
+	}
+}
+
+// Event is a single step of a solve.
+type Event struct {
+	Kind EventKind
+	// Depth is the number of guesses the step sits on top of.
+	Depth int
+
+	// Cell is the cell that changed.
+	Cell Position
+	// Value is the value placed in the cell, or 0 if none was.
+	Value int
+	// Excluded holds the values newly ruled out for the cell.
+	Excluded []int
+
+	// Rule, Orientation and Index say where a Deduction came from - the rule
+	// that made it, and the cluster it was looking at.
+	Rule        Rule
+	Orientation Orientation
+	Index       int
+	// Cause holds the cells that justify a Deduction.
+	Cause []Position
+	// Chain holds the candidates of the chain behind a Deduction made by
+	// a chain rule, in order - the links between them alternate, starting
+	// with a strong one.
+	Chain []Candidate
+	// Sets holds the cells of each set behind a Deduction made by an almost
+	// locked set rule, or Sue de Coq.
+	Sets [][]Position
+	// Trials holds the values tried out behind a Deduction made by a forcing
+	// rule.
+	Trials []Trial
+	// Unique is set for a Deduction that only holds if the puzzle has one
+	// solution.
+	Unique bool
+}
+
+func (e Event) String() string {
+	var what string
+	if e.Value != 0 {
+		what = fmt.Sprintf("%v = %d", e.Cell, e.Value)
+	} else {
+		what = fmt.Sprintf("%v excludes %v", e.Cell, e.Excluded)
+	}
+	switch e.Kind {
+	case Deduction:
+		cause := make([]string, len(e.Cause))
+		for i, each := range e.Cause {
+			cause[i] = each.String()
+		}
+		return fmt.Sprintf("%s by %v in %v %d from %s", what, e.Rule,
+			e.Orientation, e.Index+1, strings.Join(cause, " "))
+	default:
+		return fmt.Sprintf("%v %s", e.Kind, what)
+	}
+}
+
+// deductionEvent builds the event for a change, given the cell as it was
+// before and after the change was applied
+func deductionEvent(u change, before, after cell, depth int) Event {
+	result := Event{
+		Kind:        Deduction,
+		Depth:       depth,
+		Cell:        position(u.location),
+		Excluded:    after.excluded.Difference(before.excluded).Values(),
+		Rule:        u.rule,
+		Orientation: Orientation(u.orient),
+		Index:       u.index,
+		Cause:       positions(u.cause),
+		Unique:      u.rule.assumesUnique(),
+	}
+	for _, each := range u.sets {
+		result.Sets = append(result.Sets, positions(each))
+	}
+	result.Trials = u.trials
+	for _, each := range u.chain {
+		result.Chain = append(result.Chain, Candidate{Cell: position(each.location), Value: each.value})
+	}
+	if before.actual == 0 {
+		result.Value = after.actual
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+package sudoku
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// collectTrace solves a puzzle, and returns every event traced along the way
+func collectTrace(s *Solver) (Grid, []Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	trace := make(chan Event)
+	s.Trace = trace
+	var events []Event
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for each := range trace {
+			events = append(events, each)
+		}
+	}()
+
+	result, err := s.Solve(ctx)
+	close(trace)
+	<-collected
+	return result, events, err
+}
+
+func TestTraceReplay(t *testing.T) {
+	result, events, err := collectTrace(loadLine(t, easyPuzzle))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, events, "nothing was traced")
+
+	// playing the placed values back onto the puzzle gives the solution
+	replay := lineValues(easyPuzzle)
+	for _, each := range events {
+		assert.Equal(t, Deduction, each.Kind, "the easy puzzle needs no guesses")
+		assert.NotEmpty(t, each.Cause, "deduction with no cause - %v", each)
+		assert.True(t, each.Value != 0 || len(each.Excluded) > 0, "empty event - %v", each)
+		if each.Value != 0 {
+			assert.Equal(t, 0, replay[each.Cell.Row][each.Cell.Col], "cell placed twice - %v", each)
+			replay[each.Cell.Row][each.Cell.Col] = each.Value
+		}
+	}
+	assert.Equal(t, result.Values, replay, "replay does not match the solution")
+}
+
+func TestTraceRules(t *testing.T) {
+	s := New(2)
+	assert.NoError(t, s.Set(0, 0, 1))
+	s.MaxGuesses = -1
+	_, events, err := collectTrace(s)
+	assert.Equal(t, ErrGuessLimit, err)
+
+	// everything the rules can do comes from the one given
+	found := map[Rule]bool{}
+	for _, each := range events {
+		found[each.Rule] = true
+		if each.Rule == RuleEliminateKnowns {
+			assert.Equal(t, []Position{{0, 0}}, each.Cause, "wrong cause - %v", each)
+			assert.Equal(t, []int{1}, each.Excluded, "wrong exclusion - %v", each)
+		}
+	}
+	assert.True(t, found[RuleEliminateKnowns], "eliminateKnowns never fired")
+	assert.True(t, found[RuleSolvedNoPossible], "solvedNoPossible never fired")
+}
+
+func TestTraceGuesses(t *testing.T) {
+	result, events, err := collectTrace(loadLine(t, hardPuzzle))
+	assert.NoError(t, err)
+	assert.Equal(t, Solved, result.Status)
+
+	guesses := 0
+	for _, each := range events {
+		switch each.Kind {
+		case Guess:
+			guesses++
+			assert.True(t, each.Depth > 0, "guess at depth 0")
+		case Deduction:
+			assert.Contains(t, each.String(), " by ", "deduction should name its rule")
+		}
+	}
+	assert.True(t, guesses > 0, "the hard puzzle needs guesses")
+}
+
+func TestEventString(t *testing.T) {
+	e := Event{Kind: Deduction, Cell: Position{0, 1}, Excluded: []int{3},
+		Rule: RuleEliminateKnowns, Orientation: OrientRow, Index: 0,
+		Cause: []Position{{0, 4}}}
+	assert.Equal(t, "r1c2 excludes [3] by eliminateKnowns in row 1 from r1c5", e.String())
+
+	e = Event{Kind: Guess, Cell: Position{8, 8}, Value: 5, Depth: 1}
+	assert.True(t, strings.HasPrefix(e.String(), "guess r9c9 = 5"), e.String())
+}
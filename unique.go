@@ -0,0 +1,330 @@
+package sudoku
+
+// A puzzle with one solution can never end up with a deadly pattern - four
+// cells at the corners of a rectangle, holding just a and b, where the two
+// values could be swapped to give a second solution. So when a puzzle is
+// assumed to have one solution, whatever would leave such a pattern is false.
+// A unique rectangle with one corner holding more (type 1) takes a and b out
+// of that corner. With two corners in a house holding more, one of those
+// extra values must be true - just x in both corners (type 2) takes x out of
+// every cell seeing both, the extras making a naked subset with other cells
+// of the house (type 3) take their values out of the rest of the house, and
+// a or b having only those two places in the house (type 4) takes the other
+// out of both corners.
+//
+// A bivalue universal grave is a board where every cell left holds two values
+// and every value is left in two places in every house - such a board has no
+// solution or more than one. With one cell holding three values (BUG+1), the
+// value that would leave the grave behind is the one the cell holds.
+//
+// These rules only hold if the puzzle is known to have a single solution, so
+// they only run when the settings assume it. The swap must not break any rule
+// of the board but the houses, so cages, lines and the like rule them out.
+
+// rectangle is four cells of a boardWhole cluster, as indexes into it - the
+// corners are in order top left, top right, bottom left, bottom right
+type rectangle [4]int
+
+// sides holds the pairs of corners of a rectangle that share a row or column
+var sides = [][2]int{{0, 1}, {2, 3}, {0, 2}, {1, 3}}
+
+// adjacent returns true if corners i and j of a rectangle share a side
+func adjacent(i, j int) bool {
+	return i+j != 3
+}
+
+// keepsHouses returns true if the kind of cluster only asks that its values
+// differ, or is made from other clusters
+func keepsHouses(orient int) bool {
+	switch orient {
+	case boardRow, boardCol, boardSquare, boardExtra, boardIntersection, boardFish, boardWhole, boardForcing:
+		return true
+	default:
+		return false
+	}
+}
+
+// rectangles returns every rectangle on a board whose corners could swap
+// values without any cluster noticing - every cluster holding a corner is a
+// house holding a side of the rectangle, or all of it. plain is true if every
+// cell on the board sits in houses alone.
+func (w *wholeBoard) rectangles(in board) (result []rectangle, plain bool, err error) {
+	refs := make([][]clusterRef, len(w.cells))
+	plain = true
+	for i, c := range w.cells {
+		if refs[i], err = in.clustersAt(c); err != nil {
+			return nil, false, err
+		}
+		for _, ref := range refs[i] {
+			plain = plain && keepsHouses(ref.orient)
+		}
+	}
+	holds := func(i int, ref clusterRef) bool {
+		for _, each := range refs[i] {
+			if each == ref {
+				return true
+			}
+		}
+		return false
+	}
+
+	for x1 := 0; x1 < in.rows(); x1++ {
+		for x2 := x1 + 1; x2 < in.rows(); x2++ {
+			for y1 := 0; y1 < in.cols(); y1++ {
+				for y2 := y1 + 1; y2 < in.cols(); y2++ {
+					var rect rectangle
+					onBoard := true
+					for i, at := range []coord{{x1, y1}, {x1, y2}, {x2, y1}, {x2, y2}} {
+						index, ok := w.index[at]
+						onBoard = onBoard && ok
+						rect[i] = index
+					}
+					if !onBoard {
+						continue
+					}
+					if swappable(rect, refs, holds) {
+						result = append(result, rect)
+					}
+				}
+			}
+		}
+	}
+	return result, plain, nil
+}
+
+// swappable returns true if every cluster holding a corner of a rectangle is
+// a house holding a side of it, or all of it
+func swappable(rect rectangle, refs [][]clusterRef, holds func(int, clusterRef) bool) bool {
+	for _, corner := range rect {
+		for _, ref := range refs[corner] {
+			if !keepsHouses(ref.orient) {
+				return false
+			}
+			if ref.orient == boardIntersection || lateOrient(ref.orient) {
+				continue
+			}
+			var in [4]bool
+			count := 0
+			for i, each := range rect {
+				in[i] = holds(each, ref)
+				if in[i] {
+					count++
+				}
+			}
+			if count == 4 {
+				continue
+			}
+			side := false
+			for _, each := range sides {
+				side = side || count == 2 && in[each[0]] && in[each[1]]
+			}
+			if !side {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// uniqueMoves returns the uniqueness rules for the whole board
+func uniqueMoves(whole *wholeBoard, start board, opts settings) moves {
+	var looked bool
+	var view sight
+	var rects []rectangle
+	var plain bool
+	return func(cells cluster) ([]change, error) {
+		if !opts.unique || opts.level < ruleLevel(RuleUniqueRectangle1, 4) {
+			return nil, nil
+		}
+		if !looked {
+			var err error
+			if view, err = whole.look(start); err != nil {
+				return nil, err
+			}
+			if rects, plain, err = whole.rectangles(start); err != nil {
+				return nil, err
+			}
+			looked = true
+		}
+
+		var changes []change
+		dropped := make([]CandidateSet, len(cells))
+		// drop takes values out of the cells in targets, but for those in skip
+		drop := func(targets, values CandidateSet, rule Rule, cause []coord, skip ...int) {
+			for _, each := range skip {
+				targets = targets.Remove(each)
+			}
+			targets.Each(func(i int) {
+				lose := cells[i].possible.Intersect(values).Difference(dropped[i])
+				if cells[i].actual != 0 || lose.Empty() {
+					return
+				}
+				dropped[i] = dropped[i].Union(lose)
+				changes = append(changes, change{
+					cell:  cell{location: cells[i].location, excluded: lose},
+					rule:  rule,
+					cause: cause})
+			})
+		}
+
+		for _, rect := range rects {
+			common := valueSet(start.side())
+			for _, corner := range rect {
+				if cells[corner].actual != 0 {
+					common = CandidateSet{}
+				}
+				common = common.Intersect(cells[corner].possible)
+			}
+			if common.Count() < 2 {
+				continue
+			}
+			cause := []coord{cells[rect[0]].location, cells[rect[1]].location,
+				cells[rect[2]].location, cells[rect[3]].location}
+			values := common.Values()
+			for i, a := range values {
+				for _, b := range values[i+1:] {
+					pair := NewCandidateSet(a, b)
+					var roof []int
+					for j, corner := range rect {
+						if !cells[corner].possible.Equal(pair) {
+							roof = append(roof, j)
+						}
+					}
+					switch {
+					case len(roof) == 1:
+						drop(NewCandidateSet(rect[roof[0]]), pair, RuleUniqueRectangle1, cause)
+					case len(roof) == 2 && adjacent(roof[0], roof[1]):
+						uniqueRoof(cells, view, rect[roof[0]], rect[roof[1]], pair, cause, drop)
+					}
+				}
+			}
+		}
+
+		if plain {
+			if found, ok := bugPlusOne(cells, view, start.side()); ok {
+				changes = append(changes, found)
+			}
+		}
+		return changes, nil
+	}
+}
+
+// uniqueRoof tries unique rectangle types 2 to 4 on a rectangle holding just
+// a pair of values but for two corners in a house, c and d
+func uniqueRoof(cells cluster, view sight, c, d int, pair CandidateSet, cause []coord,
+	drop func(targets, values CandidateSet, rule Rule, cause []coord, skip ...int)) {
+	extra := cells[c].possible.Union(cells[d].possible).Difference(pair)
+
+	// type 2 - one of the corners is x
+	if extra.Count() == 1 && cells[c].possible.Equal(cells[d].possible) {
+		drop(view.peers[c].Intersect(view.peers[d]), extra, RuleUniqueRectangle2, cause)
+	}
+
+	for _, house := range view.houses {
+		if !house.Has(c) || !house.Has(d) {
+			continue
+		}
+		var others []int
+		house.Each(func(i int) {
+			if i != c && i != d && cells[i].actual == 0 {
+				others = append(others, i)
+			}
+		})
+
+		// type 4 - a or b is in one of the corners, so the other is in
+		// neither
+		pair.Each(func(value int) {
+			for _, i := range others {
+				if cells[i].possible.Has(value) {
+					return
+				}
+			}
+			drop(NewCandidateSet(c, d), pair.Remove(value), RuleUniqueRectangle4, cause)
+		})
+
+		// type 3 - the extras act as one cell, in a naked subset with other
+		// cells of the house
+		var subset func(from int, chosen []int, values CandidateSet)
+		subset = func(from int, chosen []int, values CandidateSet) {
+			if len(chosen) > 0 && values.Count() == len(chosen)+1 {
+				why := append([]coord{}, cause...)
+				skip := []int{c, d}
+				for _, each := range chosen {
+					why = append(why, cells[each].location)
+					skip = append(skip, each)
+				}
+				drop(house, values, RuleUniqueRectangle3, why, skip...)
+				return
+			}
+			if len(chosen) == 3 {
+				return
+			}
+			for next := from; next < len(others); next++ {
+				if union := values.Union(cells[others[next]].possible); union.Count() <= 4 {
+					subset(next+1, append(chosen, others[next]), union)
+				}
+			}
+		}
+		subset(0, nil, extra)
+	}
+}
+
+// bugPlusOne looks for a bivalue universal grave with one cell left holding
+// three values, and places the value that cell must hold
+func bugPlusOne(cells cluster, view sight, side int) (change, bool) {
+	extra := -1
+	for i, each := range cells {
+		switch {
+		case each.actual != 0 || each.possible.Count() == 2:
+		case each.possible.Count() == 3 && extra < 0:
+			extra = i
+		default:
+			return change{}, false
+		}
+	}
+	if extra < 0 {
+		return change{}, false
+	}
+
+	var result change
+	found := 0
+	cells[extra].possible.Each(func(value int) {
+		// without value, every house would have every value left in none or
+		// two of its places
+		grave := true
+		var cause CandidateSet
+		for _, house := range view.houses {
+			counts := make([]int, side+1)
+			house.Each(func(i int) {
+				if cells[i].actual != 0 {
+					return
+				}
+				possible := cells[i].possible
+				if i == extra {
+					possible = possible.Remove(value)
+				}
+				possible.Each(func(each int) {
+					counts[each]++
+				})
+			})
+			for _, count := range counts {
+				grave = grave && (count == 0 || count == 2)
+			}
+			if house.Has(extra) {
+				house.Each(func(i int) {
+					if i != extra && cells[i].actual == 0 && cells[i].possible.Has(value) {
+						cause = cause.Add(i)
+					}
+				})
+			}
+		}
+		if grave {
+			found++
+			result = change{
+				cell:  cell{location: cells[extra].location, actual: value},
+				rule:  RuleBUG,
+				cause: locations(cause, cells)}
+		}
+	})
+	return result, found == 1
+}
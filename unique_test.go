@@ -0,0 +1,138 @@
+package sudoku
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRectangles(t *testing.T) {
+	empty := NewBoard(3).puzzle
+	rects, plain, err := empty.whole.rectangles(empty)
+	assert.NoError(t, err)
+	assert.True(t, plain)
+	// two rows in a band of boxes and columns in two stacks, the other way
+	// round, or all four corners in one box
+	assert.Len(t, rects, 9*27+27*9+9*9)
+
+	killer := NewBoard(3)
+	assert.NoError(t, killer.AddCage(3, []Position{{0, 0}, {0, 1}}))
+	rects, plain, err = killer.puzzle.whole.rectangles(killer.puzzle)
+	assert.NoError(t, err)
+	assert.False(t, plain, "a cage is more than a house")
+	for _, each := range rects {
+		assert.NotContains(t, each[:], 0, "r1c1 sits in a cage")
+	}
+}
+
+func TestUniqueMoves(t *testing.T) {
+	empty := NewBoard(3).puzzle
+	excluded := func(c coord, values ...int) cell {
+		return cell{location: c, excluded: NewCandidateSet(values...)}
+	}
+	corners := []coord{{0, 0}, {0, 1}, {3, 0}, {3, 1}}
+	floor := withCandidates(t, empty, coord{0, 0}, 1, 2)
+	floor = withCandidates(t, floor, coord{0, 1}, 1, 2)
+
+	type1 := withCandidates(t, floor, coord{3, 0}, 1, 2)
+	// the same, but for corners in four boxes
+	apart := withCandidates(t, empty, coord{0, 0}, 1, 2)
+	apart = withCandidates(t, apart, coord{0, 3}, 1, 2)
+	apart = withCandidates(t, apart, coord{3, 0}, 1, 2)
+
+	type2 := withCandidates(t, floor, coord{3, 0}, 1, 2, 3)
+	type2 = withCandidates(t, type2, coord{3, 1}, 1, 2, 3)
+	var type2Changes []change
+	for _, each := range append(rowExcept(3, 0, 1), coord{4, 0}, coord{4, 1}, coord{4, 2}, coord{5, 0}, coord{5, 1}, coord{5, 2}) {
+		type2Changes = append(type2Changes, change{cell: excluded(each, 3), rule: RuleUniqueRectangle2, cause: corners})
+	}
+
+	type3 := withCandidates(t, floor, coord{3, 0}, 1, 2, 3)
+	type3 = withCandidates(t, type3, coord{3, 1}, 1, 2, 4)
+	type3 = withCandidates(t, type3, coord{3, 5}, 3, 4)
+	var type3Changes []change
+	for _, each := range rowExcept(3, 0, 1, 5) {
+		type3Changes = append(type3Changes, change{cell: excluded(each, 3, 4), rule: RuleUniqueRectangle3,
+			cause: append(corners, coord{3, 5})})
+	}
+
+	// 1 is only in the corners in the fourth row
+	type4 := withoutValue(t, floor, 1, rowExcept(3, 0, 1)...)
+
+	// every cell but six is solved, and every value is left in two places
+	// in every house - but for 2 at r2c7
+	values := make([][]int, 9)
+	for x := range values {
+		values[x] = []int{9, 9, 9, 9, 9, 9, 9, 9, 9}
+	}
+	for _, each := range []coord{{0, 0}, {0, 3}, {0, 6}, {1, 0}, {1, 3}, {1, 6}} {
+		values[each.x][each.y] = 0
+	}
+	bug := fillBoard(empty.shape, values)
+	bug = withCandidates(t, bug, coord{0, 0}, 1, 2)
+	bug = withCandidates(t, bug, coord{0, 3}, 2, 3)
+	bug = withCandidates(t, bug, coord{0, 6}, 1, 3)
+	bug = withCandidates(t, bug, coord{1, 0}, 1, 2)
+	bug = withCandidates(t, bug, coord{1, 3}, 2, 3)
+	bug = withCandidates(t, bug, coord{1, 6}, 1, 2, 3)
+
+	var tests = []struct {
+		puzzle  board
+		opts    settings
+		changes []change
+	}{
+		{type1, settings{level: Hard, unique: true}, []change{
+			{cell: excluded(coord{3, 1}, 1, 2), rule: RuleUniqueRectangle1, cause: corners},
+		}},
+		{apart, settings{level: Hard, unique: true}, nil},
+		{type2, settings{level: Hard, unique: true}, type2Changes},
+		{type3, settings{level: Hard, unique: true}, type3Changes},
+		{type4, settings{level: Hard, unique: true}, []change{
+			{cell: excluded(coord{3, 0}, 2), rule: RuleUniqueRectangle4, cause: corners},
+			{cell: excluded(coord{3, 1}, 2), rule: RuleUniqueRectangle4, cause: corners},
+		}},
+		{bug, settings{level: Hard, unique: true}, []change{
+			{cell: cell{location: coord{1, 6}, actual: 2}, rule: RuleBUG, cause: []coord{{1, 0}, {1, 3}}},
+		}},
+		// only when the puzzle is assumed to have one solution
+		{type1, settings{level: Hard}, nil},
+		{type1, settings{level: Medium, unique: true}, nil},
+	}
+
+	for id, testRun := range tests {
+		whole, err := pickCluster(testRun.puzzle, clusterRef{orient: boardWhole, index: 2})
+		assert.NoError(t, err)
+		changes, err := uniqueMoves(empty.whole, testRun.puzzle, testRun.opts)(whole)
+		assert.NoError(t, err, "test %d - unexpected error", id)
+		assert.Equal(t, testRun.changes, changes, "test %d - wrong changes", id)
+	}
+}
+
+func TestSolveUnique(t *testing.T) {
+	b, err := Parse(strings.NewReader(chainPuzzle))
+	assert.NoError(t, err)
+	_, report := Grader{Weights: DefaultGrader.Weights, AssumeUnique: true}.Grade(b)
+	assert.Equal(t, Solved, report.Status)
+	assert.NotZero(t, report.Counts[RuleUniqueRectangle4], "a unique rectangle should be used")
+	_, report = Grade(b)
+	assert.Zero(t, report.Counts[RuleUniqueRectangle4], "uniqueness isn't assumed by default")
+
+	events := make(chan Event, 10000)
+	s := NewFromBoard(b)
+	s.AssumeUnique = true
+	s.Trace = events
+	result, err := s.Solve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, lineValues(chainSolution), result.Values)
+	close(events)
+	unique := 0
+	for step := range events {
+		assert.Equal(t, step.Rule.assumesUnique(), step.Unique, "%v", step)
+		if step.Unique {
+			unique++
+		}
+	}
+	assert.NotZero(t, unique, "the trace should show uniqueness was assumed")
+}
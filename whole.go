@@ -0,0 +1,162 @@
+package sudoku
+
+// Some rules look at the whole board at once - a wing's pincers can sit
+// anywhere the pivot can see, and a chain can wander over every house. Each
+// of these rules gets a boardWhole cluster of every cell on the board, and
+// a late worker of its own. What they all need to know is which cells see
+// each other - share a cluster that holds no value twice, or have a pairwise
+// rule keeping their values apart.
+
+import (
+	"sync"
+)
+
+// wholeRules holds the moves of each boardWhole cluster, given the board the
+// pipeline starts on - in the order of the clusters
+var wholeRules = []func(whole *wholeBoard, start board, opts settings) moves{
+	wingMoves,
+	chainMoves,
+	uniqueMoves,
+	alsMoves,
+}
+
+// wholeBoard lists every cell on a board, row by row. index[c] is where coord
+// c sits in the list, and rules the number of boardWhole clusters.
+type wholeBoard struct {
+	cells []coord
+	index map[coord]int
+	rules int
+	// looked holds the last sight worked out, and the clusters it was worked
+	// out for - a board gains cages, pairs and extra clusters by swapping in
+	// new lists, so the same lists mean the same sight
+	mutex  sync.Mutex
+	looked *sight
+	seenBy sightKey
+}
+
+// sightKey holds the lists of clusters that decide which cells see each other
+type sightKey struct {
+	regions *regionMap
+	extras  *extraClusters
+	cages   *cageList
+	pairs   *pairList
+	layout  *gridLayout
+}
+
+// newWholeBoard lists every cell on the board of a shape
+func newWholeBoard(s shape) *wholeBoard {
+	result := &wholeBoard{index: make(map[coord]int), rules: len(wholeRules)}
+	for x := 0; x < s.rows(); x++ {
+		for y := 0; y < s.cols(); y++ {
+			at := coord{x: x, y: y}
+			if s.onBoard(at) {
+				result.index[at] = len(result.cells)
+				result.cells = append(result.cells, at)
+			}
+		}
+	}
+	return result
+}
+
+// moves returns the rules for whole board cluster index
+func (w *wholeBoard) moves(index int, start board, opts settings) moves {
+	return wholeRules[index](w, start, opts)
+}
+
+// seesValue returns true for the kinds of cluster that hold no value twice
+func seesValue(orient int) bool {
+	switch orient {
+	case boardRow, boardCol, boardSquare, boardExtra, boardCage:
+		return true
+	default:
+		return false
+	}
+}
+
+// peers returns every coord that sees c - that shares a row, column, square,
+// extra cluster or cage with it, or has a pairwise rule that they differ
+func (b board) peers(c coord) ([]coord, error) {
+	refs, err := b.clustersAt(c)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[coord]bool{c: true}
+	var result []coord
+	add := func(at coord) {
+		if !seen[at] {
+			seen[at] = true
+			result = append(result, at)
+		}
+	}
+	for _, ref := range refs {
+		if !seesValue(ref.orient) {
+			continue
+		}
+		cells, err := pickCluster(b, ref)
+		if err != nil {
+			return nil, err
+		}
+		for _, each := range cells {
+			add(each.location)
+		}
+	}
+	if b.pairs != nil {
+		if index, ok := b.pairs.index[c]; ok {
+			for _, link := range b.pairs.links[index] {
+				for _, rule := range link.rules {
+					if rule == pairDifferent {
+						add(link.to)
+					}
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// sight holds which cells of a boardWhole cluster see each other - peers[i]
+// is the cells cell i sees, and houses every cluster that holds each value
+// once, as the indexes of its cells
+type sight struct {
+	peers  []CandidateSet
+	houses []CandidateSet
+}
+
+// look works out which cells of the whole board see each other - the sight
+// it returns is shared, and never changed
+func (w *wholeBoard) look(in board) (sight, error) {
+	key := sightKey{regions: in.regions, extras: in.extras, cages: in.cages, pairs: in.pairs, layout: in.layout}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.looked != nil && w.seenBy == key {
+		return *w.looked, nil
+	}
+
+	var result sight
+	for _, c := range w.cells {
+		peers, err := in.peers(c)
+		if err != nil {
+			return sight{}, err
+		}
+		var set CandidateSet
+		for _, each := range peers {
+			set = set.Add(w.index[each])
+		}
+		result.peers = append(result.peers, set)
+	}
+	for orient := boardRow; orient <= boardExtra; orient++ {
+		for pos := 0; pos < in.clusterCount(orient); pos++ {
+			cells, err := pickCluster(in, clusterRef{orient: orient, index: pos})
+			if err != nil {
+				return sight{}, err
+			}
+			var set CandidateSet
+			for _, each := range cells {
+				set = set.Add(w.index[each.location])
+			}
+			result.houses = append(result.houses, set)
+		}
+	}
+	w.looked, w.seenBy = &result, key
+	return result, nil
+}
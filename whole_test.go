@@ -0,0 +1,80 @@
+package sudoku
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeers(t *testing.T) {
+	diagonal := NewBoard(3)
+	assert.NoError(t, diagonal.AddDiagonals())
+	knight := NewBoard(3)
+	assert.NoError(t, knight.AddAntiKnight())
+	nonConsecutive := NewBoard(3)
+	assert.NoError(t, nonConsecutive.AddNonConsecutive())
+	killer := NewBoard(3)
+	assert.NoError(t, killer.AddCage(3, []Position{{0, 0}, {4, 4}}))
+
+	var tests = []struct {
+		b     *Board
+		at    coord
+		peers int
+	}{
+		{NewBoard(3), coord{4, 4}, 20},
+		{NewBoard(2), coord{0, 0}, 7},
+		// both diagonals cross the middle
+		{diagonal, coord{4, 4}, 20 + 12},
+		{diagonal, coord{0, 1}, 20},
+		{knight, coord{4, 4}, 20 + 8},
+		// non-consecutive cells may hold the same value
+		{nonConsecutive, coord{4, 4}, 20},
+		{killer, coord{4, 4}, 20 + 1},
+	}
+
+	for id, testRun := range tests {
+		peers, err := testRun.b.puzzle.peers(testRun.at)
+		assert.NoError(t, err, "test %d - unexpected error", id)
+		assert.Len(t, peers, testRun.peers, "test %d - wrong number of peers", id)
+		assert.NotContains(t, peers, testRun.at, "test %d - a cell isn't its own peer", id)
+	}
+
+	_, err := NewBoard(3).puzzle.peers(coord{9, 0})
+	assert.Error(t, err, "off the board")
+}
+
+func TestLook(t *testing.T) {
+	samurai, err := NewLayoutBoard(3, 3, SamuraiCorners(3))
+	assert.NoError(t, err)
+	diagonal := NewBoard(3)
+	assert.NoError(t, diagonal.AddDiagonals())
+
+	var tests = []struct {
+		b      *Board
+		cells  int
+		houses int
+	}{
+		{NewBoard(3), 81, 27},
+		{diagonal, 81, 29},
+		{samurai, 369, 5 * 27},
+	}
+
+	for id, testRun := range tests {
+		whole := testRun.b.puzzle.whole
+		assert.Len(t, whole.cells, testRun.cells, "test %d - wrong number of cells", id)
+		view, err := whole.look(testRun.b.puzzle)
+		assert.NoError(t, err, "test %d - unexpected error", id)
+		assert.Len(t, view.peers, testRun.cells, "test %d - wrong number of peers", id)
+		assert.Len(t, view.houses, testRun.houses, "test %d - wrong number of houses", id)
+		for _, house := range view.houses {
+			assert.Equal(t, 9, house.Count(), "test %d - wrong house size", id)
+		}
+	}
+
+	// a corner of the middle grid of a samurai sees the cells of both grids
+	// it sits in - but the box they share only once
+	samuraiView, err := samurai.puzzle.whole.look(samurai.puzzle)
+	assert.NoError(t, err)
+	overlap := samurai.puzzle.whole.index[coord{6, 6}]
+	assert.Equal(t, 20+20-8, samuraiView.peers[overlap].Count())
+}
@@ -0,0 +1,152 @@
+package sudoku
+
+// Wings are made of cells with two values left, the pincers, tied together
+// by a pivot. An XY-Wing's pivot holds x or y, one pincer x or z, and the
+// other y or z - whichever the pivot holds, one of the pincers holds z, so
+// every cell seeing both pincers loses z. An XYZ-Wing's pivot holds z too, so
+// only the cells seeing the pivot as well lose it. A W-Wing's pincers both
+// hold x or y without seeing each other, and its pivot is a house where x
+// has just two places left, one seen by each pincer - one of the pincers
+// can't be x, so it is y, and every cell seeing both pincers loses y.
+
+// wingMoves returns the wing rules for the whole board
+func wingMoves(whole *wholeBoard, start board, opts settings) moves {
+	var looked bool
+	var view sight
+	return func(cells cluster) ([]change, error) {
+		if opts.level < ruleLevel(RuleXYWing, 3) {
+			return nil, nil
+		}
+		// which cells see each other never changes, so it is only worked out
+		// once, the first time it is needed
+		if !looked {
+			var err error
+			if view, err = whole.look(start); err != nil {
+				return nil, err
+			}
+			looked = true
+		}
+
+		var bivalue, trivalue []int
+		for i, each := range cells {
+			if each.actual != 0 {
+				continue
+			}
+			switch each.possible.Count() {
+			case 2:
+				bivalue = append(bivalue, i)
+			case 3:
+				trivalue = append(trivalue, i)
+			}
+		}
+
+		var changes []change
+		dropped := make([]CandidateSet, len(cells))
+		// drop takes value out of the cells in targets, but for those in cause
+		drop := func(targets CandidateSet, value int, rule Rule, cause ...int) {
+			for _, each := range cause {
+				targets = targets.Remove(each)
+			}
+			var why []coord
+			targets.Each(func(i int) {
+				if cells[i].actual != 0 || !cells[i].possible.Has(value) || dropped[i].Has(value) {
+					return
+				}
+				if why == nil {
+					for _, each := range cause {
+						why = append(why, cells[each].location)
+					}
+				}
+				dropped[i] = dropped[i].Add(value)
+				changes = append(changes, change{
+					cell:  cell{location: cells[i].location, excluded: NewCandidateSet(value)},
+					rule:  rule,
+					cause: why})
+			})
+		}
+
+		// XY-Wings - the first pincer shares the smaller value of the pivot
+		for _, pivot := range bivalue {
+			values := cells[pivot].possible
+			for _, a := range bivalue {
+				shared := cells[a].possible.Intersect(values)
+				if !view.peers[pivot].Has(a) || shared.Count() != 1 || shared.Min() != values.Min() {
+					continue
+				}
+				z := cells[a].possible.Difference(shared).Min()
+				want := values.Difference(shared).Add(z)
+				for _, b := range bivalue {
+					if b == a || !view.peers[pivot].Has(b) || !cells[b].possible.Equal(want) {
+						continue
+					}
+					drop(view.peers[a].Intersect(view.peers[b]), z, RuleXYWing, pivot, a, b)
+				}
+			}
+		}
+
+		// XYZ-Wings
+		for _, pivot := range trivalue {
+			values := cells[pivot].possible
+			for _, a := range bivalue {
+				if !view.peers[pivot].Has(a) || !cells[a].possible.SubsetOf(values) {
+					continue
+				}
+				for _, b := range bivalue {
+					if b <= a || !view.peers[pivot].Has(b) || !cells[b].possible.SubsetOf(values) {
+						continue
+					}
+					z := cells[a].possible.Intersect(cells[b].possible)
+					if z.Count() != 1 || !cells[a].possible.Union(cells[b].possible).Equal(values) {
+						continue
+					}
+					targets := view.peers[pivot].Intersect(view.peers[a]).Intersect(view.peers[b])
+					drop(targets, z.Min(), RuleXYZWing, pivot, a, b)
+				}
+			}
+		}
+
+		// W-Wings - the places left for each value in each house that
+		// doesn't have it yet
+		places := make([][]CandidateSet, len(view.houses))
+		for h, house := range view.houses {
+			places[h] = make([]CandidateSet, start.side()+1)
+			var solved CandidateSet
+			house.Each(func(i int) {
+				if cells[i].actual != 0 {
+					solved = solved.Add(cells[i].actual)
+					return
+				}
+				cells[i].possible.Each(func(value int) {
+					places[h][value] = places[h][value].Add(i)
+				})
+			})
+			solved.Each(func(value int) {
+				places[h][value] = CandidateSet{}
+			})
+		}
+		for _, a := range bivalue {
+			for _, b := range bivalue {
+				if b <= a || view.peers[a].Has(b) || !cells[a].possible.Equal(cells[b].possible) {
+					continue
+				}
+				cells[a].possible.Each(func(x int) {
+					y := cells[a].possible.Remove(x).Min()
+					for h := range view.houses {
+						link := places[h][x]
+						if link.Count() != 2 || link.Has(a) || link.Has(b) {
+							continue
+						}
+						c, d := link.Min(), link.Max()
+						if !view.peers[a].Has(c) || !view.peers[b].Has(d) {
+							c, d = d, c
+						}
+						if view.peers[a].Has(c) && view.peers[b].Has(d) {
+							drop(view.peers[a].Intersect(view.peers[b]), y, RuleWWing, a, b, c, d)
+						}
+					}
+				})
+			}
+		}
+		return changes, nil
+	}
+}
@@ -0,0 +1,95 @@
+package sudoku
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// a puzzle that needs an XY-Wing, an XYZ-Wing and W-Wings
+const (
+	wingPuzzle   = "..953...15..6.7..9.3...4..6....4...374.....5..95.........8.64..2.6........1....8."
+	wingSolution = "869532741524617839137984526618245973742369158395178264973826415286451397451793682"
+)
+
+// withCandidates returns a board with a cell left holding just some values
+func withCandidates(t *testing.T, in board, at coord, values ...int) board {
+	for value := 1; value <= in.side(); value++ {
+		if !NewCandidateSet(values...).Has(value) {
+			in = withoutValue(t, in, value, at)
+		}
+	}
+	return in
+}
+
+func TestWingMoves(t *testing.T) {
+	empty := NewBoard(3).puzzle
+	excluded := func(c coord, value int) cell { return cell{location: c, excluded: NewCandidateSet(value)} }
+
+	xyWing := withCandidates(t, empty, coord{0, 0}, 1, 2)
+	xyWing = withCandidates(t, xyWing, coord{0, 5}, 1, 3)
+	xyWing = withCandidates(t, xyWing, coord{5, 0}, 2, 3)
+
+	xyzWing := withCandidates(t, empty, coord{0, 0}, 1, 2, 3)
+	xyzWing = withCandidates(t, xyzWing, coord{0, 5}, 1, 3)
+	xyzWing = withCandidates(t, xyzWing, coord{1, 1}, 2, 3)
+
+	// 1 is only at r9c1 and r9c5 in the bottom row
+	wWing := withCandidates(t, empty, coord{0, 0}, 1, 2)
+	wWing = withCandidates(t, wWing, coord{4, 4}, 1, 2)
+	wWing = withoutValue(t, wWing, 1, rowExcept(8, 0, 4)...)
+
+	var tests = []struct {
+		puzzle  board
+		level   Difficulty
+		changes []change
+	}{
+		{xyWing, Hard, []change{
+			{cell: excluded(coord{5, 5}, 3), rule: RuleXYWing, cause: []coord{{0, 0}, {0, 5}, {5, 0}}},
+		}},
+		{xyzWing, Hard, []change{
+			{cell: excluded(coord{0, 1}, 3), rule: RuleXYZWing, cause: []coord{{0, 0}, {0, 5}, {1, 1}}},
+			{cell: excluded(coord{0, 2}, 3), rule: RuleXYZWing, cause: []coord{{0, 0}, {0, 5}, {1, 1}}},
+		}},
+		{wWing, Hard, []change{
+			{cell: excluded(coord{0, 4}, 2), rule: RuleWWing, cause: []coord{{0, 0}, {4, 4}, {8, 0}, {8, 4}}},
+			{cell: excluded(coord{4, 0}, 2), rule: RuleWWing, cause: []coord{{0, 0}, {4, 4}, {8, 0}, {8, 4}}},
+		}},
+		// wings are a Hard rule
+		{xyWing, Medium, nil},
+		{empty, Hard, nil},
+	}
+
+	for id, testRun := range tests {
+		whole, err := pickCluster(testRun.puzzle, clusterRef{orient: boardWhole, index: 0})
+		assert.NoError(t, err)
+		changes, err := wingMoves(empty.whole, empty, settings{level: testRun.level})(whole)
+		assert.NoError(t, err, "test %d - unexpected error", id)
+		assert.Equal(t, testRun.changes, changes, "test %d - wrong changes", id)
+	}
+}
+
+func TestSolveWings(t *testing.T) {
+	b, err := Parse(strings.NewReader(wingPuzzle))
+	assert.NoError(t, err)
+	level, report := Grade(b)
+	assert.Equal(t, Hard, level)
+	assert.Equal(t, Solved, report.Status)
+	assert.Zero(t, report.Guesses)
+	for _, rule := range []Rule{RuleXYWing, RuleXYZWing, RuleWWing} {
+		assert.NotZero(t, report.Counts[rule], "%v should be used", rule)
+	}
+
+	result, err := NewFromBoard(b).Solve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, lineValues(wingSolution), result.Values)
+
+	// without the wings, the rules stall
+	puzzle := b.puzzle
+	puzzle.whole = nil
+	stalled, err := propagate(context.Background(), puzzle, settings{level: Hard})
+	assert.NoError(t, err)
+	assert.False(t, stalled.solved(), "the puzzle should need the wings")
+}